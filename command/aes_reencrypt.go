@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"kriyapeople/pkg/aes"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/pg"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase"
+
+	"github.com/rs/xid"
+)
+
+// aesReencrypt upgrade every stored ciphertext to the primary aes key, run it after the primary key is
+// changed and before the old key is removed from AES_KEYS
+func aesReencrypt(args []string) (err error) {
+	ctx := "command.aesReencrypt"
+
+	fs := flag.NewFlagSet("aes-reencrypt", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only count the values without updating")
+	batch := fs.Int("batch", usecase.DefaultAesReencryptBatch, "rows read per query")
+	fs.Parse(args)
+
+	aesCredential, err := aes.NewCredential(envConfig["AES_KEY"], envConfig["AES_KEYS"], envConfig["AES_PRIMARY_KEY_ID"])
+	if err != nil {
+		return err
+	}
+
+	dbInfo := pg.Connection{
+		Host:    envConfig["DATABASE_HOST"],
+		DB:      envConfig["DATABASE_DB"],
+		User:    envConfig["DATABASE_USER"],
+		Pass:    envConfig["DATABASE_PASSWORD"],
+		Port:    str.StringToInt(envConfig["DATABASE_PORT"]),
+		SslMode: "disable",
+	}
+	db, err := dbInfo.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	uc := usecase.AesUC{ContractUC: &usecase.ContractUC{
+		ReqID:     xid.New().String(),
+		DB:        db,
+		EnvConfig: envConfig,
+		Aes:       aesCredential,
+	}}
+	res, err := uc.Reencrypt(*dryRun, *batch)
+	logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(res), ctx, "report", "")
+
+	return err
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/pg"
+	"kriyapeople/pkg/storage"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase"
+	"strconv"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/rs/xid"
+)
+
+// fileClean run the unassigned uploaded file cleaner once, used to report or clean manually
+func fileClean(args []string) (err error) {
+	ctx := "command.fileClean"
+
+	fs := flag.NewFlagSet("file-clean", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only report the files without deleting")
+	ttl := fs.String("ttl", envConfig["FILE_CLEAN_TTL"], "minimum age of unassigned file, ex: 24h")
+	limit := fs.Int("limit", str.StringToInt(envConfig["FILE_CLEAN_LIMIT"]), "maximum files per run")
+	fs.Parse(args)
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     envConfig["REDIS_HOST"],
+		Password: envConfig["REDIS_PASSWORD"],
+		DB:       0,
+	})
+	defer redisClient.Close()
+	_, err = redisClient.Ping().Result()
+	if err != nil {
+		return err
+	}
+
+	dbInfo := pg.Connection{
+		Host:    envConfig["DATABASE_HOST"],
+		DB:      envConfig["DATABASE_DB"],
+		User:    envConfig["DATABASE_USER"],
+		Pass:    envConfig["DATABASE_PASSWORD"],
+		Port:    str.StringToInt(envConfig["DATABASE_PORT"]),
+		SslMode: "disable",
+	}
+	db, err := dbInfo.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	storageInfo := storage.Connection{
+		Driver:    envConfig["STORAGE_DRIVER"],
+		Path:      envConfig["FILE_STATIC_FILE"],
+		URL:       envConfig["APP_IMAGE_URL"] + envConfig["FILE_PATH"],
+		Endpoint:  envConfig["S3_ENDPOINT"],
+		AccessKey: envConfig["S3_ACCESS_KEY"],
+		SecretKey: envConfig["S3_SECRET_KEY"],
+		Bucket:    envConfig["S3_BUCKET"],
+		Region:    envConfig["S3_REGION"],
+		UseSSL:    str.StringToBool(envConfig["S3_USE_SSL"]),
+	}
+	fileStorage, err := storageInfo.Connect()
+	if err != nil {
+		return err
+	}
+	htmlStorage, err := storage.NewLocalStorage(envConfig["HTML_FILE_STATIC_FILE"], envConfig["APP_IMAGE_URL"]+envConfig["HTML_FILE_PATH"])
+	if err != nil {
+		return err
+	}
+
+	config := map[string]string{}
+	for key, value := range envConfig {
+		config[key] = value
+	}
+	config["FILE_CLEAN_TTL"] = *ttl
+	config["FILE_CLEAN_LIMIT"] = strconv.Itoa(*limit)
+
+	uc := usecase.FileUC{ContractUC: &usecase.ContractUC{
+		ReqID:       xid.New().String(),
+		DB:          db,
+		Redis:       redisClient,
+		EnvConfig:   config,
+		Storage:     fileStorage,
+		HTMLStorage: htmlStorage,
+	}}
+	res, ok, err := uc.CleanUnassignedWithLock(*dryRun)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logruslogger.Log(logruslogger.InfoLevel, "other process is cleaning the files", ctx, "locked", "")
+		return err
+	}
+	logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(res), ctx, "report", "")
+
+	return err
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"kriyapeople/pkg/env"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/storage"
+	"kriyapeople/pkg/str"
+	"os"
+	"sort"
+)
+
+var (
+	envConfig map[string]string
+
+	// commands list of available command, called with: go run . [command] [flags]
+	commands = map[string]func(args []string) error{
+		"aes-reencrypt":    aesReencrypt,
+		"file-clean":       fileClean,
+		"storage-migrate":  storageMigrate,
+		"user-pii-migrate": userPIIMigrate,
+	}
+)
+
+// Init first time running function
+func init() {
+	// Load env variable from .env file
+	envConfig = env.NewEnvConfig("../.env")
+}
+
+func main() {
+	ctx := "command"
+
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	name := flag.Arg(0)
+	fn, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+
+	err := fn(flag.Args()[1:])
+	if err != nil {
+		logruslogger.Log(logruslogger.ErrorLevel, err.Error(), ctx, name, "")
+		os.Exit(1)
+	}
+
+	logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(flag.Args()), ctx, name, "")
+}
+
+func usage() {
+	names := []string{}
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage: go run . [command] [flags]\n\nCommands:\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %s\n", name)
+	}
+}
+
+// s3Storage connect to the s3 compatible bucket from env
+func s3Storage() (storage.IStorage, error) {
+	storageInfo := storage.Connection{
+		Driver:    storage.DriverS3,
+		Endpoint:  envConfig["S3_ENDPOINT"],
+		AccessKey: envConfig["S3_ACCESS_KEY"],
+		SecretKey: envConfig["S3_SECRET_KEY"],
+		Bucket:    envConfig["S3_BUCKET"],
+		Region:    envConfig["S3_REGION"],
+		UseSSL:    str.StringToBool(envConfig["S3_USE_SSL"]),
+	}
+
+	return storageInfo.Connect()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"flag"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/storage"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// storageMigrate copy every file in local FILE_STATIC_FILE folder into the s3 bucket,
+// file which already exist in the bucket is skipped
+func storageMigrate(args []string) (err error) {
+	ctx := "command.storageMigrate"
+
+	fs := flag.NewFlagSet("storage-migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the files without copying")
+	fs.Parse(args)
+
+	target, err := s3Storage()
+	if err != nil {
+		return err
+	}
+
+	root := envConfig["FILE_STATIC_FILE"]
+	report := map[string]int{
+		"copied":  0,
+		"skipped": 0,
+		"failed":  0,
+	}
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		objectName := filepath.ToSlash(rel)
+
+		exist, err := target.Exist(objectName)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, objectName+" "+err.Error(), ctx, "exist", "")
+			report["failed"]++
+			return nil
+		}
+		if exist {
+			report["skipped"]++
+			return nil
+		}
+		if *dryRun {
+			logruslogger.Log(logruslogger.InfoLevel, objectName, ctx, "dry_run", "")
+			report["copied"]++
+			return nil
+		}
+
+		err = copyFile(target, path, objectName, info.Size())
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, objectName+" "+err.Error(), ctx, "copy", "")
+			report["failed"]++
+			return nil
+		}
+		report["copied"]++
+
+		return nil
+	})
+	logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(report), ctx, "report", "")
+
+	return err
+}
+
+// copyFile ...
+func copyFile(target storage.IStorage, path, objectName string, size int64) (err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Only the first 512 bytes are used to sniff the content type.
+	buffer := make([]byte, 512)
+	n, _ := f.Read(buffer)
+	contentType := http.DetectContentType(buffer[:n])
+	_, err = f.Seek(0, 0)
+	if err != nil {
+		return err
+	}
+
+	return target.Put(objectName, f, size, contentType)
+}
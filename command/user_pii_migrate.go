@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"kriyapeople/pkg/aes"
+	"kriyapeople/pkg/hmacsha"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/pg"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase"
+
+	"github.com/rs/xid"
+)
+
+// userPIIMigrate encrypt or decrypt the pii fields of every user following USER_ENCRYPTED_FIELDS,
+// run it after the fields or USER_BLIND_INDEX_KEY is changed
+func userPIIMigrate(args []string) (err error) {
+	ctx := "command.userPIIMigrate"
+
+	fs := flag.NewFlagSet("user-pii-migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "only count the users without updating")
+	batch := fs.Int("batch", usecase.DefaultUserPIIMigrateBatch, "users read per query")
+	fs.Parse(args)
+
+	aesCredential, err := aes.NewCredential(envConfig["AES_KEY"], envConfig["AES_KEYS"], envConfig["AES_PRIMARY_KEY_ID"])
+	if err != nil {
+		return err
+	}
+
+	dbInfo := pg.Connection{
+		Host:    envConfig["DATABASE_HOST"],
+		DB:      envConfig["DATABASE_DB"],
+		User:    envConfig["DATABASE_USER"],
+		Pass:    envConfig["DATABASE_PASSWORD"],
+		Port:    str.StringToInt(envConfig["DATABASE_PORT"]),
+		SslMode: "disable",
+	}
+	db, err := dbInfo.Connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	uc := usecase.UserPIIUC{ContractUC: &usecase.ContractUC{
+		ReqID:         xid.New().String(),
+		DB:            db,
+		EnvConfig:     envConfig,
+		Aes:           aesCredential,
+		BlindIndexKey: hmacsha.Credential{Key: envConfig["USER_BLIND_INDEX_KEY"]},
+	}}
+	res, err := uc.Migrate(*dryRun, *batch)
+	logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(res), ctx, "report", "")
+
+	return err
+}
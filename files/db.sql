@@ -9,27 +9,150 @@ CREATE TABLE "public"."roles" (
   "updated_at" timestamp(6) DEFAULT now(),
   "deleted_at" timestamp(6)
 );
+DROP TABLE IF EXISTS "public"."files";
+CREATE TABLE "public"."files" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "type" varchar(50) NOT NULL,
+  "url" text NOT NULL,
+  "user_upload" char(36),
+  "status" varchar(20) DEFAULT 'processed' NOT NULL,
+  "thumbnails" jsonb DEFAULT '{}' NOT NULL,
+  "size" int8 DEFAULT 0 NOT NULL,
+  "created_at" timestamp(6) DEFAULT now(),
+  "updated_at" timestamp(6) DEFAULT now(),
+  "deleted_at" timestamp(6)
+);
 DROP TABLE IF EXISTS "public"."users";
 CREATE TABLE "public"."users" (
   "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
   "data" jsonb NOT NULL,
   "role_id" char(36) COLLATE "pg_catalog"."default" NOT NULL,
+  "profile_image_id" char(36),
   "created_at" timestamp(6) DEFAULT now(),
   "updated_at" timestamp(6) DEFAULT now(),
   "deleted_at" timestamp(6)
 );
+DROP TABLE IF EXISTS "public"."outbox";
+CREATE TABLE "public"."outbox" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "exchange" varchar(255) DEFAULT '' NOT NULL,
+  "routing_key" varchar(255) NOT NULL,
+  "dead_letter_key" varchar(255) DEFAULT '' NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar(20) DEFAULT 'pending' NOT NULL,
+  "attempts" int4 DEFAULT 0 NOT NULL,
+  "last_error" text,
+  "next_attempt_at" timestamp(6) DEFAULT now() NOT NULL,
+  "created_at" timestamp(6) DEFAULT now(),
+  "sent_at" timestamp(6)
+);
+
+DROP TABLE IF EXISTS "public"."mail_deliveries";
+CREATE TABLE "public"."mail_deliveries" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "queue_message_id" varchar(255) NOT NULL,
+  "template" varchar(50) NOT NULL,
+  "recipient" varchar(255) NOT NULL,
+  "driver" varchar(20) NOT NULL,
+  "status" varchar(20) NOT NULL,
+  "provider_message_id" varchar(255),
+  "error" text,
+  "attempt" int4 DEFAULT 1 NOT NULL,
+  "created_at" timestamp(6) DEFAULT now()
+);
+
+DROP TABLE IF EXISTS "public"."partner_clients";
+CREATE TABLE "public"."partner_clients" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "client_id" varchar(64) NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "secret" text NOT NULL,
+  "is_active" bool DEFAULT true NOT NULL,
+  "last_used_at" timestamp(6),
+  "created_at" timestamp(6) DEFAULT now(),
+  "updated_at" timestamp(6) DEFAULT now(),
+  "deleted_at" timestamp(6)
+);
+
+DROP TABLE IF EXISTS "public"."api_keys";
+CREATE TABLE "public"."api_keys" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "name" varchar(255) NOT NULL,
+  "key_prefix" varchar(32) NOT NULL,
+  "key_hash" char(64) NOT NULL,
+  "scopes" jsonb DEFAULT '[]' NOT NULL,
+  "allowed_ips" jsonb DEFAULT '[]' NOT NULL,
+  "expires_at" timestamp(6),
+  "last_used_at" timestamp(6),
+  "last_used_ip" varchar(64),
+  "created_by" char(36),
+  "revoked_at" timestamp(6),
+  "created_at" timestamp(6) DEFAULT now(),
+  "updated_at" timestamp(6) DEFAULT now()
+);
+
+DROP TABLE IF EXISTS "public"."webhook_deliveries";
+DROP TABLE IF EXISTS "public"."webhook_endpoints";
+CREATE TABLE "public"."webhook_endpoints" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "url" text NOT NULL,
+  "secret" text NOT NULL,
+  "events" jsonb DEFAULT '[]' NOT NULL,
+  "description" varchar(255),
+  "is_active" bool DEFAULT true NOT NULL,
+  "failure_count" int4 DEFAULT 0 NOT NULL,
+  "disabled_at" timestamp(6),
+  "created_at" timestamp(6) DEFAULT now(),
+  "updated_at" timestamp(6) DEFAULT now(),
+  "deleted_at" timestamp(6)
+);
+
+CREATE TABLE "public"."webhook_deliveries" (
+  "id" char(36) DEFAULT uuid_generate_v4 () NOT NULL,
+  "endpoint_id" char(36) NOT NULL,
+  "event_id" varchar(255) NOT NULL,
+  "event_type" varchar(100) NOT NULL,
+  "payload" jsonb NOT NULL,
+  "status" varchar(20) DEFAULT 'pending' NOT NULL,
+  "attempts" int4 DEFAULT 0 NOT NULL,
+  "next_attempt_at" timestamp(6) DEFAULT now() NOT NULL,
+  "response_status" int4,
+  "response_body" text,
+  "last_error" text,
+  "duration_ms" int8,
+  "created_at" timestamp(6) DEFAULT now(),
+  "delivered_at" timestamp(6)
+);
 
 ALTER TABLE "public"."roles" ADD CONSTRAINT "roles_pkey" PRIMARY KEY ("id");
+ALTER TABLE "public"."files" ADD CONSTRAINT "files_pkey" PRIMARY KEY ("id");
 ALTER TABLE "public"."users" ADD CONSTRAINT "users_pkey" PRIMARY KEY ("id");
 ALTER TABLE "public"."users" ADD CONSTRAINT "users_role_id_fkey" FOREIGN KEY ("role_id") REFERENCES "public"."roles" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+ALTER TABLE "public"."users" ADD CONSTRAINT "users_profile_image_id_fkey" FOREIGN KEY ("profile_image_id") REFERENCES "public"."files" ("id") ON DELETE SET NULL ON UPDATE CASCADE;
+CREATE INDEX "users_email_bidx_idx" ON "public"."users" (("data" ->> 'email_bidx'));
+CREATE INDEX "users_username_bidx_idx" ON "public"."users" (("data" ->> 'username_bidx'));
+CREATE INDEX "files_user_upload_type_idx" ON "public"."files" ("user_upload", "type");
+ALTER TABLE "public"."outbox" ADD CONSTRAINT "outbox_pkey" PRIMARY KEY ("id");
+CREATE INDEX "outbox_status_next_attempt_at_idx" ON "public"."outbox" ("status", "next_attempt_at");
+ALTER TABLE "public"."mail_deliveries" ADD CONSTRAINT "mail_deliveries_pkey" PRIMARY KEY ("id");
+CREATE INDEX "mail_deliveries_queue_message_id_idx" ON "public"."mail_deliveries" ("queue_message_id");
+ALTER TABLE "public"."webhook_endpoints" ADD CONSTRAINT "webhook_endpoints_pkey" PRIMARY KEY ("id");
+ALTER TABLE "public"."webhook_deliveries" ADD CONSTRAINT "webhook_deliveries_pkey" PRIMARY KEY ("id");
+ALTER TABLE "public"."webhook_deliveries" ADD CONSTRAINT "webhook_deliveries_endpoint_id_fkey" FOREIGN KEY ("endpoint_id") REFERENCES "public"."webhook_endpoints" ("id") ON DELETE CASCADE ON UPDATE CASCADE;
+CREATE INDEX "webhook_deliveries_status_next_attempt_at_idx" ON "public"."webhook_deliveries" ("status", "next_attempt_at");
+CREATE INDEX "webhook_deliveries_endpoint_id_created_at_idx" ON "public"."webhook_deliveries" ("endpoint_id", "created_at");
+ALTER TABLE "public"."partner_clients" ADD CONSTRAINT "partner_clients_pkey" PRIMARY KEY ("id");
+CREATE UNIQUE INDEX "partner_clients_client_id_key" ON "public"."partner_clients" ("client_id");
+ALTER TABLE "public"."api_keys" ADD CONSTRAINT "api_keys_pkey" PRIMARY KEY ("id");
+CREATE UNIQUE INDEX "api_keys_key_hash_key" ON "public"."api_keys" ("key_hash");
 
 
 BEGIN;
-INSERT INTO "public"."roles" VALUES ('d57bfbfe-4979-4809-a151-f6cd30de657b', '{"role_name": "Member", "description": "Default role for register user"}', '2020-02-17 14:41:11.322647', '2020-02-17 14:41:11.322647', NULL);
-INSERT INTO "public"."roles" VALUES ('381b7700-fd23-44b7-9d1f-befba9fa7d6a', '{"role_name": "Admin", "description": "Administrator"}', '2020-02-17 14:41:27.17323', '2020-02-17 14:41:27.17323', NULL);
+INSERT INTO "public"."roles" VALUES ('d57bfbfe-4979-4809-a151-f6cd30de657b', '{"role_name": "Member", "description": "Default role for register user", "quota": {"max_bytes": 104857600, "max_files": 200}}', '2020-02-17 14:41:11.322647', '2020-02-17 14:41:11.322647', NULL);
+INSERT INTO "public"."roles" VALUES ('381b7700-fd23-44b7-9d1f-befba9fa7d6a', '{"role_name": "Admin", "description": "Administrator", "quota": {"max_bytes": 0, "max_files": 0}}', '2020-02-17 14:41:27.17323', '2020-02-17 14:41:27.17323', NULL);
 COMMIT;
 
 BEGIN;
-INSERT INTO "public"."users" VALUES ('81b6e0e4-8be0-4656-aecf-e18a98c3a0a7', '{"email": "superadmin2@init.com", "status": {"is_active": true}, "password": "$2a$14$54ISc3vDoIG8bXr.S1TTxObKBiZ5dl9XDug2Grw1hRBTA2e5IEV4G", "username": "Superadmin 2"}', '381b7700-fd23-44b7-9d1f-befba9fa7d6a', '2020-11-23 02:48:55.863911', '2020-11-23 03:25:12.429625', NULL);
-INSERT INTO "public"."users" VALUES ('fff76956-5cf6-4b2a-b571-9e078fa31fbc', '{"email": "admin@test.com", "status": {"is_active": true}, "password": "$2a$14$MtGxJqQsXyGjggX8Q2hDpOZn85wI3FWCiw.R0mNcxe20Kz9phCbW2", "username": "admin"}', 'd57bfbfe-4979-4809-a151-f6cd30de657b', '2020-11-23 03:26:15.229383', '2020-11-23 03:34:20.921368', '2020-11-23 03:34:20.921368');
+INSERT INTO "public"."users" VALUES ('81b6e0e4-8be0-4656-aecf-e18a98c3a0a7', '{"email": "superadmin2@init.com", "status": {"is_active": true}, "password": "$2a$14$54ISc3vDoIG8bXr.S1TTxObKBiZ5dl9XDug2Grw1hRBTA2e5IEV4G", "username": "Superadmin 2"}', '381b7700-fd23-44b7-9d1f-befba9fa7d6a', NULL, '2020-11-23 02:48:55.863911', '2020-11-23 03:25:12.429625', NULL);
+INSERT INTO "public"."users" VALUES ('fff76956-5cf6-4b2a-b571-9e078fa31fbc', '{"email": "admin@test.com", "status": {"is_active": true}, "password": "$2a$14$MtGxJqQsXyGjggX8Q2hDpOZn85wI3FWCiw.R0mNcxe20Kz9phCbW2", "username": "admin"}', 'd57bfbfe-4979-4809-a151-f6cd30de657b', NULL, '2020-11-23 03:26:15.229383', '2020-11-23 03:34:20.921368', '2020-11-23 03:34:20.921368');
 COMMIT;
@@ -203,4 +203,52 @@ var (
 	InvalidEmail = "invalid_email"
 	// InvalidRegisterType ...
 	InvalidRegisterType = "invalid_register_type"
+	// ImageFormatMismatch real image format is different with the declared one
+	ImageFormatMismatch = "image_format_mismatch"
+	// InvalidFileURL remote file url is not valid or point to a non public address
+	InvalidFileURL = "invalid_file_url"
+	// DownloadFileError fail to download remote file
+	DownloadFileError = "download_file_error"
+	// InfectedFile antivirus found malware in the uploaded file
+	InfectedFile = "infected_file"
+	// ScannerUnavailable antivirus can not be reached and fail closed is used
+	ScannerUnavailable = "scanner_unavailable"
+	// StorageQuotaExceeded total uploaded size is over the role quota
+	StorageQuotaExceeded = "storage_quota_exceeded"
+	// FileCountQuotaExceeded total uploaded file is over the role quota
+	FileCountQuotaExceeded = "file_count_quota_exceeded"
+	// InvalidQueue queue name is not consumed by the worker
+	InvalidQueue = "invalid_queue"
+	// QueueUnavailable message broker can not be reached
+	QueueUnavailable = "queue_unavailable"
+	// InvalidMailTemplate mail template type is not found
+	InvalidMailTemplate = "invalid_mail_template"
+	// InvalidWebhookEvent webhook subscribe to unknown event type
+	InvalidWebhookEvent = "invalid_webhook_event"
+	// InactiveWebhook webhook endpoint is disabled
+	InactiveWebhook = "inactive_webhook"
+	// InvalidSignature signed request does not match the client secret
+	InvalidSignature = "invalid_signature"
+	// ExpiredSignature signed request timestamp is out of the tolerance
+	ExpiredSignature = "expired_signature"
+	// ReplayedNonce signed request nonce is used already
+	ReplayedNonce = "replayed_nonce"
+	// InvalidScope api key scope is not found
+	InvalidScope = "invalid_scope"
+	// InvalidExpiredAt ...
+	InvalidExpiredAt = "invalid_expired_at"
+	// InvalidAPIKey api key is not found or revoked
+	InvalidAPIKey = "invalid_api_key"
+	// ExpiredAPIKey ...
+	ExpiredAPIKey = "expired_api_key"
+	// ForbiddenIP request ip is not allowed for the api key
+	ForbiddenIP = "forbidden_ip"
+	// InsufficientScope api key does not have the scope of the route
+	InsufficientScope = "insufficient_scope"
+	// InvalidEncryptedPayload request body can not be decrypted
+	InvalidEncryptedPayload = "invalid_encrypted_payload"
+	// EncryptedPayloadRequired route accept only encrypted payload
+	EncryptedPayloadRequired = "encrypted_payload_required"
+	// ExpiredEncryptionSession encryption session is not found or expired
+	ExpiredEncryptionSession = "expired_encryption_session"
 )
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+var (
+	apiKeySelectString = `SELECT def."id", def."name", def."key_prefix", def."key_hash", def."scopes", def."allowed_ips",
+		def."expires_at", def."last_used_at", def."last_used_ip", def."created_by", def."revoked_at", def."created_at",
+		def."updated_at" FROM "api_keys" def`
+)
+
+func (model apiKeyModel) scanRows(rows *sql.Rows) (d APIKeyEntity, err error) {
+	err = rows.Scan(
+		&d.ID, &d.Name, &d.KeyPrefix, &d.KeyHash, &d.Scopes, &d.AllowedIPs, &d.ExpiresAt, &d.LastUsedAt, &d.LastUsedIP,
+		&d.CreatedBy, &d.RevokedAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+
+	return d, err
+}
+
+func (model apiKeyModel) scanRow(row *sql.Row) (d APIKeyEntity, err error) {
+	err = row.Scan(
+		&d.ID, &d.Name, &d.KeyPrefix, &d.KeyHash, &d.Scopes, &d.AllowedIPs, &d.ExpiresAt, &d.LastUsedAt, &d.LastUsedIP,
+		&d.CreatedBy, &d.RevokedAt, &d.CreatedAt, &d.UpdatedAt,
+	)
+
+	return d, err
+}
+
+// apiKeyModel ...
+type apiKeyModel struct {
+	DB *sql.DB
+}
+
+// IAPIKey ...
+type IAPIKey interface {
+	FindAll(offset, limit int) ([]APIKeyEntity, int, error)
+	FindByID(id string) (APIKeyEntity, error)
+	FindByHash(keyHash string) (APIKeyEntity, error)
+	Store(body APIKeyEntity, changedAt time.Time) (string, error)
+	Revoke(id string, changedAt time.Time) (string, error)
+	UpdateLastUsed(id, ip string, usedAt time.Time, interval time.Duration) error
+}
+
+// APIKeyEntity only the sha256 of the key is stored, scopes and allowed ips are json array
+type APIKeyEntity struct {
+	ID         string         `db:"id"`
+	Name       string         `db:"name"`
+	KeyPrefix  string         `db:"key_prefix"`
+	KeyHash    string         `db:"key_hash"`
+	Scopes     string         `db:"scopes"`
+	AllowedIPs string         `db:"allowed_ips"`
+	ExpiresAt  sql.NullTime   `db:"expires_at"`
+	LastUsedAt sql.NullString `db:"last_used_at"`
+	LastUsedIP sql.NullString `db:"last_used_ip"`
+	CreatedBy  sql.NullString `db:"created_by"`
+	RevokedAt  sql.NullString `db:"revoked_at"`
+	CreatedAt  string         `db:"created_at"`
+	UpdatedAt  string         `db:"updated_at"`
+}
+
+// NewAPIKeyModel ...
+func NewAPIKeyModel(db *sql.DB) IAPIKey {
+	return &apiKeyModel{DB: db}
+}
+
+// FindAll revoked keys are listed too
+func (model apiKeyModel) FindAll(offset, limit int) (res []APIKeyEntity, count int, err error) {
+	query := apiKeySelectString + ` ORDER BY def."created_at" DESC OFFSET $1 LIMIT $2`
+	rows, err := model.DB.Query(query, offset, limit)
+	if err != nil {
+		return res, count, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, count, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+	if err != nil {
+		return res, count, err
+	}
+
+	query = `SELECT COUNT(def."id") FROM "api_keys" def`
+	err = model.DB.QueryRow(query).Scan(&count)
+
+	return res, count, err
+}
+
+// FindByID ...
+func (model apiKeyModel) FindByID(id string) (res APIKeyEntity, err error) {
+	query := apiKeySelectString + ` WHERE def."id" = $1`
+	row := model.DB.QueryRow(query, id)
+	res, err = model.scanRow(row)
+
+	return res, err
+}
+
+// FindByHash not revoked key of the hash
+func (model apiKeyModel) FindByHash(keyHash string) (res APIKeyEntity, err error) {
+	query := apiKeySelectString + ` WHERE def."revoked_at" IS NULL AND def."key_hash" = $1`
+	row := model.DB.QueryRow(query, keyHash)
+	res, err = model.scanRow(row)
+
+	return res, err
+}
+
+// Store ...
+func (model apiKeyModel) Store(body APIKeyEntity, changedAt time.Time) (res string, err error) {
+	sql := `INSERT INTO "api_keys" ("name", "key_prefix", "key_hash", "scopes", "allowed_ips", "expires_at",
+		"created_by", "created_at", "updated_at") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $8) RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.Name, body.KeyPrefix, body.KeyHash, body.Scopes, body.AllowedIPs, body.ExpiresAt,
+		body.CreatedBy, changedAt).Scan(&res)
+
+	return res, err
+}
+
+// Revoke ...
+func (model apiKeyModel) Revoke(id string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "api_keys" SET "revoked_at" = $1, "updated_at" = $1 WHERE "revoked_at" IS NULL AND "id" = $2
+		RETURNING "id"`
+	err = model.DB.QueryRow(sql, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// UpdateLastUsed the row is written at most once per interval for the same ip
+func (model apiKeyModel) UpdateLastUsed(id, ip string, usedAt time.Time, interval time.Duration) (err error) {
+	sql := `UPDATE "api_keys" SET "last_used_at" = $1, "last_used_ip" = $2 WHERE "id" = $3 AND (
+		"last_used_at" IS NULL OR "last_used_at" < $4 OR "last_used_ip" IS DISTINCT FROM $2)`
+	_, err = model.DB.Exec(sql, usedAt, ip, id, usedAt.Add(-interval))
+
+	return err
+}
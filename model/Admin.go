@@ -15,12 +15,13 @@ var (
 		"def.created_at", "def.updated_at",
 	}
 
-	adminSelectString = `SELECT def.id, def."data" ->> 'email' as email, def."data" ->> 'password' as password, def."data" ->> 'username' as username, def."data" -> 'status' ->> 'is_active' as status, r."id" as role_id, r."data" ->> 'role_name' as role_name, def.created_at, def.updated_at, def.deleted_at FROM "users" def LEFT JOIN "roles" r ON r."id" = def."role_id"`
+	adminSelectString = `SELECT def.id, def."data" ->> 'email' as email, def."data" ->> 'email_enc' as email_enc, def."data" ->> 'password' as password, def."data" ->> 'username' as username, def."data" ->> 'username_enc' as username_enc, def."data" ->> 'language' as language, def."data" -> 'status' ->> 'is_active' as status, r."id" as role_id, r."data" ->> 'role_name' as role_name, def.profile_image_id, f.url as profile_image_url, f.status as profile_image_status, def.created_at, def.updated_at, def.deleted_at FROM "users" def LEFT JOIN "roles" r ON r."id" = def."role_id" LEFT JOIN "files" f ON f."id" = def."profile_image_id"`
 )
 
 func (model adminModel) scanRows(rows *sql.Rows) (d UserEntity, err error) {
 	err = rows.Scan(
-		&d.ID, &d.Email, &d.Password, &d.UserName, &d.Status, &d.RoleID, &d.Role.Name, &d.CreatedAt,
+		&d.ID, &d.Email, &d.EmailEnc, &d.Password, &d.UserName, &d.UserNameEnc, &d.Language, &d.Status, &d.RoleID, &d.Role.Name, &d.ProfileImageID,
+		&d.ProfileImageURL, &d.ProfileImageStatus, &d.CreatedAt,
 		&d.UpdatedAt, &d.DeletedAt,
 	)
 
@@ -29,7 +30,8 @@ func (model adminModel) scanRows(rows *sql.Rows) (d UserEntity, err error) {
 
 func (model adminModel) scanRow(row *sql.Row) (d UserEntity, err error) {
 	err = row.Scan(
-		&d.ID, &d.Email, &d.Password, &d.UserName, &d.Status, &d.RoleID, &d.Role.Name, &d.CreatedAt,
+		&d.ID, &d.Email, &d.EmailEnc, &d.Password, &d.UserName, &d.UserNameEnc, &d.Language, &d.Status, &d.RoleID, &d.Role.Name, &d.ProfileImageID,
+		&d.ProfileImageURL, &d.ProfileImageStatus, &d.CreatedAt,
 		&d.UpdatedAt, &d.DeletedAt,
 	)
 
@@ -38,31 +40,48 @@ func (model adminModel) scanRow(row *sql.Row) (d UserEntity, err error) {
 
 // adminModel ...
 type adminModel struct {
-	DB *sql.DB
+	DB Querier
 }
 
 // IAdmin ...
 type IAdmin interface {
-	FindAll(search string, offset, limit int, by, sort string) ([]UserEntity, int, error)
+	FindAll(search, emailIndex, userNameIndex string, offset, limit int, by, sort string) ([]UserEntity, int, error)
+	FindAllData(lastID string, limit int) ([]UserDataEntity, error)
 	FindByID(id string) (UserEntity, error)
-	FindByEmail(email string) (UserEntity, error)
+	FindByEmail(email, emailIndex string) (UserEntity, error)
 	Store(body viewmodel.UserVM, changedAt time.Time) (string, error)
 	Update(id string, body viewmodel.UserVM, changedAt time.Time) (string, error)
+	UpdateData(id, data string, changedAt time.Time) (string, error)
+	ReplaceData(id, oldData, data string) (string, error)
+	UpdateProfileImage(id, profileImageID string, changedAt time.Time) (string, error)
 	Destroy(id string, changedAt time.Time) (string, error)
 }
 
 // UserEntity ....
 type UserEntity struct {
-	ID        string         `db:"id"`
-	Email     sql.NullString `db:"email"`
-	Password  sql.NullString `db:"password"`
-	UserName  sql.NullString `db:"user_name"`
-	RoleID    sql.NullString `db:"role_id"`
-	Role      RoleEntity     `db:"role"`
-	Status    sql.NullBool   `db:"status"`
-	CreatedAt string         `db:"created_at"`
-	UpdatedAt string         `db:"updated_at"`
-	DeletedAt sql.NullString `db:"deleted_at"`
+	ID          string         `db:"id"`
+	Email       sql.NullString `db:"email"`
+	EmailEnc    sql.NullString `db:"email_enc"`
+	Password    sql.NullString `db:"password"`
+	UserName    sql.NullString `db:"user_name"`
+	UserNameEnc sql.NullString `db:"user_name_enc"`
+	Language    sql.NullString `db:"language"`
+	RoleID      sql.NullString `db:"role_id"`
+	Role        RoleEntity     `db:"role"`
+	Status      sql.NullBool   `db:"status"`
+	CreatedAt   string         `db:"created_at"`
+	UpdatedAt   string         `db:"updated_at"`
+	DeletedAt   sql.NullString `db:"deleted_at"`
+
+	ProfileImageID     sql.NullString `db:"profile_image_id"`
+	ProfileImageURL    sql.NullString `db:"profile_image_url"`
+	ProfileImageStatus sql.NullString `db:"profile_image_status"`
+}
+
+// UserDataEntity raw data json of the user
+type UserDataEntity struct {
+	ID   string `db:"id"`
+	Data string `db:"data"`
 }
 
 // NewAdminModel ...
@@ -70,13 +89,19 @@ func NewAdminModel(db *sql.DB) IAdmin {
 	return &adminModel{DB: db}
 }
 
-// FindAll ...
-func (model adminModel) FindAll(search string, offset, limit int, by, sort string) (res []UserEntity, count int, err error) {
+// NewAdminModelTx ...
+func NewAdminModelTx(tx *sql.Tx) IAdmin {
+	return &adminModel{DB: tx}
+}
+
+// FindAll plaintext field is searched by substring, encrypted field only by the exact blind index
+func (model adminModel) FindAll(search, emailIndex, userNameIndex string, offset, limit int, by, sort string) (res []UserEntity, count int, err error) {
 	query := adminSelectString + ` WHERE def."deleted_at" IS NULL AND (
 	LOWER (def."data" ->> 'email' ) LIKE $1 
 	OR LOWER ( def."data" ->> 'username' ) LIKE $1 
+	OR def."data" ->> 'email_bidx' = $4 OR def."data" ->> 'username_bidx' = $5
 	) ORDER BY ` + by + ` ` + sort + ` OFFSET $2 LIMIT $3`
-	rows, err := model.DB.Query(query, `%`+strings.ToLower(search)+`%`, offset, limit)
+	rows, err := model.DB.Query(query, `%`+strings.ToLower(search)+`%`, offset, limit, emailIndex, userNameIndex)
 	if err != nil {
 		return res, count, err
 	}
@@ -98,8 +123,9 @@ func (model adminModel) FindAll(search string, offset, limit int, by, sort strin
 		LEFT JOIN "roles" r ON r."id" = def."role_id"
 		WHERE def."deleted_at" IS NULL AND (
 			LOWER (def."data" ->> 'email' ) LIKE $1 
-			OR LOWER ( def."data" ->> 'username' ) like $1 )`
-	err = model.DB.QueryRow(query, `%`+strings.ToLower(search)+`%`).Scan(&count)
+			OR LOWER ( def."data" ->> 'username' ) like $1
+			OR def."data" ->> 'email_bidx' = $2 OR def."data" ->> 'username_bidx' = $3 )`
+	err = model.DB.QueryRow(query, `%`+strings.ToLower(search)+`%`, emailIndex, userNameIndex).Scan(&count)
 
 	return res, count, err
 }
@@ -114,10 +140,33 @@ func (model adminModel) FindByID(id string) (res UserEntity, err error) {
 	return res, err
 }
 
-// FindByEmail ...
-func (model adminModel) FindByEmail(email string) (res UserEntity, err error) {
-	query := adminSelectString + ` WHERE def."deleted_at" IS NULL  AND LOWER (def."data" ->> 'email' ) = $1 ORDER BY def."created_at" DESC  LIMIT 1`
-	row := model.DB.QueryRow(query, strings.ToLower(email))
+// FindAllData raw data of the users after lastID ordered by id, deleted user is included
+func (model adminModel) FindAllData(lastID string, limit int) (res []UserDataEntity, err error) {
+	query := `SELECT def."id", def."data" FROM "users" def WHERE def."id" > $1 ORDER BY def."id" LIMIT $2`
+	rows, err := model.DB.Query(query, lastID, limit)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d := UserDataEntity{}
+		err = rows.Scan(&d.ID, &d.Data)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
+
+// FindByEmail match the plaintext email or the blind index of the encrypted email
+func (model adminModel) FindByEmail(email, emailIndex string) (res UserEntity, err error) {
+	query := adminSelectString + ` WHERE def."deleted_at" IS NULL  AND (LOWER (def."data" ->> 'email' ) = $1
+		OR def."data" ->> 'email_bidx' = $2) ORDER BY def."created_at" DESC  LIMIT 1`
+	row := model.DB.QueryRow(query, strings.ToLower(email), emailIndex)
 	res, err = model.scanRow(row)
 
 	return res, err
@@ -142,6 +191,32 @@ func (model adminModel) Update(id string, body viewmodel.UserVM, changedAt time.
 	return res, err
 }
 
+// UpdateData merge data json into existing data
+func (model adminModel) UpdateData(id, data string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "users" SET "data" = "data" || $1::jsonb, "updated_at" = $2 WHERE "deleted_at" IS NULL
+		AND "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, data, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// ReplaceData the data is replaced only when it is not changed since it is read, updated_at is kept
+func (model adminModel) ReplaceData(id, oldData, data string) (res string, err error) {
+	sql := `UPDATE "users" SET "data" = $1 WHERE "id" = $2 AND "data" = $3::jsonb RETURNING "id"`
+	err = model.DB.QueryRow(sql, data, id, oldData).Scan(&res)
+
+	return res, err
+}
+
+// UpdateProfileImage ...
+func (model adminModel) UpdateProfileImage(id, profileImageID string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "users" SET "profile_image_id" = $1, "updated_at" = $2 WHERE "deleted_at" IS NULL
+		AND "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, profileImageID, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
 // Destroy ...
 func (model adminModel) Destroy(id string, changedAt time.Time) (res string, err error) {
 	sql := `UPDATE "users" SET "updated_at" = $1, "deleted_at" = $1
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"database/sql"
+)
+
+var (
+	// EncryptedColumns every column encrypted by the aes credential, the table, column and field is not user input
+	EncryptedColumns = []EncryptedColumn{
+		{Table: "webhook_endpoints", Column: "secret"},
+		{Table: "partner_clients", Column: "secret"},
+		{Table: "users", Column: "data", Field: "email_enc"},
+		{Table: "users", Column: "data", Field: "username_enc"},
+	}
+)
+
+// EncryptedColumn field is the key inside jsonb column, empty when the column itself is encrypted
+type EncryptedColumn struct {
+	Table  string
+	Column string
+	Field  string
+}
+
+// value ...
+func (column EncryptedColumn) value() string {
+	if column.Field != "" {
+		return `"` + column.Column + `" ->> '` + column.Field + `'`
+	}
+
+	return `"` + column.Column + `"`
+}
+
+// set ...
+func (column EncryptedColumn) set(param string) string {
+	if column.Field != "" {
+		return `"` + column.Column + `" = jsonb_set("` + column.Column + `", '{` + column.Field + `}', to_jsonb(` +
+			param + `::text))`
+	}
+
+	return `"` + column.Column + `" = ` + param
+}
+
+// encryptedColumnModel ...
+type encryptedColumnModel struct {
+	DB     Querier
+	Column EncryptedColumn
+}
+
+// IEncryptedColumn ...
+type IEncryptedColumn interface {
+	FindAll(lastID string, limit int) ([]EncryptedColumnEntity, error)
+	Update(id, old, value string) (string, error)
+}
+
+// EncryptedColumnEntity ...
+type EncryptedColumnEntity struct {
+	ID    string `db:"id"`
+	Value string `db:"value"`
+}
+
+// NewEncryptedColumnModel ...
+func NewEncryptedColumnModel(db *sql.DB, column EncryptedColumn) IEncryptedColumn {
+	return &encryptedColumnModel{DB: db, Column: column}
+}
+
+// FindAll rows after lastID ordered by id, deleted row is included
+func (model encryptedColumnModel) FindAll(lastID string, limit int) (res []EncryptedColumnEntity, err error) {
+	query := `SELECT "id", ` + model.Column.value() + ` FROM "` + model.Column.Table + `"
+		WHERE "id" > $1 AND ` + model.Column.value() + ` IS NOT NULL ORDER BY "id" LIMIT $2`
+	rows, err := model.DB.Query(query, lastID, limit)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d := EncryptedColumnEntity{}
+		err = rows.Scan(&d.ID, &d.Value)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
+
+// Update the value is replaced only when it is not changed since it is read, updated_at is kept
+func (model encryptedColumnModel) Update(id, old, value string) (res string, err error) {
+	sql := `UPDATE "` + model.Column.Table + `" SET ` + model.Column.set("$1") + `
+		WHERE "id" = $2 AND ` + model.Column.value() + ` = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, value, id, old).Scan(&res)
+
+	return res, err
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"database/sql"
+
+	"github.com/lib/pq"
 )
 
 var (
@@ -12,19 +14,32 @@ var (
 	FileAdminProfile = "admin_profile"
 	// FileUserProfile ...
 	FileUserProfile = "user_profile"
+	// FileHTMLPicture picture for html content, stored in public html picture folder
+	FileHTMLPicture = "html_picture"
 	// FileWhitelist ...
-	FileWhitelist = []string{FileAdminProfile, FileUserProfile}
-
-	fileSelectString = `SELECT f."id", f."type", f."url", f."user_upload", f."created_at", f."updated_at",
-	f."deleted_at" FROM "files" f
-	LEFT JOIN "admins" admins ON admins."profile_image_id" = f."id"
+	FileWhitelist = []string{FileAdminProfile, FileUserProfile, FileHTMLPicture}
+	// FileAssignableWhitelist file type which must be claimed by a user record
+	FileAssignableWhitelist = []string{FileAdminProfile, FileUserProfile}
+
+	// FileStatusPending waiting for image processing
+	FileStatusPending = "pending"
+	// FileStatusProcessed ...
+	FileStatusProcessed = "processed"
+	// FileStatusFailed image processing failed
+	FileStatusFailed = "failed"
+	// FileStatusQuarantined antivirus found malware in the file, it is never served
+	FileStatusQuarantined = "quarantined"
+
+	fileSelectString = `SELECT f."id", f."type", f."url", f."user_upload", f."status", f."thumbnails", f."size",
+	f."created_at", f."updated_at", f."deleted_at" FROM "files" f
 	LEFT JOIN "users" users ON users."profile_image_id" = f."id"`
-	unassignedQueryString = `AND admins."id" IS NULL AND users."id" IS NULL`
+	unassignedQueryString = `AND users."id" IS NULL`
 )
 
 func (model fileModel) scanRows(rows *sql.Rows) (d FileEntity, err error) {
 	err = rows.Scan(
-		&d.ID, &d.Type, &d.URL, &d.UserUpload, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+		&d.ID, &d.Type, &d.URL, &d.UserUpload, &d.Status, &d.Thumbnails, &d.Size, &d.CreatedAt, &d.UpdatedAt,
+		&d.DeletedAt,
 	)
 
 	return d, err
@@ -32,7 +47,8 @@ func (model fileModel) scanRows(rows *sql.Rows) (d FileEntity, err error) {
 
 func (model fileModel) scanRow(row *sql.Row) (d FileEntity, err error) {
 	err = row.Scan(
-		&d.ID, &d.Type, &d.URL, &d.UserUpload, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+		&d.ID, &d.Type, &d.URL, &d.UserUpload, &d.Status, &d.Thumbnails, &d.Size, &d.CreatedAt, &d.UpdatedAt,
+		&d.DeletedAt,
 	)
 
 	return d, err
@@ -41,10 +57,27 @@ func (model fileModel) scanRow(row *sql.Row) (d FileEntity, err error) {
 // IFile ...
 type IFile interface {
 	FindAllUnassignedByUserID(userUpload, types string) (data []FileEntity, err error)
+	FindAllUnassignedBefore(types []string, before time.Time, limit int) (data []FileEntity, err error)
 	FindByID(id string) (FileEntity, error)
 	FindUnassignedByID(id, types, userUpload string) (FileEntity, error)
 	Store(body viewmodel.FileVM, changedAt time.Time) (string, error)
+	UpdateProcessed(id, status, thumbnails string, size int64, changedAt time.Time) (string, error)
 	Destroy(id string, changedAt time.Time) (string, error)
+	LockUser(userUpload string) error
+	UsageByUserID(userUpload string) (FileUsageEntity, error)
+	SelectUsage(userUpload string) ([]FileUsageEntity, error)
+}
+
+// FileUsageEntity total size and count of not deleted files
+type FileUsageEntity struct {
+	UserUpload  sql.NullString `db:"user_upload"`
+	UserName    sql.NullString `db:"username"`
+	UserNameEnc sql.NullString `db:"username_enc"`
+	Type        sql.NullString `db:"type"`
+	Files       int64          `db:"files"`
+	Bytes       int64          `db:"bytes"`
+	MaxBytes    sql.NullInt64  `db:"max_bytes"`
+	MaxFiles    sql.NullInt64  `db:"max_files"`
 }
 
 // FileEntity ....
@@ -53,6 +86,9 @@ type FileEntity struct {
 	Type       sql.NullString `db:"type"`
 	URL        sql.NullString `db:"url"`
 	UserUpload sql.NullString `db:"user_upload"`
+	Status     sql.NullString `db:"status"`
+	Thumbnails sql.NullString `db:"thumbnails"`
+	Size       sql.NullInt64  `db:"size"`
 	CreatedAt  string         `db:"created_at"`
 	UpdatedAt  string         `db:"updated_at"`
 	DeletedAt  sql.NullString `db:"deleted_at"`
@@ -60,7 +96,7 @@ type FileEntity struct {
 
 // fileModel ...
 type fileModel struct {
-	DB *sql.DB
+	DB Querier
 }
 
 // NewFileModel ...
@@ -68,6 +104,11 @@ func NewFileModel(db *sql.DB) IFile {
 	return &fileModel{DB: db}
 }
 
+// NewFileModelTx ...
+func NewFileModelTx(tx *sql.Tx) IFile {
+	return &fileModel{DB: tx}
+}
+
 // FindAllUnassignedByUserID ...
 func (model fileModel) FindAllUnassignedByUserID(userUpload, types string) (res []FileEntity, err error) {
 	query := fileSelectString + ` WHERE f."deleted_at" IS NULL AND f."user_upload" = $1 AND f."type" = $2
@@ -91,10 +132,33 @@ func (model fileModel) FindAllUnassignedByUserID(userUpload, types string) (res
 	return res, err
 }
 
+// FindAllUnassignedBefore ...
+func (model fileModel) FindAllUnassignedBefore(types []string, before time.Time, limit int) (res []FileEntity, err error) {
+	query := fileSelectString + ` WHERE f."deleted_at" IS NULL AND f."type" = ANY($1) AND f."created_at" < $2
+		AND COALESCE(f."status", '') <> $4 ` + unassignedQueryString + ` ORDER BY f."created_at" LIMIT $3`
+
+	rows, err := model.DB.Query(query, pq.Array(types), before, limit, FileStatusQuarantined)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
+
 // FindByID ...
 func (model fileModel) FindByID(id string) (res FileEntity, err error) {
-	query := `SELECT "id", "type", "url", "user_upload", "created_at", "updated_at", "deleted_at"
-		FROM "files" WHERE "deleted_at" IS NULL AND "id" = $1
+	query := `SELECT "id", "type", "url", "user_upload", "status", "thumbnails", "size", "created_at", "updated_at",
+		"deleted_at" FROM "files" WHERE "deleted_at" IS NULL AND "id" = $1
 		ORDER BY "created_at" DESC LIMIT 1`
 	row := model.DB.QueryRow(query, id)
 	res, err = model.scanRow(row)
@@ -115,9 +179,18 @@ func (model fileModel) FindUnassignedByID(id, types, userUpload string) (res Fil
 // Store ...
 func (model fileModel) Store(body viewmodel.FileVM, changedAt time.Time) (res string, err error) {
 	sql :=
-		`INSERT INTO "files" ("type", "url", "user_upload", "created_at", "updated_at")
-		VALUES($1, $2, $3, $4, $4) RETURNING "id"`
-	err = model.DB.QueryRow(sql, body.Type, body.URL, body.UserUpload, changedAt).Scan(&res)
+		`INSERT INTO "files" ("type", "url", "user_upload", "status", "size", "created_at", "updated_at")
+		VALUES($1, $2, $3, $4, $5, $6, $6) RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.Type, body.URL, body.UserUpload, body.Status, body.Size, changedAt).Scan(&res)
+
+	return res, err
+}
+
+// UpdateProcessed ...
+func (model fileModel) UpdateProcessed(id, status, thumbnails string, size int64, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "files" SET "status" = $1, "thumbnails" = $2, "size" = $3, "updated_at" = $4
+		WHERE "deleted_at" IS NULL AND "id" = $5 RETURNING "id"`
+	err = model.DB.QueryRow(sql, status, thumbnails, size, changedAt, id).Scan(&res)
 
 	return res, err
 }
@@ -129,3 +202,49 @@ func (model fileModel) Destroy(id string, changedAt time.Time) (res string, err
 
 	return res, err
 }
+
+// LockUser serialize the uploads of the user until the transaction end, must be called inside a transaction
+func (model fileModel) LockUser(userUpload string) (err error) {
+	_, err = model.DB.Exec(`SELECT pg_advisory_xact_lock(hashtext('files_quota:' || $1))`, userUpload)
+
+	return err
+}
+
+// UsageByUserID ...
+func (model fileModel) UsageByUserID(userUpload string) (res FileUsageEntity, err error) {
+	query := `SELECT COUNT("id"), COALESCE(SUM("size"), 0) FROM "files"
+		WHERE "deleted_at" IS NULL AND "user_upload" = $1`
+	err = model.DB.QueryRow(query, userUpload).Scan(&res.Files, &res.Bytes)
+	res.UserUpload = sql.NullString{String: userUpload, Valid: true}
+
+	return res, err
+}
+
+// SelectUsage usage grouped by user and file type, empty userUpload select every user
+func (model fileModel) SelectUsage(userUpload string) (res []FileUsageEntity, err error) {
+	query := `SELECT f."user_upload", u."data" ->> 'username' as username, u."data" ->> 'username_enc' as username_enc,
+		f."type", COUNT(f."id"), COALESCE(SUM(f."size"), 0), (r."data" -> 'quota' ->> 'max_bytes')::bigint as max_bytes,
+		(r."data" -> 'quota' ->> 'max_files')::bigint as max_files
+		FROM "files" f LEFT JOIN "users" u ON u."id" = f."user_upload" LEFT JOIN "roles" r ON r."id" = u."role_id"
+		WHERE f."deleted_at" IS NULL AND ($1 = '' OR f."user_upload" = $1)
+		GROUP BY f."user_upload", u."data" ->> 'username', u."data" ->> 'username_enc', f."type", r."data" -> 'quota'
+		ORDER BY f."user_upload", f."type"`
+
+	rows, err := model.DB.Query(query, userUpload)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		d := FileUsageEntity{}
+		err = rows.Scan(&d.UserUpload, &d.UserName, &d.UserNameEnc, &d.Type, &d.Files, &d.Bytes, &d.MaxBytes, &d.MaxFiles)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
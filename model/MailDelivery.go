@@ -0,0 +1,63 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+var (
+	// MailDeliveryStatusSent ...
+	MailDeliveryStatusSent = "sent"
+	// MailDeliveryStatusQueued accepted by the provider but not delivered yet
+	MailDeliveryStatusQueued = "queued"
+	// MailDeliveryStatusFailed ...
+	MailDeliveryStatusFailed = "failed"
+)
+
+// mailDeliveryModel ...
+type mailDeliveryModel struct {
+	DB *sql.DB
+}
+
+// IMailDelivery ...
+type IMailDelivery interface {
+	Store(body MailDeliveryEntity, changedAt time.Time) (string, error)
+	ExistDelivered(queueMessageID string) (bool, error)
+}
+
+// MailDeliveryEntity one delivery attempt of the queued mail
+type MailDeliveryEntity struct {
+	ID                string         `db:"id"`
+	QueueMessageID    string         `db:"queue_message_id"`
+	Template          string         `db:"template"`
+	Recipient         string         `db:"recipient"`
+	Driver            string         `db:"driver"`
+	Status            string         `db:"status"`
+	ProviderMessageID sql.NullString `db:"provider_message_id"`
+	Error             sql.NullString `db:"error"`
+	Attempt           int            `db:"attempt"`
+	CreatedAt         string         `db:"created_at"`
+}
+
+// NewMailDeliveryModel ...
+func NewMailDeliveryModel(db *sql.DB) IMailDelivery {
+	return &mailDeliveryModel{DB: db}
+}
+
+// Store ...
+func (model mailDeliveryModel) Store(body MailDeliveryEntity, changedAt time.Time) (res string, err error) {
+	sql := `INSERT INTO "mail_deliveries" ("queue_message_id", "template", "recipient", "driver", "status",
+		"provider_message_id", "error", "attempt", "created_at") VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.QueueMessageID, body.Template, body.Recipient, body.Driver, body.Status,
+		body.ProviderMessageID, body.Error, body.Attempt, changedAt).Scan(&res)
+
+	return res, err
+}
+
+// ExistDelivered check whether the queued mail is accepted by the provider already, so redelivery does not send it twice
+func (model mailDeliveryModel) ExistDelivered(queueMessageID string) (res bool, err error) {
+	query := `SELECT EXISTS(SELECT 1 FROM "mail_deliveries" WHERE "queue_message_id" = $1 AND "status" IN ($2, $3))`
+	err = model.DB.QueryRow(query, queueMessageID, MailDeliveryStatusSent, MailDeliveryStatusQueued).Scan(&res)
+
+	return res, err
+}
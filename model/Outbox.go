@@ -0,0 +1,133 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+var (
+	// OutboxStatusPending ...
+	OutboxStatusPending = "pending"
+	// OutboxStatusSent ...
+	OutboxStatusSent = "sent"
+
+	outboxSelectString = `SELECT def."id", def."exchange", def."routing_key", def."dead_letter_key", def."payload", def."status",
+		def."attempts", def."last_error", def."next_attempt_at", def."created_at", def."sent_at" FROM "outbox" def`
+)
+
+func (model outboxModel) scanRows(rows *sql.Rows) (d OutboxEntity, err error) {
+	err = rows.Scan(
+		&d.ID, &d.Exchange, &d.RoutingKey, &d.DeadLetterKey, &d.Payload, &d.Status, &d.Attempts, &d.LastError,
+		&d.NextAttemptAt, &d.CreatedAt, &d.SentAt,
+	)
+
+	return d, err
+}
+
+// outboxModel ...
+type outboxModel struct {
+	DB Querier
+}
+
+// IOutbox ...
+type IOutbox interface {
+	Claim(now time.Time, lease time.Duration, limit int) ([]OutboxEntity, error)
+	Store(exchange, routingKey, deadLetterKey, payload string, changedAt time.Time) (string, error)
+	MarkSent(id string, changedAt time.Time) (string, error)
+	MarkRetry(id, lastError string, nextAttemptAt time.Time) (string, error)
+	Lag(now time.Time) (OutboxLagEntity, error)
+}
+
+// OutboxEntity ....
+type OutboxEntity struct {
+	ID            string         `db:"id"`
+	Exchange      string         `db:"exchange"`
+	RoutingKey    string         `db:"routing_key"`
+	DeadLetterKey string         `db:"dead_letter_key"`
+	Payload       string         `db:"payload"`
+	Status        string         `db:"status"`
+	Attempts      int            `db:"attempts"`
+	LastError     sql.NullString `db:"last_error"`
+	NextAttemptAt string         `db:"next_attempt_at"`
+	CreatedAt     string         `db:"created_at"`
+	SentAt        sql.NullString `db:"sent_at"`
+}
+
+// OutboxLagEntity ...
+type OutboxLagEntity struct {
+	Pending    int64   `db:"pending"`
+	LagSeconds float64 `db:"lag_seconds"`
+}
+
+// NewOutboxModel ...
+func NewOutboxModel(db *sql.DB) IOutbox {
+	return &outboxModel{DB: db}
+}
+
+// NewOutboxModelTx outbox row must be written in the same transaction as the business change
+func NewOutboxModelTx(tx *sql.Tx) IOutbox {
+	return &outboxModel{DB: tx}
+}
+
+// Claim take the due rows by moving their next attempt forward by lease, the row lock is held only by
+// this statement. Row of crashed relay is taken again after the lease
+func (model outboxModel) Claim(now time.Time, lease time.Duration, limit int) (res []OutboxEntity, err error) {
+	query := `UPDATE "outbox" def SET "next_attempt_at" = $1 WHERE def."id" IN (
+			SELECT o."id" FROM "outbox" o WHERE o."status" = $2 AND o."next_attempt_at" <= $3
+			ORDER BY o."created_at" LIMIT $4 FOR UPDATE SKIP LOCKED
+		) RETURNING def."id", def."exchange", def."routing_key", def."dead_letter_key", def."payload", def."status",
+		def."attempts", def."last_error", def."next_attempt_at", def."created_at", def."sent_at"`
+
+	rows, err := model.DB.Query(query, now.Add(lease), OutboxStatusPending, now, limit)
+	if err != nil {
+		return res, err
+	}
+
+	defer rows.Close()
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
+
+// Store exchange is empty when the message is published straight into the routing key queue
+func (model outboxModel) Store(exchange, routingKey, deadLetterKey, payload string, changedAt time.Time) (res string, err error) {
+	sql := `INSERT INTO "outbox" ("exchange", "routing_key", "dead_letter_key", "payload", "status", "next_attempt_at",
+		"created_at") VALUES($1, $2, $3, $4, $5, $6, $6) RETURNING "id"`
+	err = model.DB.QueryRow(sql, exchange, routingKey, deadLetterKey, payload, OutboxStatusPending, changedAt).Scan(&res)
+
+	return res, err
+}
+
+// MarkSent ...
+func (model outboxModel) MarkSent(id string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "outbox" SET "status" = $1, "attempts" = "attempts" + 1, "last_error" = NULL, "sent_at" = $2
+		WHERE "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, OutboxStatusSent, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// MarkRetry ...
+func (model outboxModel) MarkRetry(id, lastError string, nextAttemptAt time.Time) (res string, err error) {
+	sql := `UPDATE "outbox" SET "attempts" = "attempts" + 1, "last_error" = $1, "next_attempt_at" = $2
+		WHERE "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, lastError, nextAttemptAt, id).Scan(&res)
+
+	return res, err
+}
+
+// Lag count pending rows and the age of the oldest one
+func (model outboxModel) Lag(now time.Time) (res OutboxLagEntity, err error) {
+	query := `SELECT COUNT("id"), COALESCE(EXTRACT(EPOCH FROM ($2 - MIN("created_at"))), 0)
+		FROM "outbox" WHERE "status" = $1`
+	err = model.DB.QueryRow(query, OutboxStatusPending, now).Scan(&res.Pending, &res.LagSeconds)
+
+	return res, err
+}
@@ -0,0 +1,152 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+var (
+	partnerClientSelectString = `SELECT def."id", def."client_id", def."name", def."secret", def."is_active",
+		def."last_used_at", def."created_at", def."updated_at", def."deleted_at" FROM "partner_clients" def`
+)
+
+func (model partnerClientModel) scanRows(rows *sql.Rows) (d PartnerClientEntity, err error) {
+	err = rows.Scan(
+		&d.ID, &d.ClientID, &d.Name, &d.Secret, &d.IsActive, &d.LastUsedAt, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+	)
+
+	return d, err
+}
+
+func (model partnerClientModel) scanRow(row *sql.Row) (d PartnerClientEntity, err error) {
+	err = row.Scan(
+		&d.ID, &d.ClientID, &d.Name, &d.Secret, &d.IsActive, &d.LastUsedAt, &d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+	)
+
+	return d, err
+}
+
+// partnerClientModel ...
+type partnerClientModel struct {
+	DB *sql.DB
+}
+
+// IPartnerClient ...
+type IPartnerClient interface {
+	FindAll(offset, limit int) ([]PartnerClientEntity, int, error)
+	FindByID(id string) (PartnerClientEntity, error)
+	FindByClientID(clientID string) (PartnerClientEntity, error)
+	Store(body PartnerClientEntity, changedAt time.Time) (string, error)
+	UpdateStatus(id string, isActive bool, changedAt time.Time) (string, error)
+	UpdateSecret(id, secret string, changedAt time.Time) (string, error)
+	UpdateLastUsed(id string, usedAt time.Time) (string, error)
+	Destroy(id string, changedAt time.Time) (string, error)
+}
+
+// PartnerClientEntity secret is encrypted, it is needed in plain to verify the hmac signature
+type PartnerClientEntity struct {
+	ID         string         `db:"id"`
+	ClientID   string         `db:"client_id"`
+	Name       string         `db:"name"`
+	Secret     string         `db:"secret"`
+	IsActive   bool           `db:"is_active"`
+	LastUsedAt sql.NullString `db:"last_used_at"`
+	CreatedAt  string         `db:"created_at"`
+	UpdatedAt  string         `db:"updated_at"`
+	DeletedAt  sql.NullString `db:"deleted_at"`
+}
+
+// NewPartnerClientModel ...
+func NewPartnerClientModel(db *sql.DB) IPartnerClient {
+	return &partnerClientModel{DB: db}
+}
+
+// FindAll ...
+func (model partnerClientModel) FindAll(offset, limit int) (res []PartnerClientEntity, count int, err error) {
+	query := partnerClientSelectString + ` WHERE def."deleted_at" IS NULL
+		ORDER BY def."created_at" DESC OFFSET $1 LIMIT $2`
+	rows, err := model.DB.Query(query, offset, limit)
+	if err != nil {
+		return res, count, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, count, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+	if err != nil {
+		return res, count, err
+	}
+
+	query = `SELECT COUNT(def."id") FROM "partner_clients" def WHERE def."deleted_at" IS NULL`
+	err = model.DB.QueryRow(query).Scan(&count)
+
+	return res, count, err
+}
+
+// FindByID ...
+func (model partnerClientModel) FindByID(id string) (res PartnerClientEntity, err error) {
+	query := partnerClientSelectString + ` WHERE def."deleted_at" IS NULL AND def."id" = $1`
+	row := model.DB.QueryRow(query, id)
+	res, err = model.scanRow(row)
+
+	return res, err
+}
+
+// FindByClientID ...
+func (model partnerClientModel) FindByClientID(clientID string) (res PartnerClientEntity, err error) {
+	query := partnerClientSelectString + ` WHERE def."deleted_at" IS NULL AND def."client_id" = $1`
+	row := model.DB.QueryRow(query, clientID)
+	res, err = model.scanRow(row)
+
+	return res, err
+}
+
+// Store ...
+func (model partnerClientModel) Store(body PartnerClientEntity, changedAt time.Time) (res string, err error) {
+	sql := `INSERT INTO "partner_clients" ("client_id", "name", "secret", "is_active", "created_at", "updated_at")
+		VALUES($1, $2, $3, $4, $5, $5) RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.ClientID, body.Name, body.Secret, body.IsActive, changedAt).Scan(&res)
+
+	return res, err
+}
+
+// UpdateStatus ...
+func (model partnerClientModel) UpdateStatus(id string, isActive bool, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "partner_clients" SET "is_active" = $1, "updated_at" = $2 WHERE "deleted_at" IS NULL
+		AND "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, isActive, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// UpdateSecret ...
+func (model partnerClientModel) UpdateSecret(id, secret string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "partner_clients" SET "secret" = $1, "updated_at" = $2 WHERE "deleted_at" IS NULL
+		AND "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, secret, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// UpdateLastUsed ...
+func (model partnerClientModel) UpdateLastUsed(id string, usedAt time.Time) (res string, err error) {
+	sql := `UPDATE "partner_clients" SET "last_used_at" = $1 WHERE "id" = $2 RETURNING "id"`
+	err = model.DB.QueryRow(sql, usedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// Destroy ...
+func (model partnerClientModel) Destroy(id string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "partner_clients" SET "is_active" = false, "updated_at" = $1, "deleted_at" = $1
+		WHERE "deleted_at" IS NULL AND "id" = $2 RETURNING "id"`
+	err = model.DB.QueryRow(sql, changedAt, id).Scan(&res)
+
+	return res, err
+}
@@ -46,6 +46,13 @@ type IRole interface {
 	SelectAll(search, by, sort string) ([]RoleEntity, error)
 	FindByID(id string) (RoleEntity, error)
 	FindByCode(code string) (RoleEntity, error)
+	FindQuotaByUserID(userID string) (RoleQuotaEntity, error)
+}
+
+// RoleQuotaEntity file quota from role data "quota", null or 0 means unlimited
+type RoleQuotaEntity struct {
+	MaxBytes sql.NullInt64 `db:"max_bytes"`
+	MaxFiles sql.NullInt64 `db:"max_files"`
 }
 
 // RoleEntity ....
@@ -106,3 +113,12 @@ func (model roleModel) FindByCode(code string) (res RoleEntity, err error) {
 
 	return res, err
 }
+
+// FindQuotaByUserID ...
+func (model roleModel) FindQuotaByUserID(userID string) (res RoleQuotaEntity, err error) {
+	query := `SELECT (r."data" -> 'quota' ->> 'max_bytes')::bigint, (r."data" -> 'quota' ->> 'max_files')::bigint
+		FROM "users" u JOIN "roles" r ON r."id" = u."role_id" WHERE u."id" = $1`
+	err = model.DB.QueryRow(query, userID).Scan(&res.MaxBytes, &res.MaxFiles)
+
+	return res, err
+}
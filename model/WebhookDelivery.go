@@ -0,0 +1,166 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+var (
+	// WebhookDeliveryStatusPending ...
+	WebhookDeliveryStatusPending = "pending"
+	// WebhookDeliveryStatusSuccess ...
+	WebhookDeliveryStatusSuccess = "success"
+	// WebhookDeliveryStatusFailed no attempt left
+	WebhookDeliveryStatusFailed = "failed"
+
+	webhookDeliverySelectString = `SELECT def."id", def."endpoint_id", def."event_id", def."event_type", def."payload",
+		def."status", def."attempts", def."next_attempt_at", def."response_status", def."response_body", def."last_error",
+		def."duration_ms", def."created_at", def."delivered_at" FROM "webhook_deliveries" def`
+)
+
+func (model webhookDeliveryModel) scanRows(rows *sql.Rows) (d WebhookDeliveryEntity, err error) {
+	err = rows.Scan(
+		&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseStatus, &d.ResponseBody, &d.LastError, &d.DurationMs, &d.CreatedAt, &d.DeliveredAt,
+	)
+
+	return d, err
+}
+
+func (model webhookDeliveryModel) scanRow(row *sql.Row) (d WebhookDeliveryEntity, err error) {
+	err = row.Scan(
+		&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseStatus, &d.ResponseBody, &d.LastError, &d.DurationMs, &d.CreatedAt, &d.DeliveredAt,
+	)
+
+	return d, err
+}
+
+// webhookDeliveryModel ...
+type webhookDeliveryModel struct {
+	DB Querier
+}
+
+// IWebhookDelivery ...
+type IWebhookDelivery interface {
+	FindAllByEndpoint(endpointID, status string, offset, limit int) ([]WebhookDeliveryEntity, int, error)
+	FindByID(endpointID, id string) (WebhookDeliveryEntity, error)
+	Claim(now time.Time, lease time.Duration, limit int) ([]WebhookDeliveryEntity, error)
+	Store(endpointID, eventID, eventType, payload string, nextAttemptAt, changedAt time.Time) (string, error)
+	MarkAttempt(id string, body WebhookDeliveryEntity) (string, error)
+}
+
+// WebhookDeliveryEntity one event sent to one endpoint, the columns keep the result of the last attempt
+type WebhookDeliveryEntity struct {
+	ID             string         `db:"id"`
+	EndpointID     string         `db:"endpoint_id"`
+	EventID        string         `db:"event_id"`
+	EventType      string         `db:"event_type"`
+	Payload        string         `db:"payload"`
+	Status         string         `db:"status"`
+	Attempts       int            `db:"attempts"`
+	NextAttemptAt  string         `db:"next_attempt_at"`
+	ResponseStatus sql.NullInt64  `db:"response_status"`
+	ResponseBody   sql.NullString `db:"response_body"`
+	LastError      sql.NullString `db:"last_error"`
+	DurationMs     sql.NullInt64  `db:"duration_ms"`
+	CreatedAt      string         `db:"created_at"`
+	DeliveredAt    sql.NullString `db:"delivered_at"`
+}
+
+// NewWebhookDeliveryModel ...
+func NewWebhookDeliveryModel(db *sql.DB) IWebhookDelivery {
+	return &webhookDeliveryModel{DB: db}
+}
+
+// NewWebhookDeliveryModelTx delivery must be written in the same transaction as the event
+func NewWebhookDeliveryModelTx(tx *sql.Tx) IWebhookDelivery {
+	return &webhookDeliveryModel{DB: tx}
+}
+
+// FindAllByEndpoint delivery log of the endpoint, empty status list every status
+func (model webhookDeliveryModel) FindAllByEndpoint(endpointID, status string, offset, limit int) (res []WebhookDeliveryEntity, count int, err error) {
+	query := webhookDeliverySelectString + ` WHERE def."endpoint_id" = $1 AND ($2 = '' OR def."status" = $2)
+		ORDER BY def."created_at" DESC OFFSET $3 LIMIT $4`
+	rows, err := model.DB.Query(query, endpointID, status, offset, limit)
+	if err != nil {
+		return res, count, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, count, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+	if err != nil {
+		return res, count, err
+	}
+
+	query = `SELECT COUNT(def."id") FROM "webhook_deliveries" def WHERE def."endpoint_id" = $1
+		AND ($2 = '' OR def."status" = $2)`
+	err = model.DB.QueryRow(query, endpointID, status).Scan(&count)
+
+	return res, count, err
+}
+
+// FindByID ...
+func (model webhookDeliveryModel) FindByID(endpointID, id string) (res WebhookDeliveryEntity, err error) {
+	query := webhookDeliverySelectString + ` WHERE def."endpoint_id" = $1 AND def."id" = $2`
+	row := model.DB.QueryRow(query, endpointID, id)
+	res, err = model.scanRow(row)
+
+	return res, err
+}
+
+// Claim take the due deliveries of active endpoints by moving their next attempt forward by lease,
+// delivery of crashed sender is taken again after the lease
+func (model webhookDeliveryModel) Claim(now time.Time, lease time.Duration, limit int) (res []WebhookDeliveryEntity, err error) {
+	query := `UPDATE "webhook_deliveries" def SET "next_attempt_at" = $1 WHERE def."id" IN (
+			SELECT d."id" FROM "webhook_deliveries" d JOIN "webhook_endpoints" e ON e."id" = d."endpoint_id"
+			WHERE d."status" = $2 AND d."next_attempt_at" <= $3 AND e."is_active" = true AND e."deleted_at" IS NULL
+			ORDER BY d."next_attempt_at" LIMIT $4 FOR UPDATE OF d SKIP LOCKED
+		) RETURNING def."id", def."endpoint_id", def."event_id", def."event_type", def."payload", def."status",
+		def."attempts", def."next_attempt_at", def."response_status", def."response_body", def."last_error",
+		def."duration_ms", def."created_at", def."delivered_at"`
+	rows, err := model.DB.Query(query, now.Add(lease), WebhookDeliveryStatusPending, now, limit)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
+
+// Store next attempt in the future keep the delivery away from the relay, used by manual redelivery
+func (model webhookDeliveryModel) Store(endpointID, eventID, eventType, payload string, nextAttemptAt, changedAt time.Time) (res string, err error) {
+	sql := `INSERT INTO "webhook_deliveries" ("endpoint_id", "event_id", "event_type", "payload", "status",
+		"next_attempt_at", "created_at") VALUES($1, $2, $3, $4, $5, $6, $7) RETURNING "id"`
+	err = model.DB.QueryRow(sql, endpointID, eventID, eventType, payload, WebhookDeliveryStatusPending, nextAttemptAt,
+		changedAt).Scan(&res)
+
+	return res, err
+}
+
+// MarkAttempt record the result of the attempt and the next status
+func (model webhookDeliveryModel) MarkAttempt(id string, body WebhookDeliveryEntity) (res string, err error) {
+	sql := `UPDATE "webhook_deliveries" SET "status" = $1, "attempts" = "attempts" + 1, "next_attempt_at" = $2,
+		"response_status" = $3, "response_body" = $4, "last_error" = $5, "duration_ms" = $6, "delivered_at" = $7
+		WHERE "id" = $8 RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.Status, body.NextAttemptAt, body.ResponseStatus, body.ResponseBody,
+		body.LastError, body.DurationMs, body.DeliveredAt, id).Scan(&res)
+
+	return res, err
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+)
+
+var (
+	webhookEndpointSelectString = `SELECT def."id", def."url", def."secret", def."events", def."description", def."is_active",
+		def."failure_count", def."disabled_at", def."created_at", def."updated_at", def."deleted_at"
+		FROM "webhook_endpoints" def`
+)
+
+func (model webhookEndpointModel) scanRows(rows *sql.Rows) (d WebhookEndpointEntity, err error) {
+	err = rows.Scan(
+		&d.ID, &d.URL, &d.Secret, &d.Events, &d.Description, &d.IsActive, &d.FailureCount, &d.DisabledAt,
+		&d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+	)
+
+	return d, err
+}
+
+func (model webhookEndpointModel) scanRow(row *sql.Row) (d WebhookEndpointEntity, err error) {
+	err = row.Scan(
+		&d.ID, &d.URL, &d.Secret, &d.Events, &d.Description, &d.IsActive, &d.FailureCount, &d.DisabledAt,
+		&d.CreatedAt, &d.UpdatedAt, &d.DeletedAt,
+	)
+
+	return d, err
+}
+
+// webhookEndpointModel ...
+type webhookEndpointModel struct {
+	DB Querier
+}
+
+// IWebhookEndpoint ...
+type IWebhookEndpoint interface {
+	FindAll(offset, limit int) ([]WebhookEndpointEntity, int, error)
+	FindAllActiveByEvent(eventType string) ([]WebhookEndpointEntity, error)
+	FindByID(id string) (WebhookEndpointEntity, error)
+	Store(body WebhookEndpointEntity, changedAt time.Time) (string, error)
+	Update(id string, body WebhookEndpointEntity, changedAt time.Time) (string, error)
+	UpdateSecret(id, secret string, changedAt time.Time) (string, error)
+	Destroy(id string, changedAt time.Time) (string, error)
+	ResetFailure(id string) (string, error)
+	AddFailure(id string, disableAfter int, changedAt time.Time) (bool, error)
+}
+
+// WebhookEndpointEntity secret is encrypted, events is json array of the subscribed event types
+type WebhookEndpointEntity struct {
+	ID           string         `db:"id"`
+	URL          string         `db:"url"`
+	Secret       string         `db:"secret"`
+	Events       string         `db:"events"`
+	Description  sql.NullString `db:"description"`
+	IsActive     bool           `db:"is_active"`
+	FailureCount int            `db:"failure_count"`
+	DisabledAt   sql.NullString `db:"disabled_at"`
+	CreatedAt    string         `db:"created_at"`
+	UpdatedAt    string         `db:"updated_at"`
+	DeletedAt    sql.NullString `db:"deleted_at"`
+}
+
+// NewWebhookEndpointModel ...
+func NewWebhookEndpointModel(db *sql.DB) IWebhookEndpoint {
+	return &webhookEndpointModel{DB: db}
+}
+
+// NewWebhookEndpointModelTx ...
+func NewWebhookEndpointModelTx(tx *sql.Tx) IWebhookEndpoint {
+	return &webhookEndpointModel{DB: tx}
+}
+
+// FindAll ...
+func (model webhookEndpointModel) FindAll(offset, limit int) (res []WebhookEndpointEntity, count int, err error) {
+	query := webhookEndpointSelectString + ` WHERE def."deleted_at" IS NULL
+		ORDER BY def."created_at" DESC OFFSET $1 LIMIT $2`
+	rows, err := model.DB.Query(query, offset, limit)
+	if err != nil {
+		return res, count, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, count, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+	if err != nil {
+		return res, count, err
+	}
+
+	query = `SELECT COUNT(def."id") FROM "webhook_endpoints" def WHERE def."deleted_at" IS NULL`
+	err = model.DB.QueryRow(query).Scan(&count)
+
+	return res, count, err
+}
+
+// FindAllActiveByEvent active endpoints subscribed to the event type
+func (model webhookEndpointModel) FindAllActiveByEvent(eventType string) (res []WebhookEndpointEntity, err error) {
+	query := webhookEndpointSelectString + ` WHERE def."deleted_at" IS NULL AND def."is_active" = true
+		AND def."events" ? $1`
+	rows, err := model.DB.Query(query, eventType)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		d, err := model.scanRows(rows)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, d)
+	}
+	err = rows.Err()
+
+	return res, err
+}
+
+// FindByID ...
+func (model webhookEndpointModel) FindByID(id string) (res WebhookEndpointEntity, err error) {
+	query := webhookEndpointSelectString + ` WHERE def."deleted_at" IS NULL AND def."id" = $1`
+	row := model.DB.QueryRow(query, id)
+	res, err = model.scanRow(row)
+
+	return res, err
+}
+
+// Store ...
+func (model webhookEndpointModel) Store(body WebhookEndpointEntity, changedAt time.Time) (res string, err error) {
+	sql := `INSERT INTO "webhook_endpoints" (
+		"url", "secret", "events", "description", "is_active", "created_at", "updated_at"
+		) VALUES($1, $2, $3, $4, $5, $6, $6) RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.URL, body.Secret, body.Events, body.Description, body.IsActive, changedAt).Scan(&res)
+
+	return res, err
+}
+
+// Update enabling the endpoint reset the failure counter
+func (model webhookEndpointModel) Update(id string, body WebhookEndpointEntity, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "webhook_endpoints" SET "url" = $1, "events" = $2, "description" = $3, "is_active" = $4,
+		"failure_count" = CASE WHEN $4 AND NOT "is_active" THEN 0 ELSE "failure_count" END,
+		"disabled_at" = CASE WHEN $4 THEN NULL ELSE COALESCE("disabled_at", $5) END,
+		"updated_at" = $5 WHERE "deleted_at" IS NULL AND "id" = $6 RETURNING "id"`
+	err = model.DB.QueryRow(sql, body.URL, body.Events, body.Description, body.IsActive, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// UpdateSecret ...
+func (model webhookEndpointModel) UpdateSecret(id, secret string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "webhook_endpoints" SET "secret" = $1, "updated_at" = $2 WHERE "deleted_at" IS NULL
+		AND "id" = $3 RETURNING "id"`
+	err = model.DB.QueryRow(sql, secret, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// Destroy ...
+func (model webhookEndpointModel) Destroy(id string, changedAt time.Time) (res string, err error) {
+	sql := `UPDATE "webhook_endpoints" SET "is_active" = false, "updated_at" = $1, "deleted_at" = $1
+		WHERE "deleted_at" IS NULL AND "id" = $2 RETURNING "id"`
+	err = model.DB.QueryRow(sql, changedAt, id).Scan(&res)
+
+	return res, err
+}
+
+// ResetFailure reset the consecutive failure counter after successful delivery
+func (model webhookEndpointModel) ResetFailure(id string) (res string, err error) {
+	sql := `UPDATE "webhook_endpoints" SET "failure_count" = 0 WHERE "id" = $1 RETURNING "id"`
+	err = model.DB.QueryRow(sql, id).Scan(&res)
+
+	return res, err
+}
+
+// AddFailure count the failed attempt, the endpoint is disabled once the counter reach disableAfter.
+// It return true when the endpoint is disabled by this failure
+func (model webhookEndpointModel) AddFailure(id string, disableAfter int, changedAt time.Time) (res bool, err error) {
+	sql := `UPDATE "webhook_endpoints" SET "failure_count" = "failure_count" + 1,
+		"is_active" = "is_active" AND "failure_count" + 1 < $1,
+		"disabled_at" = CASE WHEN "is_active" AND "failure_count" + 1 >= $1 THEN $2 ELSE "disabled_at" END
+		WHERE "id" = $3 RETURNING COALESCE(NOT "is_active" AND "disabled_at" = $2, false)`
+	err = model.DB.QueryRow(sql, disableAfter, changedAt, id).Scan(&res)
+
+	return res, err
+}
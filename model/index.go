@@ -4,6 +4,13 @@ import (
 	"database/sql"
 )
 
+// Querier is implemented by both *sql.DB and *sql.Tx, so model can run inside a transaction
+type Querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 // SQLGdbc ...
 type SQLGdbc interface {
 	Exec(query string, args ...interface{}) (sql.Result, error)
@@ -44,8 +51,7 @@ func (sdt *SQLDBTx) TxBegin() (*SQLConnTx, error) {
 }
 
 // TxEnd ...
-func (sct *SQLConnTx) TxEnd(txFunc func() error) error {
-	var err error
+func (sct *SQLConnTx) TxEnd(txFunc func() error) (err error) {
 	tx := sct.DB
 
 	defer func() {
@@ -9,14 +9,66 @@ import (
 	"encoding/hex"
 	"errors"
 	"io"
+	"regexp"
+	"strings"
 )
 
-// Credential ...
+const (
+	// envelopeVersion prefix of the gcm ciphertext: v2.[key id].[base64 url of nonce + ciphertext + tag].
+	// Legacy cfb ciphertext is hex so it never has the separator
+	envelopeVersion = "v2"
+	separator       = "."
+	keySize         = 32
+)
+
+var (
+	// ErrNoPrimaryKey ...
+	ErrNoPrimaryKey = errors.New("aes primary key is not configured")
+	// ErrUnknownKeyID ciphertext is encrypted by a key which is not configured anymore
+	ErrUnknownKeyID = errors.New("aes key id is not configured")
+	// ErrInvalidCiphertext ...
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+	keyIDRegex = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+)
+
+// Credential Keys is every 32 bytes key which can decrypt, PrimaryKeyID is the key used to encrypt.
+// Key is the legacy AES_KEY, used only to decrypt old cfb ciphertext
 type Credential struct {
-	Key string
+	Key          string
+	Keys         map[string][]byte
+	PrimaryKeyID string
 }
 
-// shaKey ...
+// NewCredential keys is comma separated [key id]:[64 hex characters], ex: 2024a:0f1e...,2023b:9a8b...
+func NewCredential(legacyKey, keys, primaryKeyID string) (res Credential, err error) {
+	res = Credential{Key: legacyKey, Keys: map[string][]byte{}, PrimaryKeyID: primaryKeyID}
+	for _, pair := range strings.Split(keys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, ":", 2)
+		if len(parts) != 2 || !keyIDRegex.MatchString(parts[0]) {
+			return res, errors.New("invalid aes key id: " + parts[0])
+		}
+		key, err := hex.DecodeString(parts[1])
+		if err != nil || len(key) != keySize {
+			return res, errors.New("aes key " + parts[0] + " must be 64 hex characters")
+		}
+		if _, ok := res.Keys[parts[0]]; ok {
+			return res, errors.New("duplicate aes key id: " + parts[0])
+		}
+		res.Keys[parts[0]] = key
+	}
+	if _, ok := res.Keys[primaryKeyID]; !ok {
+		return res, ErrNoPrimaryKey
+	}
+
+	return res, err
+}
+
+// shaKey key of the legacy cfb ciphertext
 func (cred *Credential) shaKey() (res []byte) {
 	h := sha1.New()
 	h.Write([]byte(cred.Key))
@@ -26,86 +78,125 @@ func (cred *Credential) shaKey() (res []byte) {
 	return res
 }
 
-// Encrypt ...
-func (cred *Credential) Encrypt(textString string) (string, error) {
-	key := cred.shaKey()
-	text := []byte(textString)
-
+// gcm ...
+func (cred *Credential) gcm(keyID string) (cipher.AEAD, error) {
+	key, ok := cred.Keys[keyID]
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
 	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// additionalData the version and key id is authenticated with the ciphertext
+func additionalData(keyID string) []byte {
+	return []byte(envelopeVersion + separator + keyID)
+}
+
+// Encrypt aes-256-gcm with the primary key
+func (cred *Credential) Encrypt(textString string) (string, error) {
+	if cred.PrimaryKeyID == "" {
+		return "", ErrNoPrimaryKey
+	}
+	gcm, err := cred.gcm(cred.PrimaryKeyID)
 	if err != nil {
 		return "", err
 	}
-	b := base64.StdEncoding.EncodeToString(text)
-	ciphertext := make([]byte, aes.BlockSize+len(b))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
-	cfb := cipher.NewCFBEncrypter(block, iv)
-	cfb.XORKeyStream(ciphertext[aes.BlockSize:], []byte(b))
+	ciphertext := gcm.Seal(nonce, nonce, []byte(textString), additionalData(cred.PrimaryKeyID))
 
-	res := hex.EncodeToString(ciphertext)
+	res := envelopeVersion + separator + cred.PrimaryKeyID + separator + base64.RawURLEncoding.EncodeToString(ciphertext)
 	return res, nil
 }
 
 // EncryptNoErr ...
 func (cred *Credential) EncryptNoErr(textString string) string {
-	key := cred.shaKey()
-	text := []byte(textString)
-
-	block, err := aes.NewCipher(key)
+	res, err := cred.Encrypt(textString)
 	if err != nil {
 		return ""
 	}
-	b := base64.StdEncoding.EncodeToString(text)
-	ciphertext := make([]byte, aes.BlockSize+len(b))
-	iv := ciphertext[:aes.BlockSize]
-	if _, err := io.ReadFull(rand.Reader, iv); err != nil {
+
+	return res
+}
+
+// KeyID key id of the gcm ciphertext, empty for legacy cfb ciphertext
+func KeyID(text string) string {
+	parts := strings.SplitN(text, separator, 3)
+	if len(parts) != 3 || parts[0] != envelopeVersion {
 		return ""
 	}
-	cfb := cipher.NewCFBEncrypter(block, iv)
-	cfb.XORKeyStream(ciphertext[aes.BlockSize:], []byte(b))
 
-	res := hex.EncodeToString(ciphertext)
-	return res
+	return parts[1]
 }
 
-// Decrypt ...
+// NeedReencrypt ciphertext is legacy cfb or not encrypted by the primary key
+func (cred *Credential) NeedReencrypt(text string) bool {
+	return text != "" && KeyID(text) != cred.PrimaryKeyID
+}
+
+// Decrypt gcm ciphertext by its key id, or legacy cfb ciphertext by the legacy key
 func (cred *Credential) Decrypt(text string) (string, error) {
-	key := cred.shaKey()
-	ciphertext, _ := hex.DecodeString(text)
+	if !strings.HasPrefix(text, envelopeVersion+separator) {
+		return cred.decryptLegacy(text)
+	}
 
-	block, err := aes.NewCipher(key)
+	parts := strings.SplitN(text, separator, 3)
+	if len(parts) != 3 {
+		return "", ErrInvalidCiphertext
+	}
+	gcm, err := cred.gcm(parts[1])
 	if err != nil {
 		return "", err
 	}
-	if len(ciphertext) < aes.BlockSize {
-		return "", errors.New("ciphertext too short")
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", ErrInvalidCiphertext
 	}
-	iv := ciphertext[:aes.BlockSize]
-	ciphertext = ciphertext[aes.BlockSize:]
-	cfb := cipher.NewCFBDecrypter(block, iv)
-	cfb.XORKeyStream(ciphertext, ciphertext)
-	data, err := base64.StdEncoding.DecodeString(string(ciphertext))
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return "", ErrInvalidCiphertext
+	}
+	nonce := ciphertext[:gcm.NonceSize()]
+	data, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], additionalData(parts[1]))
 	if err != nil {
-		return "", err
+		return "", ErrInvalidCiphertext
 	}
 
-	res := string(data[:])
+	res := string(data)
 	return res, nil
 }
 
 // DecryptNoErr ...
 func (cred *Credential) DecryptNoErr(text string) string {
+	res, err := cred.Decrypt(text)
+	if err != nil {
+		return ""
+	}
+
+	return res
+}
+
+// decryptLegacy hex of the iv + cfb ciphertext of the base64 text
+func (cred *Credential) decryptLegacy(text string) (string, error) {
 	key := cred.shaKey()
-	ciphertext, _ := hex.DecodeString(text)
+	ciphertext, err := hex.DecodeString(text)
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
 
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return ""
+		return "", err
 	}
 	if len(ciphertext) < aes.BlockSize {
-		return ""
+		return "", errors.New("ciphertext too short")
 	}
 	iv := ciphertext[:aes.BlockSize]
 	ciphertext = ciphertext[aes.BlockSize:]
@@ -113,9 +204,9 @@ func (cred *Credential) DecryptNoErr(text string) string {
 	cfb.XORKeyStream(ciphertext, ciphertext)
 	data, err := base64.StdEncoding.DecodeString(string(ciphertext))
 	if err != nil {
-		return ""
+		return "", err
 	}
 
 	res := string(data[:])
-	return res
+	return res, nil
 }
@@ -0,0 +1,179 @@
+package aes
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const (
+	key1 = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	key2 = "1f1e1d1c1b1a191817161514131211100f0e0d0c0b0a09080706050403020100"
+
+	// legacyFixture encrypted by the baseline cfb Encrypt with legacyKey
+	legacyKey     = "fixture-legacy-key"
+	legacyFixture = "ab4cef56ce35f43a517b7ff4e00524146fb796b14d9902d845c293db4e47203f714596db224869da5f6423ab"
+	plaintext     = "john.doe@example.com"
+)
+
+func newCredential(t *testing.T, primaryKeyID string) Credential {
+	res, err := NewCredential(legacyKey, "k1:"+key1+", k2:"+key2, primaryKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func TestNewCredential(t *testing.T) {
+	cases := map[string]struct {
+		keys    string
+		primary string
+	}{
+		"missing primary key": {keys: "k1:" + key1, primary: "k2"},
+		"short key":           {keys: "k1:" + key1[:62], primary: "k1"},
+		"non hex key":         {keys: "k1:" + strings.Repeat("zz", 32), primary: "k1"},
+		"invalid key id":      {keys: "k.1:" + key1, primary: "k.1"},
+		"duplicate key id":    {keys: "k1:" + key1 + ",k1:" + key2, primary: "k1"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewCredential(legacyKey, c.keys, c.primary); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	cred := newCredential(t, "k1")
+	for _, text := range []string{plaintext, "", strings.Repeat("x", 4096)} {
+		ciphertext, err := cred.Encrypt(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if KeyID(ciphertext) != "k1" || !strings.HasPrefix(ciphertext, "v2.k1.") {
+			t.Fatalf("unexpected envelope %s", ciphertext)
+		}
+		res, err := cred.Decrypt(ciphertext)
+		if err != nil || res != text {
+			t.Fatalf("expected %q, got %q %v", text, res, err)
+		}
+	}
+
+	// Random nonce, the same plaintext never give the same ciphertext
+	if cred.EncryptNoErr(plaintext) == cred.EncryptNoErr(plaintext) {
+		t.Fatal("expected different ciphertext")
+	}
+}
+
+func TestDecryptNonPrimaryKey(t *testing.T) {
+	k1 := newCredential(t, "k1")
+	ciphertext, err := k1.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cred := newCredential(t, "k2")
+	res, err := cred.Decrypt(ciphertext)
+	if err != nil || res != plaintext {
+		t.Fatalf("expected %q, got %q %v", plaintext, res, err)
+	}
+}
+
+func TestDecryptUnknownKeyID(t *testing.T) {
+	k1 := newCredential(t, "k1")
+	ciphertext, err := k1.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cred, err := NewCredential(legacyKey, "k2:"+key2, "k2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = cred.Decrypt(ciphertext); err != ErrUnknownKeyID {
+		t.Fatalf("expected ErrUnknownKeyID, got %v", err)
+	}
+	if cred.DecryptNoErr(ciphertext) != "" {
+		t.Fatal("expected empty plaintext")
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	cred := newCredential(t, "k1")
+	ciphertext, err := cred.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.SplitN(ciphertext, separator, 3)
+	raw, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flipped := append([]byte{}, raw...)
+	flipped[len(flipped)-1] ^= 1
+	cases := map[string]string{
+		// Flipped bit of the ciphertext or the tag
+		"ciphertext": parts[0] + separator + parts[1] + separator + base64.RawURLEncoding.EncodeToString(flipped),
+		// Same ciphertext under other key id fail the additional data even when the key is the same
+		"key id": parts[0] + separator + "k2" + separator + parts[2],
+		// Truncated below nonce and tag size
+		"truncated":  parts[0] + separator + parts[1] + separator + base64.RawURLEncoding.EncodeToString(raw[:20]),
+		"not base64": parts[0] + separator + parts[1] + separator + "!!!",
+		"no payload": parts[0] + separator + parts[1],
+	}
+
+	// k2 has the same key as k1, so only the additional data differ
+	sameKey, err := NewCredential(legacyKey, "k1:"+key1+",k2:"+key1, "k1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, text := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := sameKey.Decrypt(text); err == nil {
+				t.Fatal("expected error")
+			}
+		})
+	}
+}
+
+func TestDecryptLegacy(t *testing.T) {
+	cred := newCredential(t, "k1")
+	res, err := cred.Decrypt(legacyFixture)
+	if err != nil || res != plaintext {
+		t.Fatalf("expected %q, got %q %v", plaintext, res, err)
+	}
+
+	if _, err = cred.Decrypt("not hex"); err != ErrInvalidCiphertext {
+		t.Fatalf("expected ErrInvalidCiphertext, got %v", err)
+	}
+}
+
+func TestNeedReencrypt(t *testing.T) {
+	k1 := newCredential(t, "k1")
+	ciphertext, err := k1.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name     string
+		cred     Credential
+		text     string
+		expected bool
+	}{
+		{name: "primary key", cred: k1, text: ciphertext, expected: false},
+		{name: "other key", cred: newCredential(t, "k2"), text: ciphertext, expected: true},
+		{name: "legacy", cred: k1, text: legacyFixture, expected: true},
+		{name: "empty", cred: k1, text: "", expected: false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if res := c.cred.NeedReencrypt(c.text); res != c.expected {
+				t.Fatalf("expected %v, got %v", c.expected, res)
+			}
+		})
+	}
+}
@@ -1,18 +1,55 @@
 package aesfront
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"encoding/base64"
+	"errors"
 )
 
-// Credential ...
+var (
+	// ErrInvalidCiphertext returned for every decrypt failure, the cause is not told to the caller
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+)
+
+// Credential with Iv is the static AES-CBC credential of the existing clients. Empty Iv is the session mode,
+// it use AES-GCM with random nonce for every message, the nonce is put before the ciphertext
 type Credential struct {
 	Key string
 	Iv  string
 }
 
-// Decrypt ...
+// Encrypt ...
+func (cred *Credential) Encrypt(data string) (res string, err error) {
+	block, err := aes.NewCipher([]byte(cred.Key))
+	if err != nil {
+		return res, err
+	}
+
+	if cred.Iv == "" {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return res, err
+		}
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err = rand.Read(nonce); err != nil {
+			return res, err
+		}
+
+		return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(data), nil)), err
+	}
+
+	plaintext := PKCS5Padding([]byte(data), aes.BlockSize)
+	ciphertext := make([]byte, len(plaintext))
+	mode := cipher.NewCBCEncrypter(block, []byte(cred.Iv))
+	mode.CryptBlocks(ciphertext, plaintext)
+
+	return base64.StdEncoding.EncodeToString(ciphertext), err
+}
+
+// Decrypt any failure return ErrInvalidCiphertext
 func (cred *Credential) Decrypt(data string) (res string, err error) {
 	if data == "" {
 		return res, nil
@@ -20,26 +57,63 @@ func (cred *Credential) Decrypt(data string) (res string, err error) {
 
 	ciphertext, err := base64.StdEncoding.DecodeString(data)
 	if err != nil {
-		return res, err
+		return res, ErrInvalidCiphertext
 	}
 
 	block, err := aes.NewCipher([]byte(cred.Key))
 	if err != nil {
-		return res, err
+		return res, ErrInvalidCiphertext
 	}
 
-	if len(ciphertext)%aes.BlockSize != 0 {
-		return res, err
+	if cred.Iv == "" {
+		gcm, err := cipher.NewGCM(block)
+		if err != nil || len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+			return res, ErrInvalidCiphertext
+		}
+		nonce := ciphertext[:gcm.NonceSize()]
+		plaintext, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], nil)
+		if err != nil {
+			return res, ErrInvalidCiphertext
+		}
+
+		return string(plaintext), nil
+	}
+
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return res, ErrInvalidCiphertext
 	}
 
 	mode := cipher.NewCBCDecrypter(block, []byte(cred.Iv))
 	mode.CryptBlocks(ciphertext, ciphertext)
+	plaintext, err := PKCS5Trimming(ciphertext)
+	if err != nil {
+		return res, ErrInvalidCiphertext
+	}
 
-	return string(PKCS5Trimming(ciphertext)), err
+	return string(plaintext), err
+}
+
+// PKCS5Padding ...
+func PKCS5Padding(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+
+	return append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
 }
 
 // PKCS5Trimming ...
-func PKCS5Trimming(encrypt []byte) []byte {
-	padding := encrypt[len(encrypt)-1]
-	return encrypt[:len(encrypt)-int(padding)]
+func PKCS5Trimming(encrypt []byte) ([]byte, error) {
+	if len(encrypt) == 0 {
+		return nil, ErrInvalidCiphertext
+	}
+	padding := int(encrypt[len(encrypt)-1])
+	if padding == 0 || padding > aes.BlockSize || padding > len(encrypt) {
+		return nil, ErrInvalidCiphertext
+	}
+	for _, b := range encrypt[len(encrypt)-padding:] {
+		if int(b) != padding {
+			return nil, ErrInvalidCiphertext
+		}
+	}
+
+	return encrypt[:len(encrypt)-padding], nil
 }
@@ -0,0 +1,102 @@
+package aesfront
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+const (
+	staticKey  = "0123456789abcdef0123456789abcdef"
+	staticIv   = "fedcba9876543210"
+	sessionKey = "0123456789abcdef"
+	plaintext  = `{"email":"john.doe@example.com","password":"secret"}`
+)
+
+// flip change one bit of the decoded ciphertext at index, negative index count from the end
+func flip(t *testing.T, data string, index int) string {
+	ciphertext, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index < 0 {
+		index += len(ciphertext)
+	}
+	ciphertext[index] ^= 1
+
+	return base64.StdEncoding.EncodeToString(ciphertext)
+}
+
+func TestRoundTrip(t *testing.T) {
+	cases := map[string]Credential{
+		"static":  {Key: staticKey, Iv: staticIv},
+		"session": {Key: sessionKey},
+	}
+	for name, cred := range cases {
+		t.Run(name, func(t *testing.T) {
+			ciphertext, err := cred.Encrypt(plaintext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := cred.Decrypt(ciphertext)
+			if err != nil || res != plaintext {
+				t.Fatalf("expected %q, got %q (%v)", plaintext, res, err)
+			}
+		})
+	}
+}
+
+func TestSessionNonce(t *testing.T) {
+	cred := Credential{Key: sessionKey}
+	first, err := cred.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := cred.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Fatal("expected a new nonce for every message")
+	}
+}
+
+func TestDecryptInvalid(t *testing.T) {
+	static := Credential{Key: staticKey, Iv: staticIv}
+	session := Credential{Key: sessionKey}
+	staticCiphertext, err := static.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionCiphertext, err := session.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		cred Credential
+		data string
+	}{
+		"static invalid base64":      {cred: static, data: "not base64!"},
+		"static partial block":       {cred: static, data: base64.StdEncoding.EncodeToString([]byte("short"))},
+		"static invalid padding":     {cred: static, data: flip(t, staticCiphertext, -1)},
+		"static wrong key":           {cred: Credential{Key: staticKey[:16], Iv: staticIv}, data: staticCiphertext},
+		"session invalid base64":     {cred: session, data: "not base64!"},
+		"session short ciphertext":   {cred: session, data: base64.StdEncoding.EncodeToString(make([]byte, 27))},
+		"session modified nonce":     {cred: session, data: flip(t, sessionCiphertext, 0)},
+		"session modified body":      {cred: session, data: flip(t, sessionCiphertext, 12)},
+		"session modified tag":       {cred: session, data: flip(t, sessionCiphertext, -1)},
+		"session wrong key":          {cred: Credential{Key: "fedcba9876543210"}, data: sessionCiphertext},
+		"session invalid key length": {cred: Credential{Key: "short"}, data: sessionCiphertext},
+		"static ciphertext as session": {
+			cred: Credential{Key: staticKey}, data: staticCiphertext,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := c.cred.Decrypt(c.data)
+			if err != ErrInvalidCiphertext {
+				t.Fatalf("expected %v, got %q (%v)", ErrInvalidCiphertext, res, err)
+			}
+		})
+	}
+}
@@ -6,7 +6,8 @@ import (
 	"github.com/streadway/amqp"
 )
 
-// IQueue ...
+// IQueue is the legacy publisher which share one channel and publish without confirmation,
+// use IPublisher instead
 type IQueue interface {
 	PushQueue(data map[string]interface{}, types string) error
 	PushQueueReconnect(url string, data map[string]interface{}, types, deadLetterKey string) (*amqp.Connection, *amqp.Channel, error)
@@ -21,6 +22,23 @@ const (
 	// ActivationMailDeadLetter ...
 	ActivationMailDeadLetter = "activation_mail.deadletter.queue"
 
+	// ChangeEmailMailExchange ...
+	ChangeEmailMailExchange = "change_email_mail.exchange"
+	// ChangeEmailMail ...
+	ChangeEmailMail = "change_email_mail.incoming.queue"
+	// ChangeEmailMailDeadLetter ...
+	ChangeEmailMailDeadLetter = "change_email_mail.deadletter.queue"
+
+	// ImageProcessExchange ...
+	ImageProcessExchange = "image_process.exchange"
+	// ImageProcess ...
+	ImageProcess = "image_process.incoming.queue"
+	// ImageProcessDeadLetter ...
+	ImageProcessDeadLetter = "image_process.deadletter.queue"
+
+	// EventExchange topic exchange of the domain events, routing key is the event type ex: user.created
+	EventExchange = "kriyapeople.event.exchange"
+
 	// ResetPasswordMailExchange ...
 	ResetPasswordMailExchange = "reset_password_mail.exchange"
 	// ResetPasswordMail ...
@@ -29,6 +47,33 @@ const (
 	ResetPasswordMailDeadLetter = "reset_password_mail.deadletter.queue"
 )
 
+// Queue incoming queue with its dead letter queue, the name is used by the worker and the admin endpoints
+type Queue struct {
+	Name       string
+	Exchange   string
+	Incoming   string
+	DeadLetter string
+}
+
+// Queues every queue consumed by the worker
+var Queues = []Queue{
+	{"activation_mail", ActivationMailExchange, ActivationMail, ActivationMailDeadLetter},
+	{"reset_password_mail", ResetPasswordMailExchange, ResetPasswordMail, ResetPasswordMailDeadLetter},
+	{"change_email_mail", ChangeEmailMailExchange, ChangeEmailMail, ChangeEmailMailDeadLetter},
+	{"image_process", ImageProcessExchange, ImageProcess, ImageProcessDeadLetter},
+}
+
+// FindQueue ...
+func FindQueue(name string) (res Queue, ok bool) {
+	for _, q := range Queues {
+		if q.Name == name {
+			return q, true
+		}
+	}
+
+	return res, false
+}
+
 // queue ...
 type queue struct {
 	Connection *amqp.Connection
@@ -102,7 +147,7 @@ func (m queue) PushQueueReconnect(url string, data map[string]interface{}, types
 
 	body, err := json.Marshal(data)
 	if err != nil {
-		return nil, nil, nil
+		return m.Connection, m.Channel, err
 	}
 
 	err = m.Channel.Publish("", queue.Name, false, false, amqp.Publishing{
@@ -147,7 +192,7 @@ func (m queue) PushDLQueueReconnect(url string, data map[string]interface{}, typ
 
 	body, err := json.Marshal(data)
 	if err != nil {
-		return nil, nil, nil
+		return m.Connection, m.Channel, err
 	}
 
 	err = m.Channel.Publish("", queue.Name, false, false, amqp.Publishing{
@@ -0,0 +1,528 @@
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/rs/xid"
+	"github.com/streadway/amqp"
+)
+
+var (
+	// DefaultPoolSize ...
+	DefaultPoolSize = 4
+	// DefaultConfirmTimeout ...
+	DefaultConfirmTimeout = 5 * time.Second
+	// DefaultBufferSize ...
+	DefaultBufferSize = 1000
+	// DefaultReconnectMin ...
+	DefaultReconnectMin = time.Second
+	// DefaultReconnectMax ...
+	DefaultReconnectMax = 30 * time.Second
+	// DefaultCloseTimeout ...
+	DefaultCloseTimeout = 10 * time.Second
+
+	// ErrBufferFull broker is not available and the outage buffer is full
+	ErrBufferFull = errors.New("amqp_buffer_full")
+	// ErrNack broker refused the message
+	ErrNack = errors.New("amqp_nack")
+	// ErrConfirmTimeout broker did not confirm the message in time
+	ErrConfirmTimeout = errors.New("amqp_confirm_timeout")
+	// ErrPublisherClosed ...
+	ErrPublisherClosed = errors.New("amqp_publisher_closed")
+	// ErrUnavailable broker is not available and the message is not buffered
+	ErrUnavailable = errors.New("amqp_unavailable")
+
+	// errNoChannel no channel is released from the pool in time
+	errNoChannel = errors.New("amqp_no_channel")
+)
+
+// noBufferKey ...
+type noBufferKey struct{}
+
+// messageIDKey ...
+type messageIDKey struct{}
+
+// WithoutBuffer make Publish return ErrUnavailable instead of buffering the message,
+// used by caller which keep the message by itself until it is confirmed
+func WithoutBuffer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noBufferKey{}, true)
+}
+
+// WithMessageID publish the message with the given id instead of a new one, used by caller which
+// can publish the same message more than once so the consumer can detect the duplicate
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+// IPublisher ...
+type IPublisher interface {
+	Publish(ctx context.Context, queueName, deadLetterKey string, data map[string]interface{}) error
+	PublishExchange(ctx context.Context, exchange, routingKey string, data map[string]interface{}) error
+	Close() error
+}
+
+// PublisherConfig ...
+type PublisherConfig struct {
+	URL            string
+	PoolSize       int
+	ConfirmTimeout time.Duration
+	BufferSize     int
+	ReconnectMin   time.Duration
+	ReconnectMax   time.Duration
+	CloseTimeout   time.Duration
+}
+
+// message is published into the exchange, or straight into the queue when exchange is empty
+type message struct {
+	id            string
+	exchange      string
+	routingKey    string
+	deadLetterKey string
+	body          []byte
+}
+
+// confirmChannel channel in confirm mode, only one message is in flight per channel
+// so every confirmation belongs to the last published message
+type confirmChannel struct {
+	channel  *amqp.Channel
+	confirms chan amqp.Confirmation
+}
+
+// publisher ...
+type publisher struct {
+	config PublisherConfig
+
+	mu        sync.RWMutex
+	conn      *amqp.Connection
+	pool      chan *confirmChannel
+	lost      chan struct{}
+	declared  map[string]bool
+	connected bool
+
+	buffer  chan message
+	closed  chan struct{}
+	drained chan struct{}
+	once    sync.Once
+}
+
+// NewPublisher connect to the broker and keep the connection alive in background,
+// every message is published with confirmation and buffered while the broker is not available
+func NewPublisher(config PublisherConfig) (IPublisher, error) {
+	if config.PoolSize <= 0 {
+		config.PoolSize = DefaultPoolSize
+	}
+	if config.ConfirmTimeout <= 0 {
+		config.ConfirmTimeout = DefaultConfirmTimeout
+	}
+	if config.BufferSize <= 0 {
+		config.BufferSize = DefaultBufferSize
+	}
+	if config.ReconnectMin <= 0 {
+		config.ReconnectMin = DefaultReconnectMin
+	}
+	if config.ReconnectMax <= 0 {
+		config.ReconnectMax = DefaultReconnectMax
+	}
+	if config.CloseTimeout <= 0 {
+		config.CloseTimeout = DefaultCloseTimeout
+	}
+
+	p := &publisher{
+		config:  config,
+		buffer:  make(chan message, config.BufferSize),
+		closed:  make(chan struct{}),
+		drained: make(chan struct{}),
+	}
+	err := p.connect()
+	if err != nil {
+		return nil, err
+	}
+	go p.drain()
+
+	return p, nil
+}
+
+// connect open the connection and fill the channel pool
+func (p *publisher) connect() (err error) {
+	conn, err := amqp.Dial(p.config.URL)
+	if err != nil {
+		return err
+	}
+
+	pool := make(chan *confirmChannel, p.config.PoolSize)
+	for i := 0; i < p.config.PoolSize; i++ {
+		ch, err := p.openChannel(conn)
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		pool <- ch
+	}
+
+	lost := make(chan struct{})
+	p.mu.Lock()
+	p.conn = conn
+	p.pool = pool
+	p.lost = lost
+	p.declared = map[string]bool{}
+	p.connected = true
+	p.mu.Unlock()
+
+	go p.watch(conn, lost)
+
+	return nil
+}
+
+// openChannel ...
+func (p *publisher) openChannel(conn *amqp.Connection) (res *confirmChannel, err error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return res, err
+	}
+	err = channel.Confirm(false)
+	if err != nil {
+		channel.Close()
+		return res, err
+	}
+
+	res = &confirmChannel{
+		channel:  channel,
+		confirms: channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+	}
+
+	return res, err
+}
+
+// watch reconnect with capped exponential backoff when the connection is closed,
+// lost is closed to release the publish waiting for a channel of the closed connection
+func (p *publisher) watch(conn *amqp.Connection, lost chan struct{}) {
+	closeErr := <-conn.NotifyClose(make(chan *amqp.Error, 1))
+
+	p.mu.Lock()
+	p.connected = false
+	p.mu.Unlock()
+	close(lost)
+
+	select {
+	case <-p.closed:
+		return
+	default:
+	}
+	log.Printf("amqp publisher connection closed: %v", closeErr)
+
+	wait := p.config.ReconnectMin
+	for {
+		select {
+		case <-p.closed:
+			return
+		case <-time.After(wait):
+		}
+
+		err := p.connect()
+		if err == nil {
+			log.Printf("amqp publisher reconnected")
+			return
+		}
+		log.Printf("amqp publisher reconnect failed, retry in %s: %v", wait, err)
+
+		wait *= 2
+		if wait > p.config.ReconnectMax {
+			wait = p.config.ReconnectMax
+		}
+	}
+}
+
+// drain publish the buffered messages in order while connected, the message is retried
+// until the broker confirm it. The remaining messages are flushed when the publisher is closed
+func (p *publisher) drain() {
+	defer close(p.drained)
+
+	for {
+		var msg message
+		select {
+		case msg = <-p.buffer:
+		case <-p.closed:
+			p.flush(nil)
+			return
+		}
+
+		for !p.publishBuffered(msg, p.config.ConfirmTimeout) {
+			select {
+			case <-p.closed:
+				p.flush(&msg)
+				return
+			case <-time.After(p.config.ReconnectMin):
+			}
+		}
+	}
+}
+
+// publishBuffered ...
+func (p *publisher) publishBuffered(msg message, timeout time.Duration) bool {
+	p.mu.RLock()
+	connected := p.connected
+	p.mu.RUnlock()
+	if !connected {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := p.publish(ctx, msg)
+	if err != nil {
+		log.Printf("amqp publisher flush failed on %s %s: %v", msg.exchange, msg.routingKey, err)
+		return false
+	}
+
+	return true
+}
+
+// flush publish the held and the buffered messages until the close timeout, what is left is dropped and logged
+func (p *publisher) flush(held *message) {
+	deadline := time.Now().Add(p.config.CloseTimeout)
+	next := func() (msg message, ok bool) {
+		if held != nil {
+			msg, held = *held, nil
+			return msg, true
+		}
+		select {
+		case msg = <-p.buffer:
+			return msg, true
+		default:
+			return msg, false
+		}
+	}
+
+	for msg, ok := next(); ok; msg, ok = next() {
+		for {
+			timeout := time.Until(deadline)
+			if timeout <= 0 {
+				p.drop(msg)
+				for msg, ok = next(); ok; msg, ok = next() {
+					p.drop(msg)
+				}
+				return
+			}
+			if timeout > p.config.ConfirmTimeout {
+				timeout = p.config.ConfirmTimeout
+			}
+			if p.publishBuffered(msg, timeout) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
+
+// drop log the message which is not published, the body is not logged
+func (p *publisher) drop(msg message) {
+	log.Printf("amqp publisher dropped message %s on %s %s", msg.id, msg.exchange, msg.routingKey)
+}
+
+// enqueue keep the message until the broker is back
+func (p *publisher) enqueue(msg message) error {
+	select {
+	case p.buffer <- msg:
+		return nil
+	default:
+		return ErrBufferFull
+	}
+}
+
+// enqueueOrFail ...
+func (p *publisher) enqueueOrFail(ctx context.Context, msg message) error {
+	if noBuffer, _ := ctx.Value(noBufferKey{}).(bool); noBuffer {
+		return ErrUnavailable
+	}
+
+	return p.enqueue(msg)
+}
+
+// Publish publish json body into the queue through default exchange and wait for the confirmation,
+// the message is buffered when the broker is not available
+func (p *publisher) Publish(ctx context.Context, queueName, deadLetterKey string, data map[string]interface{}) (err error) {
+	return p.send(ctx, message{
+		routingKey:    queueName,
+		deadLetterKey: deadLetterKey,
+	}, data)
+}
+
+// PublishExchange publish json body into durable topic exchange with the routing key
+func (p *publisher) PublishExchange(ctx context.Context, exchange, routingKey string, data map[string]interface{}) (err error) {
+	return p.send(ctx, message{
+		exchange:   exchange,
+		routingKey: routingKey,
+	}, data)
+}
+
+// send ...
+func (p *publisher) send(ctx context.Context, msg message, data map[string]interface{}) (err error) {
+	select {
+	case <-p.closed:
+		return ErrPublisherClosed
+	default:
+	}
+
+	// Same id is kept on retry and dead letter, used to select the message on replay
+	msg.id, _ = ctx.Value(messageIDKey{}).(string)
+	if msg.id == "" {
+		msg.id = xid.New().String()
+	}
+	msg.body, err = json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	connected := p.connected
+	p.mu.RUnlock()
+	if !connected {
+		return p.enqueueOrFail(ctx, msg)
+	}
+
+	err = p.publish(ctx, msg)
+	if err == amqp.ErrClosed {
+		// The broken channel is already replaced, retry once before waiting for reconnection
+		err = p.publish(ctx, msg)
+	}
+	if err == amqp.ErrClosed || err == errNoChannel {
+		return p.enqueueOrFail(ctx, msg)
+	}
+
+	return err
+}
+
+// publish ...
+func (p *publisher) publish(ctx context.Context, msg message) (err error) {
+	p.mu.RLock()
+	conn, pool, lost := p.conn, p.pool, p.lost
+	p.mu.RUnlock()
+
+	// The channel of the closed connection is not returned into the pool, so the wait must not
+	// outlive the connection
+	var ch *confirmChannel
+	wait := time.NewTimer(p.config.ConfirmTimeout)
+	defer wait.Stop()
+	select {
+	case ch = <-pool:
+	case <-lost:
+		return amqp.ErrClosed
+	case <-wait.C:
+		return errNoChannel
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Channel with unknown state is replaced, late confirmation must not be read by the next message
+	healthy := false
+	defer func() {
+		if healthy {
+			pool <- ch
+			return
+		}
+		ch.channel.Close()
+		newCh, openErr := p.openChannel(conn)
+		if openErr != nil {
+			// Connection is gone, the pool is refilled on reconnect
+			return
+		}
+		pool <- newCh
+	}()
+
+	err = p.declare(ch.channel, msg)
+	if err != nil {
+		return err
+	}
+
+	err = ch.channel.Publish(msg.exchange, msg.routingKey, false, false, amqp.Publishing{
+		DeliveryMode: amqp.Persistent,
+		ContentType:  "application/json",
+		MessageId:    msg.id,
+		Body:         msg.body,
+	})
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(p.config.ConfirmTimeout)
+	defer timer.Stop()
+	select {
+	case confirm, ok := <-ch.confirms:
+		if !ok {
+			return amqp.ErrClosed
+		}
+		healthy = true
+		if !confirm.Ack {
+			return ErrNack
+		}
+		return nil
+	case <-timer.C:
+		return ErrConfirmTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// declare declare the exchange, or the queue with the dead letter queue, once per connection
+func (p *publisher) declare(channel *amqp.Channel, msg message) (err error) {
+	key := "queue:" + msg.routingKey
+	if msg.exchange != "" {
+		key = "exchange:" + msg.exchange
+	}
+
+	p.mu.RLock()
+	declared := p.declared[key]
+	p.mu.RUnlock()
+	if declared {
+		return nil
+	}
+
+	if msg.exchange != "" {
+		err = channel.ExchangeDeclare(msg.exchange, "topic", true, false, false, false, nil)
+	} else {
+		var args amqp.Table
+		if msg.deadLetterKey != "" {
+			args = amqp.Table{
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": msg.deadLetterKey,
+			}
+		}
+		_, err = channel.QueueDeclare(msg.routingKey, true, false, false, false, args)
+	}
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	p.declared[key] = true
+	p.mu.Unlock()
+
+	return nil
+}
+
+// Close stop accepting message, flush the buffer until the close timeout and close the connection
+func (p *publisher) Close() (err error) {
+	p.once.Do(func() {
+		close(p.closed)
+		<-p.drained
+
+		// Message buffered by a send racing with the close
+		for len(p.buffer) > 0 {
+			p.drop(<-p.buffer)
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.connected = false
+		if p.conn != nil {
+			err = p.conn.Close()
+		}
+	})
+
+	return err
+}
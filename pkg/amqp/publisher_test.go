@@ -0,0 +1,81 @@
+package amqp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newDisconnectedPublisher publisher which is still marked as connected, but every channel of the pool
+// is dropped by the broken connection
+func newDisconnectedPublisher(confirmTimeout time.Duration) *publisher {
+	return &publisher{
+		config:    PublisherConfig{ConfirmTimeout: confirmTimeout, ReconnectMin: time.Second, CloseTimeout: time.Second},
+		pool:      make(chan *confirmChannel, 1),
+		lost:      make(chan struct{}),
+		declared:  map[string]bool{},
+		connected: true,
+		buffer:    make(chan message, 1),
+		closed:    make(chan struct{}),
+	}
+}
+
+func TestPublishConnectionClosedWhilePublishing(t *testing.T) {
+	cases := map[string]struct {
+		ctx       context.Context
+		wantErr   error
+		wantQueue int
+	}{
+		"buffered":       {ctx: context.Background(), wantQueue: 1},
+		"without buffer": {ctx: WithoutBuffer(context.Background()), wantErr: ErrUnavailable},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			p := newDisconnectedPublisher(time.Minute)
+			res := make(chan error, 1)
+			go func() {
+				res <- p.Publish(c.ctx, "queue", "", map[string]interface{}{"id": "1"})
+			}()
+
+			// The publish is waiting for a channel when the connection is closed
+			time.Sleep(50 * time.Millisecond)
+			p.mu.Lock()
+			p.connected = false
+			p.mu.Unlock()
+			close(p.lost)
+
+			select {
+			case err := <-res:
+				if err != c.wantErr {
+					t.Fatalf("expected %v, got %v", c.wantErr, err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("publish is blocked after the connection is closed")
+			}
+			if len(p.buffer) != c.wantQueue {
+				t.Fatalf("expected %d buffered message, got %d", c.wantQueue, len(p.buffer))
+			}
+		})
+	}
+}
+
+func TestPublishNoChannelUntilConfirmTimeout(t *testing.T) {
+	p := newDisconnectedPublisher(50 * time.Millisecond)
+
+	start := time.Now()
+	err := p.Publish(WithoutBuffer(context.Background()), "queue", "", map[string]interface{}{"id": "1"})
+	if err != ErrUnavailable {
+		t.Fatalf("expected %v, got %v", ErrUnavailable, err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("publish waited %s for a channel", elapsed)
+	}
+
+	err = p.Publish(context.Background(), "queue", "", map[string]interface{}{"id": "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.buffer) != 1 {
+		t.Fatalf("expected the message to be buffered, got %d", len(p.buffer))
+	}
+}
@@ -4,12 +4,57 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"kriyapeople/usecase"
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
 )
 
+var (
+	// DefaultReconnectMin ...
+	DefaultReconnectMin = time.Second
+	// DefaultReconnectMax ...
+	DefaultReconnectMax = 30 * time.Second
+	// DefaultConfirmTimeout wait for the broker confirmation of the retry and dead letter publish
+	DefaultConfirmTimeout = 5 * time.Second
+
+	// ErrShutdown consumer is shut down while waiting for the connection
+	ErrShutdown = errors.New("amqp_consumer_shutdown")
+)
+
+// HandlerFunc process one delivery. Nil error ack the delivery, error wrapped by Requeue is retried
+// after a delay up to MaxFailCounter attempts and any other error send it into the dead letter queue
+type HandlerFunc func(d amqp.Delivery) error
+
+// requeueError ...
+type requeueError struct {
+	err error
+}
+
+func (e requeueError) Error() string {
+	return e.err.Error()
+}
+
+func (e requeueError) Unwrap() error {
+	return e.err
+}
+
+// Requeue mark the error as temporary, ex: database or storage is not available
+func Requeue(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return requeueError{err: err}
+}
+
+// IsRequeue ...
+func IsRequeue(err error) bool {
+	var target requeueError
+
+	return errors.As(err, &target)
+}
+
 // Consumer holds all infromation
 // about the RabbitMQ connection
 // This setup does limit a consumer
@@ -26,15 +71,24 @@ type Consumer struct {
 	exchange     string // exchange that we will bind to
 	exchangeType string // topic, direct, etc...
 	bindingKey   string // routing key that we are using
-}
 
-// FailCounter ...
-type FailCounter struct {
-	Counter int
-}
+	reconnectMin time.Duration
+	reconnectMax time.Duration
+	maxFail      int
+	retryDelays  []time.Duration
+	declared     map[string]bool // delay and dead letter queues declared on the current connection
 
-// MaxFailCounter ...
-var MaxFailCounter = 3
+	// publish channel in confirm mode used by the retry and dead letter publish, one message in flight
+	publishMu      sync.Mutex
+	publish        *amqp.Channel
+	confirms       chan amqp.Confirmation
+	confirmTimeout time.Duration
+
+	mu       sync.Mutex
+	quit     chan struct{}
+	closing  bool
+	handlers sync.WaitGroup // running handler goroutines, waited on shutdown
+}
 
 // NewConsumer returns a Consumer struct
 // that has been initialized properly
@@ -53,56 +107,79 @@ func NewConsumer(
 		exchangeType: exchangeType,
 		bindingKey:   bindingKey,
 		done:         make(chan error),
+		reconnectMin: DefaultReconnectMin,
+		reconnectMax: DefaultReconnectMax,
+		maxFail:      MaxFailCounter,
+		retryDelays:  DefaultRetryDelays,
+		declared:     map[string]bool{},
+		quit:         make(chan struct{}),
+
+		confirmTimeout: DefaultConfirmTimeout,
 	}
 
 }
 
-// ReConnect is called in places where NotifyClose() channel is called
-// wait 30 seconds before trying to reconnect. Any shorter amount of time
-// will  likely destroy the error log while waiting for servers to come
-// back online. This requires two parameters which is just to satisfy
-// the AccounceQueue call and allows greater flexability
-func (c *Consumer) ReConnect(queueName, bindingKey string) (<-chan amqp.Delivery, error) {
-	time.Sleep(30 * time.Second)
-
-	if err := c.Connect(); err != nil {
-		log.Printf("Could not connect in reconnect call: %v", err.Error())
+// SetReconnect set the first and the maximum wait between reconnection attempts
+func (c *Consumer) SetReconnect(min, max time.Duration) *Consumer {
+	if min > 0 {
+		c.reconnectMin = min
 	}
-
-	deliveries, err := c.AnnounceQueue(queueName, bindingKey)
-	if err != nil {
-		return deliveries, errors.New("Couldn't connect")
+	if max > 0 {
+		c.reconnectMax = max
+	}
+	if c.reconnectMax < c.reconnectMin {
+		c.reconnectMax = c.reconnectMin
 	}
 
-	return deliveries, nil
+	return c
 }
 
-// Connect to RabbitMQ server
-func (c *Consumer) Connect() error {
+// ReConnect is called in places where NotifyClose() channel is called.
+// Connect and AnnounceQueue are retried with capped exponential backoff
+// until they succeed, so an outage of the broker never terminates the worker.
+// ErrShutdown is returned when the consumer is shut down while waiting
+func (c *Consumer) ReConnect(queueName, bindingKey string) (<-chan amqp.Delivery, error) {
+	wait := c.reconnectMin
+	for {
+		select {
+		case <-c.quit:
+			return nil, ErrShutdown
+		case <-time.After(wait):
+		}
 
-	var err error
+		err := c.Connect()
+		if err == nil {
+			deliveries, err := c.AnnounceQueue(queueName, bindingKey)
+			if err == nil {
+				return deliveries, nil
+			}
+			c.closeConn()
+		}
+		wait *= 2
+		if wait > c.reconnectMax {
+			wait = c.reconnectMax
+		}
+		log.Printf("reconnect %q failed, retry in %s: %v", queueName, wait, err)
+	}
+}
 
+// Connect to RabbitMQ server
+func (c *Consumer) Connect() error {
 	log.Printf("dialing %q", c.uri)
-	c.conn, err = amqp.Dial(c.uri)
+	conn, err := amqp.Dial(c.uri)
 	if err != nil {
 		return fmt.Errorf("Dial: %s", err)
 	}
 
-	go func() {
-		// Waits here for the channel to be closed
-		log.Printf("closing: %s", <-c.conn.NotifyClose(make(chan *amqp.Error)))
-		// Let Handle know it's not time to reconnect
-		c.done <- errors.New("Channel Closed")
-	}()
-
 	log.Printf("got Connection, getting Channel")
-	c.channel, err = c.conn.Channel()
+	channel, err := conn.Channel()
 	if err != nil {
+		conn.Close()
 		return fmt.Errorf("Channel: %s", err)
 	}
 
 	log.Printf("got Channel, declaring Exchange (%q)", c.exchange)
-	if err = c.channel.ExchangeDeclare(
+	if err = channel.ExchangeDeclare(
 		c.exchange,     // name of the exchange
 		c.exchangeType, // type
 		true,           // durable
@@ -111,22 +188,89 @@ func (c *Consumer) Connect() error {
 		false,          // noWait
 		nil,            // arguments
 	); err != nil {
+		conn.Close()
 		return fmt.Errorf("Exchange Declare: %s", err)
 	}
 
+	publish, confirms, err := openConfirmChannel(conn)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("Publish Channel: %s", err)
+	}
+
+	c.mu.Lock()
+	c.conn = conn
+	c.channel = channel
+	c.declared = map[string]bool{}
+	c.mu.Unlock()
+
+	c.publishMu.Lock()
+	c.publish = publish
+	c.confirms = confirms
+	c.publishMu.Unlock()
+
+	go func() {
+		// Waits here for the channel to be closed
+		log.Printf("closing: %s", <-conn.NotifyClose(make(chan *amqp.Error)))
+
+		// Connection closed by closeConn or replaced already is not reconnected
+		c.mu.Lock()
+		current := c.conn == conn
+		c.mu.Unlock()
+		if !current {
+			return
+		}
+
+		// Let Handle know it's time to reconnect, nobody listens after shutdown
+		select {
+		case c.done <- errors.New("Channel Closed"):
+		case <-c.quit:
+		}
+	}()
+
 	return nil
 }
 
+// openConfirmChannel ...
+func openConfirmChannel(conn *amqp.Connection) (*amqp.Channel, chan amqp.Confirmation, error) {
+	channel, err := conn.Channel()
+	if err != nil {
+		return nil, nil, err
+	}
+	err = channel.Confirm(false)
+	if err != nil {
+		channel.Close()
+		return nil, nil, err
+	}
+
+	return channel, channel.NotifyPublish(make(chan amqp.Confirmation, 1)), nil
+}
+
+// closeConn ...
+func (c *Consumer) closeConn() {
+	c.mu.Lock()
+	conn := c.conn
+	c.conn = nil
+	c.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+}
+
 // AnnounceQueue sets the queue that will be listened to for this
 // connection...
 func (c *Consumer) AnnounceQueue(queueName, bindingKey string) (<-chan amqp.Delivery, error) {
+	c.mu.Lock()
+	channel := c.channel
+	c.mu.Unlock()
+
 	args := amqp.Table{
 		"x-dead-letter-exchange":    "",
 		"x-dead-letter-routing-key": bindingKey,
 	}
 
 	log.Printf("declared Exchange, declaring Queue %q", queueName)
-	queue, err := c.channel.QueueDeclare(
+	queue, err := channel.QueueDeclare(
 		queueName, // name of the queue
 		true,      // durable
 		false,     // delete when usused
@@ -139,7 +283,7 @@ func (c *Consumer) AnnounceQueue(queueName, bindingKey string) (<-chan amqp.Deli
 	}
 
 	log.Printf("declared Exchange, declaring dead letter Queue %q", bindingKey)
-	_, err = c.channel.QueueDeclare(
+	_, err = channel.QueueDeclare(
 		bindingKey, // name of the queue
 		true,       // durable
 		false,      // delete when usused
@@ -160,12 +304,12 @@ func (c *Consumer) AnnounceQueue(queueName, bindingKey string) (<-chan amqp.Deli
 	// I would reccomend upping the about of Threads and Processors the go process
 	// uses before changing this although you will eventually need to reach some
 	// balance between threads, procs, and Qos.
-	err = c.channel.Qos(50, 0, false)
+	err = channel.Qos(50, 0, false)
 	if err != nil {
 		return nil, fmt.Errorf("Error setting qos: %s", err)
 	}
 
-	if err = c.channel.QueueBind(
+	if err = channel.QueueBind(
 		queue.Name, // name of the queue
 		bindingKey, // bindingKey
 		c.exchange, // sourceExchange
@@ -176,7 +320,7 @@ func (c *Consumer) AnnounceQueue(queueName, bindingKey string) (<-chan amqp.Deli
 	}
 
 	log.Printf("Queue bound to Exchange, starting Consume (consumer tag %q)", c.consumerTag)
-	deliveries, err := c.channel.Consume(
+	deliveries, err := channel.Consume(
 		queue.Name,    // name
 		c.consumerTag, // consumerTag,
 		false,         // noAck
@@ -192,39 +336,118 @@ func (c *Consumer) AnnounceQueue(queueName, bindingKey string) (<-chan amqp.Deli
 	return deliveries, nil
 }
 
-// Handle has all the logic to make sure your program keeps running
-// d should be a delievey channel as created when you call AnnounceQueue
-// fn should be a function that handles the processing of deliveries
-// this should be the last thing called in main as code under it will
-// become unreachable unless put int a goroutine. The q and rk params
-// are redundent but allow you to have multiple queue listeners in main
-// without them you would be tied into only using one queue per connection
+// Handle has all the logic to make sure your program keeps running.
+// It connects with backoff, starts threads goroutines calling fn for every delivery
+// and reconnects when the connection is closed. It returns after Shutdown,
+// so run it in a goroutine for every queue
 func (c *Consumer) Handle(
-	d <-chan amqp.Delivery,
-	fn func(<-chan amqp.Delivery, *usecase.ContractUC),
+	fn HandlerFunc,
 	threads int,
 	queue string,
 	routingKey string,
-	uc usecase.ContractUC,
 ) {
+	if threads <= 0 {
+		threads = 1
+	}
 
-	var err error
-
-	for {
+	d, err := c.connectNow(queue, routingKey)
+	for err == nil {
+		c.mu.Lock()
+		if c.closing {
+			c.mu.Unlock()
+			break
+		}
 		for i := 0; i < threads; i++ {
-			go fn(d, &uc)
+			c.handlers.Add(1)
+			go c.work(d, fn, queue, routingKey)
 		}
+		c.mu.Unlock()
 
-		// Go into reconnect loop when
-		// c.done is passed non nil values
-		if <-c.done != nil {
-			d, err = c.ReConnect(queue, routingKey)
-			if err != nil {
-				// Very likely chance of failing
-				// should not cause worker to terminate
-				log.Fatalf("Reconnecting Error: %s", err)
-			}
+		// Go into reconnect loop when the connection is closed
+		select {
+		case <-c.done:
+		case <-c.quit:
+			return
+		}
+
+		d, err = c.ReConnect(queue, routingKey)
+		if err == nil {
+			log.Printf("reconnected %q", queue)
+		}
+	}
+}
+
+// connectNow connect right away, falling back to ReConnect
+func (c *Consumer) connectNow(queue, routingKey string) (<-chan amqp.Delivery, error) {
+	err := c.Connect()
+	if err == nil {
+		d, err := c.AnnounceQueue(queue, routingKey)
+		if err == nil {
+			return d, nil
+		}
+		c.closeConn()
+	}
+	log.Printf("connect %q failed: %v", queue, err)
+
+	return c.ReConnect(queue, routingKey)
+}
+
+// work process the deliveries until the channel is closed or the consumer is cancelled
+func (c *Consumer) work(d <-chan amqp.Delivery, fn HandlerFunc, queue, deadLetter string) {
+	defer c.handlers.Done()
+
+	for delivery := range d {
+		err := c.call(fn, delivery)
+		if err != nil {
+			c.failed(delivery, queue, deadLetter, err)
+			continue
+		}
+		delivery.Ack(false)
+	}
+}
+
+// call recover the panic of the handler so one bad message does not stop the worker
+func (c *Consumer) call(fn HandlerFunc, d amqp.Delivery) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
 		}
-		log.Println("Reconnected... possibly")
+	}()
+
+	return fn(d)
+}
+
+// Shutdown stop consuming new deliveries, wait up to timeout for the in-flight deliveries
+// and close the connection. Unacked deliveries are returned to the queue by the broker
+func (c *Consumer) Shutdown(timeout time.Duration) error {
+	c.mu.Lock()
+	if c.closing {
+		c.mu.Unlock()
+		return nil
 	}
+	c.closing = true
+	close(c.quit)
+	channel := c.channel
+	c.mu.Unlock()
+
+	if channel != nil {
+		// Cancel close the deliveries channel, the handlers return after the current delivery
+		channel.Cancel(c.consumerTag, false)
+	}
+
+	finished := make(chan struct{})
+	go func() {
+		c.handlers.Wait()
+		close(finished)
+	}()
+
+	var err error
+	select {
+	case <-finished:
+	case <-time.After(timeout):
+		err = fmt.Errorf("%s: in-flight deliveries did not finish in %s", c.consumerTag, timeout)
+	}
+	c.closeConn()
+
+	return err
 }
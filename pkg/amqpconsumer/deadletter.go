@@ -0,0 +1,234 @@
+package amqpconsumer
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+var (
+	// ErrReplayNack broker refused the replayed message
+	ErrReplayNack = errors.New("amqp_replay_nack")
+	// ErrReplayTimeout broker did not confirm the replayed message in time
+	ErrReplayTimeout = errors.New("amqp_replay_timeout")
+)
+
+// DeadLetterMessage ...
+type DeadLetterMessage struct {
+	ID          string
+	ContentType string
+	Body        []byte
+	RetryCount  int
+	LastError   string
+	Reason      string
+	Timestamp   time.Time
+}
+
+// MessageID id of the message to select it on replay or purge, message published without id use the hash of the body
+func MessageID(d amqp.Delivery) string {
+	if d.MessageId != "" {
+		return d.MessageId
+	}
+	sum := sha1.Sum(d.Body)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// deathReason reason of the latest x-death entry added by the broker, ex: rejected or expired
+func deathReason(headers amqp.Table) string {
+	deaths, ok := headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return ""
+	}
+	death, ok := deaths[0].(amqp.Table)
+	if !ok {
+		return ""
+	}
+	reason, _ := death["reason"].(string)
+
+	return reason
+}
+
+// DeadLetter browse and reprocess the dead letter queue of one incoming queue
+type DeadLetter struct {
+	conn       *amqp.Connection
+	channel    *amqp.Channel
+	confirms   chan amqp.Confirmation
+	queue      string
+	deadLetter string
+}
+
+// OpenDeadLetter open a dedicated connection, it must be closed after use
+func OpenDeadLetter(uri, queue, deadLetter string) (res *DeadLetter, err error) {
+	conn, err := amqp.Dial(uri)
+	if err != nil {
+		return res, fmt.Errorf("Dial: %s", err)
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return res, fmt.Errorf("Channel: %s", err)
+	}
+	err = channel.Confirm(false)
+	if err != nil {
+		conn.Close()
+		return res, fmt.Errorf("Confirm: %s", err)
+	}
+
+	res = &DeadLetter{
+		conn:       conn,
+		channel:    channel,
+		confirms:   channel.NotifyPublish(make(chan amqp.Confirmation, 1)),
+		queue:      queue,
+		deadLetter: deadLetter,
+	}
+
+	return res, err
+}
+
+// Close ...
+func (dl *DeadLetter) Close() error {
+	return dl.conn.Close()
+}
+
+// Count ready messages in the dead letter queue
+func (dl *DeadLetter) Count() (int, error) {
+	q, err := dl.channel.QueueInspect(dl.deadLetter)
+
+	return q.Messages, err
+}
+
+// scan get up to limit messages without removing them, take decide whether the message is acked,
+// every other message is returned into the queue
+func (dl *DeadLetter) scan(limit int, take func(d amqp.Delivery) (bool, error)) (err error) {
+	deliveries := []amqp.Delivery{}
+	defer func() {
+		for _, d := range deliveries {
+			d.Nack(false, true)
+		}
+	}()
+
+	for i := 0; i < limit; i++ {
+		d, ok, err := dl.channel.Get(dl.deadLetter, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		taken, err := take(d)
+		if err != nil {
+			deliveries = append(deliveries, d)
+			return err
+		}
+		if taken {
+			err = d.Ack(false)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return nil
+}
+
+// List return up to limit messages from the head of the dead letter queue, the messages stay in the queue
+func (dl *DeadLetter) List(limit int) (res []DeadLetterMessage, err error) {
+	err = dl.scan(limit, func(d amqp.Delivery) (bool, error) {
+		lastError, _ := d.Headers[HeaderLastError].(string)
+		res = append(res, DeadLetterMessage{
+			ID:          MessageID(d),
+			ContentType: d.ContentType,
+			Body:        d.Body,
+			RetryCount:  RetryCount(d.Headers),
+			LastError:   lastError,
+			Reason:      deathReason(d.Headers),
+			Timestamp:   d.Timestamp,
+		})
+
+		return false, nil
+	})
+
+	return res, err
+}
+
+// Replay publish the selected messages back into the incoming queue with a fresh retry counter,
+// the message is removed from the dead letter queue only after the broker confirm the replay
+func (dl *DeadLetter) Replay(ids []string, limit int) (res []string, err error) {
+	selected := map[string]bool{}
+	for _, id := range ids {
+		selected[id] = true
+	}
+
+	err = dl.scan(limit, func(d amqp.Delivery) (bool, error) {
+		id := MessageID(d)
+		if !selected[id] {
+			return false, nil
+		}
+
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			if k == HeaderRetryCount || k == HeaderLastError || k == "x-death" {
+				continue
+			}
+			headers[k] = v
+		}
+		err := dl.channel.Publish("", dl.queue, false, false, amqp.Publishing{
+			Headers:      headers,
+			ContentType:  d.ContentType,
+			DeliveryMode: amqp.Persistent,
+			MessageId:    d.MessageId,
+			Timestamp:    d.Timestamp,
+			Body:         d.Body,
+		})
+		if err != nil {
+			return false, err
+		}
+		// The message stay in the dead letter queue when the confirmation is lost
+		timer := time.NewTimer(DefaultConfirmTimeout)
+		defer timer.Stop()
+		select {
+		case confirm, ok := <-dl.confirms:
+			if !ok || !confirm.Ack {
+				return false, ErrReplayNack
+			}
+		case <-timer.C:
+			return false, ErrReplayTimeout
+		}
+		res = append(res, id)
+		delete(selected, id)
+
+		return true, nil
+	})
+
+	return res, err
+}
+
+// Purge remove the selected messages, every message is removed when ids is empty
+func (dl *DeadLetter) Purge(ids []string, limit int) (res int, err error) {
+	if len(ids) == 0 {
+		return dl.channel.QueuePurge(dl.deadLetter, false)
+	}
+
+	selected := map[string]bool{}
+	for _, id := range ids {
+		selected[id] = true
+	}
+	err = dl.scan(limit, func(d amqp.Delivery) (bool, error) {
+		if !selected[MessageID(d)] {
+			return false, nil
+		}
+		res++
+
+		return true, nil
+	})
+
+	return res, err
+}
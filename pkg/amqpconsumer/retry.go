@@ -0,0 +1,194 @@
+package amqpconsumer
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/streadway/amqp"
+)
+
+const (
+	// HeaderRetryCount failed attempts of the message so far
+	HeaderRetryCount = "x-retry-count"
+	// HeaderLastError error of the last failed attempt
+	HeaderLastError = "x-last-error"
+)
+
+var (
+	// ErrRepublishNack broker refused the retry or dead letter publish
+	ErrRepublishNack = errors.New("amqp_republish_nack")
+	// ErrRepublishTimeout broker did not confirm the retry or dead letter publish in time
+	ErrRepublishTimeout = errors.New("amqp_republish_timeout")
+
+	// MaxFailCounter failed attempts before the message goes to the dead letter queue
+	MaxFailCounter = 3
+	// DefaultRetryDelays wait before each retry, the last delay is used for the rest of the attempts
+	DefaultRetryDelays = []time.Duration{10 * time.Second, time.Minute}
+)
+
+// SetRetry set the maximum failed attempts and the wait before each retry
+func (c *Consumer) SetRetry(max int, delays []time.Duration) *Consumer {
+	if max > 0 {
+		c.maxFail = max
+	}
+	if len(delays) > 0 {
+		c.retryDelays = delays
+	}
+
+	return c
+}
+
+// ParseDelays parse comma separated durations, ex: 10s,1m,10m. Invalid value is skipped
+func ParseDelays(value string) (res []time.Duration) {
+	for _, v := range strings.Split(value, ",") {
+		delay, err := time.ParseDuration(strings.TrimSpace(v))
+		if err == nil && delay > 0 {
+			res = append(res, delay)
+		}
+	}
+
+	return res
+}
+
+// RetryCount read the retry counter header, the value type depends on the publisher
+func RetryCount(headers amqp.Table) int {
+	switch v := headers[HeaderRetryCount].(type) {
+	case int:
+		return v
+	case int8:
+		return int(v)
+	case int16:
+		return int(v)
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	}
+
+	return 0
+}
+
+// RetryQueueName delay queue of the queue, ex: image_process.incoming.queue.retry.10s
+func RetryQueueName(queueName string, delay time.Duration) string {
+	return queueName + ".retry." + delay.String()
+}
+
+// retryDelay ...
+func (c *Consumer) retryDelay(attempt int) time.Duration {
+	if attempt > len(c.retryDelays) {
+		attempt = len(c.retryDelays)
+	}
+
+	return c.retryDelays[attempt-1]
+}
+
+// failed decide what happen to the failed delivery. Temporary failure is published into the delay queue
+// which dead letter it back into the incoming queue after the ttl, permanent failure and the last attempt
+// is published into the dead letter queue with the retry headers
+func (c *Consumer) failed(d amqp.Delivery, queueName, deadLetter string, cause error) {
+	attempt := RetryCount(d.Headers) + 1
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[HeaderRetryCount] = int32(attempt)
+	headers[HeaderLastError] = cause.Error()
+
+	if IsRequeue(cause) && attempt < c.maxFail {
+		delay := c.retryDelay(attempt)
+		err := c.republish(d, RetryQueueName(queueName, delay), headers, amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queueName,
+		})
+		if err != nil {
+			log.Printf("retry %q message failed, requeue: %v", queueName, err)
+			d.Nack(false, true)
+			return
+		}
+		log.Printf("retry %q message in %s (%d/%d): %v", queueName, delay, attempt, c.maxFail, cause)
+		d.Ack(false)
+		return
+	}
+
+	err := c.republish(d, deadLetter, headers, nil)
+	if err != nil {
+		// The incoming queue dead letter the rejected message without the retry headers
+		log.Printf("dead letter %q message failed, reject: %v", queueName, err)
+		d.Nack(false, false)
+		return
+	}
+	log.Printf("dead letter %q message after %d attempts: %v", queueName, attempt, cause)
+	d.Ack(false)
+}
+
+// republish publish the delivery into the queue through default exchange and wait for the broker confirmation,
+// so the delivery is acked only after the copy is safe. The queue is declared once per connection
+func (c *Consumer) republish(d amqp.Delivery, queueName string, headers, args amqp.Table) (err error) {
+	c.publishMu.Lock()
+	defer c.publishMu.Unlock()
+	if c.publish == nil {
+		return amqp.ErrClosed
+	}
+
+	c.mu.Lock()
+	conn := c.conn
+	declared := c.declared[queueName]
+	c.mu.Unlock()
+
+	// Channel with unknown state is replaced, late confirmation must not be read by the next message
+	healthy := false
+	defer func() {
+		if healthy || conn == nil {
+			return
+		}
+		c.publish.Close()
+		publish, confirms, openErr := openConfirmChannel(conn)
+		if openErr != nil {
+			// Connection is gone, the channel is opened again on reconnect
+			c.publish = nil
+			return
+		}
+		c.publish, c.confirms = publish, confirms
+	}()
+
+	if !declared {
+		_, err = c.publish.QueueDeclare(queueName, true, false, false, false, args)
+		if err != nil {
+			return err
+		}
+		c.mu.Lock()
+		c.declared[queueName] = true
+		c.mu.Unlock()
+	}
+
+	err = c.publish.Publish("", queueName, false, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	timer := time.NewTimer(c.confirmTimeout)
+	defer timer.Stop()
+	select {
+	case confirm, ok := <-c.confirms:
+		if !ok {
+			return amqp.ErrClosed
+		}
+		healthy = true
+		if !confirm.Ack {
+			return ErrRepublishNack
+		}
+		return nil
+	case <-timer.C:
+		return ErrRepublishTimeout
+	}
+}
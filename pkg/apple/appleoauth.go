@@ -1,20 +1,11 @@
 package apple
 
 import (
-	"bytes"
 	"crypto/ecdsa"
-	"crypto/rsa"
 	"crypto/x509"
-	"encoding/base64"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
-	"kriyapeople/pkg/interfacepkg"
-	"io/ioutil"
-	"math/big"
-	"net/http"
-	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -23,131 +14,70 @@ import (
 // AuthKeysAPI ...
 const AuthKeysAPI = "https://appleid.apple.com/auth/keys"
 
-// GetJWK ...
-func GetJWK() (res map[string]interface{}, err error) {
-	response, err := http.Get(AuthKeysAPI)
-	if err != nil {
-		return res, errors.New("invalid_apple_api")
-	}
-	if response.StatusCode >= 400 {
-		return res, errors.New("invalid_apple_api")
-	}
-
-	defer response.Body.Close()
-	responseBody, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return res, errors.New("error_read_body")
-	}
-	err = json.Unmarshal(responseBody, &res)
-	fmt.Println(res["error"])
-	if err != nil {
-		fmt.Println(err)
-		return res, err
-	}
+// Issuer ...
+const Issuer = "https://appleid.apple.com"
 
-	return res, err
+// Credential ...
+type Credential struct {
+	ClientIDs []string
+	Keys      KeyProvider
 }
 
-// GetPublicKey ...
-func GetPublicKey(kid string) (res string, err error) {
-	JwkArrayInterface, err := GetJWK()
-	if err != nil {
-		return res, err
-	}
+// VerifyJWT verify apple identity token signature, issuer, audience, expiry and nonce.
+// Nonce is only checked when it is not empty.
+func (cred *Credential) VerifyJWT(token, email, nonce string) (claims jwt.MapClaims, err error) {
+	claims = jwt.MapClaims{}
 
-	JwkArray := JwkArrayInterface["keys"].([]interface{})
-	for _, jwk := range JwkArray {
-		JwkKid := interfacepkg.InterfaceStringToString(jwk, "kid")
-		if JwkKid == kid {
-			kty := interfacepkg.InterfaceStringToString(jwk, "kty")
-			if kty != "RSA" {
-				return res, errors.New("invalid key type")
-			}
-
-			// decode the base64 bytes for n
-			n := interfacepkg.InterfaceStringToString(jwk, "n")
-			nb, err := base64.RawURLEncoding.DecodeString(n)
-			if err != nil {
-				return res, err
-			}
-
-			e := 0
-			// The default exponent is usually 65537, so just compare the
-			// base64 for [1,0,1] or [0,1,0,1]
-			JwkE := interfacepkg.InterfaceStringToString(jwk, "e")
-			if JwkE == "AQAB" || JwkE == "AAEAAQ" {
-				e = 65537
-			} else {
-				// need to decode "e" as a big-endian int
-				return res, errors.New("need to deocde e")
-			}
-
-			pk := &rsa.PublicKey{
-				N: new(big.Int).SetBytes(nb),
-				E: e,
-			}
-
-			der, err := x509.MarshalPKIXPublicKey(pk)
-			if err != nil {
-				return res, err
-			}
-
-			block := &pem.Block{
-				Type:  "RSA PUBLIC KEY",
-				Bytes: der,
-			}
-
-			var out bytes.Buffer
-			pem.Encode(&out, block)
-			return out.String(), err
+	// Parse and verify token with apple public key
+	_, err = jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		if jwt.SigningMethodRS256 != token.Method {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("empty_apple_kid")
 		}
-	}
-	return res, err
-}
-
-// VerifyJWT ...
-func VerifyJWT(token, email string) (err error) {
-	claims := jwt.MapClaims{}
 
-	// Parse token
-	jwtToken, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
-		return nil, err
+		return cred.Keys.GetKey(kid)
 	})
 	if err != nil {
-		return err
+		return claims, err
 	}
 
-	// Generate Public Key
-	pubKey, err := GetPublicKey(jwtToken.Header["kid"].(string))
-	if err != nil {
-		return err
+	if !claims.VerifyExpiresAt(time.Now().Unix(), true) {
+		return claims, errors.New("Apple token already expired")
 	}
-	key, err := jwt.ParseRSAPublicKeyFromPEM([]byte(pubKey))
-	if err != nil {
-		return err
+	if !claims.VerifyIssuer(Issuer, true) {
+		return claims, errors.New("Invalid Apple issuer")
 	}
-
-	// Verify token with Public Key
-	parts := strings.Split(token, ".")
-	err = jwt.SigningMethodRS256.Verify(strings.Join(parts[0:2], "."), parts[2], key)
-	if err != nil {
-		return err
+	validAudience := false
+	for _, clientID := range cred.ClientIDs {
+		if clientID != "" && claims.VerifyAudience(clientID, true) {
+			validAudience = true
+			break
+		}
 	}
-
-	exp, _ := claims["exp"].(int64)
-	if exp < time.Now().Unix() {
-		return errors.New("Apple token already expired")
+	if !validAudience {
+		return claims, errors.New("Invalid Apple audience")
 	}
-	appleEmail := claims["email"].(string)
+	if nonce != "" {
+		claimNonce, _ := claims["nonce"].(string)
+		if claimNonce != nonce {
+			return claims, errors.New("Invalid Apple nonce")
+		}
+	}
+
+	appleEmail, _ := claims["email"].(string)
 	if appleEmail != email {
-		return errors.New("Invalid Apple ID")
+		return claims, errors.New("Invalid Apple ID")
 	}
-	emailVerified, _ := claims["email_verified"].(bool)
-	if emailVerified != true {
-		return errors.New("Email is not verified")
+	// Apple send email_verified either as boolean or as string
+	emailVerified := fmt.Sprintf("%v", claims["email_verified"])
+	if emailVerified != "true" {
+		return claims, errors.New("Email is not verified")
 	}
 
-	return err
+	return claims, nil
 }
 
 // generate private key from pem encoded string
@@ -0,0 +1,203 @@
+package apple
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	res, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            Issuer,
+		"aud":            "com.kriyapeople.app",
+		"exp":            float64(time.Now().Add(time.Hour).Unix()),
+		"nonce":          "nonce",
+		"email":          "admin@kriyapeople.com",
+		"email_verified": "true",
+	}
+}
+
+func TestVerifyJWT(t *testing.T) {
+	key := generateKey(t)
+	cred := Credential{
+		ClientIDs: []string{"", "com.kriyapeople.web", "com.kriyapeople.app"},
+		Keys:      NewStaticKeys(map[string]*rsa.PublicKey{"kid1": &key.PublicKey}),
+	}
+
+	cases := []struct {
+		name    string
+		kid     string
+		key     *rsa.PrivateKey
+		claims  func(jwt.MapClaims)
+		email   string
+		nonce   string
+		isValid bool
+	}{
+		{name: "valid", isValid: true},
+		{name: "valid without nonce check", nonce: "-", isValid: true},
+		{name: "email_verified as boolean", claims: func(c jwt.MapClaims) { c["email_verified"] = true }, isValid: true},
+		{name: "bad issuer", claims: func(c jwt.MapClaims) { c["iss"] = "https://example.com" }},
+		{name: "bad audience", claims: func(c jwt.MapClaims) { c["aud"] = "com.other.app" }},
+		{name: "empty audience", claims: func(c jwt.MapClaims) { c["aud"] = "" }},
+		{name: "expired", claims: func(c jwt.MapClaims) { c["exp"] = float64(time.Now().Add(-time.Minute).Unix()) }},
+		{name: "missing exp", claims: func(c jwt.MapClaims) { delete(c, "exp") }},
+		{name: "bad nonce", claims: func(c jwt.MapClaims) { c["nonce"] = "other" }},
+		{name: "unknown kid", kid: "kid2"},
+		{name: "signed by other key", key: generateKey(t)},
+		{name: "other email", email: "other@kriyapeople.com"},
+		{name: "unverified email", claims: func(c jwt.MapClaims) { c["email_verified"] = "false" }},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			claims := validClaims()
+			if c.claims != nil {
+				c.claims(claims)
+			}
+			kid, signKey, email, nonce := "kid1", key, "admin@kriyapeople.com", "nonce"
+			if c.kid != "" {
+				kid = c.kid
+			}
+			if c.key != nil {
+				signKey = c.key
+			}
+			if c.email != "" {
+				email = c.email
+			}
+			if c.nonce == "-" {
+				nonce = ""
+			}
+
+			_, err := cred.VerifyJWT(signToken(t, signKey, kid, claims), email, nonce)
+			if c.isValid && err != nil {
+				t.Fatalf("expected valid token, got %v", err)
+			}
+			if !c.isValid && err == nil {
+				t.Fatal("expected invalid token")
+			}
+		})
+	}
+}
+
+func TestVerifyJWTRejectOtherAlgorithm(t *testing.T) {
+	key := generateKey(t)
+	cred := Credential{
+		ClientIDs: []string{"com.kriyapeople.app"},
+		Keys:      NewStaticKeys(map[string]*rsa.PublicKey{"kid1": &key.PublicKey}),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, validClaims())
+	token.Header["kid"] = "kid1"
+	signed, err := token.SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = cred.VerifyJWT(signed, "admin@kriyapeople.com", "nonce")
+	if err == nil {
+		t.Fatal("expected HS256 token to be rejected")
+	}
+}
+
+func TestJWKSCacheRefresh(t *testing.T) {
+	keys := map[string]*rsa.PrivateKey{"kid1": generateKey(t)}
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		set := JWKSet{}
+		for kid, key := range keys {
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Kid: kid,
+				Use: "sig",
+				Alg: "RS256",
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	defer server.Close()
+
+	cache := NewJWKSCache(server.URL, time.Hour).(*jwksCache)
+	cache.RefreshInterval = time.Hour
+
+	res, err := cache.GetKey("kid1")
+	if err != nil || res.N.Cmp(keys["kid1"].N) != 0 {
+		t.Fatalf("expected kid1, got %v", err)
+	}
+	if _, err = cache.GetKey("kid1"); err != nil || atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected cached key, got %v after %d fetches", err, hits)
+	}
+
+	// Unknown kid does not refresh again within the refresh interval
+	keys["kid2"] = generateKey(t)
+	for i := 0; i < 3; i++ {
+		if _, err = cache.GetKey("kid2"); err == nil {
+			t.Fatal("expected unknown kid")
+		}
+	}
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Fatalf("expected 1 fetch, got %d", hits)
+	}
+
+	// Unknown kid refresh once the interval has passed
+	cache.refreshedAt = time.Now().Add(-2 * time.Hour)
+	if _, err = cache.GetKey("kid2"); err != nil {
+		t.Fatalf("expected rotated kid2, got %v", err)
+	}
+	if atomic.LoadInt32(&hits) != 2 {
+		t.Fatalf("expected 2 fetches, got %d", hits)
+	}
+
+	// Keys are fetched again after the ttl
+	cache.fetchedAt = time.Now().Add(-2 * time.Hour)
+	if _, err = cache.GetKey("kid1"); err != nil || atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected refetch after ttl, got %v after %d fetches", err, hits)
+	}
+}
+
+func TestJWKPublicKeyExponent(t *testing.T) {
+	key := generateKey(t)
+	jwk := JWK{
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(3).Bytes()),
+	}
+	res, err := jwk.PublicKey()
+	if err != nil || res.E != 3 {
+		t.Fatalf("expected exponent 3, got %v", err)
+	}
+
+	jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(1).Bytes())
+	if _, err = jwk.PublicKey(); err == nil {
+		t.Fatal("expected invalid exponent")
+	}
+}
@@ -0,0 +1,202 @@
+package apple
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// DefaultJWKSTTL ...
+	DefaultJWKSTTL = 24 * time.Hour
+	// DefaultRefreshInterval minimum wait between two refreshes caused by an unknown kid
+	DefaultRefreshInterval = time.Minute
+)
+
+// KeyProvider resolve apple public key by kid
+type KeyProvider interface {
+	GetKey(kid string) (*rsa.PublicKey, error)
+}
+
+// JWK ...
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSet ...
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey convert jwk into rsa public key
+func (jwk JWK) PublicKey() (res *rsa.PublicKey, err error) {
+	if jwk.Kty != "RSA" {
+		return res, errors.New("invalid_key_type")
+	}
+
+	nb, err := base64.RawURLEncoding.DecodeString(jwk.N)
+	if err != nil {
+		return res, err
+	}
+
+	// "e" is a big-endian unsigned integer
+	eb, err := base64.RawURLEncoding.DecodeString(jwk.E)
+	if err != nil {
+		return res, err
+	}
+	e := new(big.Int).SetBytes(eb)
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return res, errors.New("invalid_key_exponent")
+	}
+
+	res = &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nb),
+		E: int(e.Int64()),
+	}
+
+	return res, err
+}
+
+// ParseJWKSet ...
+func ParseJWKSet(data []byte) (res map[string]*rsa.PublicKey, err error) {
+	set := JWKSet{}
+	err = json.Unmarshal(data, &set)
+	if err != nil {
+		return res, err
+	}
+
+	res = map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		res[jwk.Kid] = key
+	}
+	if len(res) == 0 {
+		return res, errors.New("empty_apple_keys")
+	}
+
+	return res, err
+}
+
+// jwksCache ...
+type jwksCache struct {
+	URL             string
+	TTL             time.Duration
+	RefreshInterval time.Duration
+	Client          *http.Client
+
+	mu          sync.Mutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	refreshedAt time.Time
+}
+
+// NewJWKSCache return key provider which download apple keys once per ttl
+// and refresh early when a token refers to an unknown kid
+func NewJWKSCache(url string, ttl time.Duration) KeyProvider {
+	if ttl <= 0 {
+		ttl = DefaultJWKSTTL
+	}
+
+	return &jwksCache{
+		URL:             url,
+		TTL:             ttl,
+		RefreshInterval: DefaultRefreshInterval,
+		Client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// GetKey ...
+func (cache *jwksCache) GetKey(kid string) (res *rsa.PublicKey, err error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	now := time.Now()
+	if cache.keys == nil || now.Sub(cache.fetchedAt) > cache.TTL {
+		err = cache.refresh(now)
+		if err != nil && cache.keys == nil {
+			return res, err
+		}
+	}
+
+	res, ok := cache.keys[kid]
+	if ok {
+		return res, nil
+	}
+
+	// Unknown kid, apple may have rotated the keys
+	if now.Sub(cache.refreshedAt) < cache.RefreshInterval {
+		return res, errors.New("unknown_apple_kid")
+	}
+	err = cache.refresh(now)
+	if err != nil {
+		return res, err
+	}
+	res, ok = cache.keys[kid]
+	if !ok {
+		return res, errors.New("unknown_apple_kid")
+	}
+
+	return res, nil
+}
+
+// refresh ...
+func (cache *jwksCache) refresh(now time.Time) (err error) {
+	cache.refreshedAt = now
+
+	response, err := cache.Client.Get(cache.URL)
+	if err != nil {
+		return errors.New("invalid_apple_api")
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 400 {
+		return errors.New("invalid_apple_api")
+	}
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return errors.New("error_read_body")
+	}
+	keys, err := ParseJWKSet(responseBody)
+	if err != nil {
+		return err
+	}
+
+	cache.keys = keys
+	cache.fetchedAt = now
+
+	return err
+}
+
+// staticKeys ...
+type staticKeys struct {
+	keys map[string]*rsa.PublicKey
+}
+
+// NewStaticKeys return key provider with fixed keys, used for fixture keys
+func NewStaticKeys(keys map[string]*rsa.PublicKey) KeyProvider {
+	return &staticKeys{keys: keys}
+}
+
+// GetKey ...
+func (s staticKeys) GetKey(kid string) (res *rsa.PublicKey, err error) {
+	res, ok := s.keys[kid]
+	if !ok {
+		return res, errors.New("unknown_apple_kid")
+	}
+
+	return res, err
+}
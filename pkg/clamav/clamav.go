@@ -0,0 +1,136 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// DefaultTimeout ...
+	DefaultTimeout = 30 * time.Second
+	// DefaultChunkSize size of every INSTREAM chunk, must be lower than clamd StreamMaxLength
+	DefaultChunkSize = 64 * 1024
+
+	// ErrUnavailable clamd can not be reached or give unexpected answer
+	ErrUnavailable = errors.New("scanner_unavailable")
+)
+
+// Result ...
+type Result struct {
+	Infected  bool
+	Signature string
+}
+
+// IScanner ...
+type IScanner interface {
+	Scan(reader io.Reader) (Result, error)
+	Ping() error
+}
+
+// Client clamd client using the tcp INSTREAM protocol
+type Client struct {
+	Address   string
+	Timeout   time.Duration
+	ChunkSize int
+}
+
+// NewClient ...
+func NewClient(address string, timeout time.Duration) IScanner {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	return &Client{
+		Address:   address,
+		Timeout:   timeout,
+		ChunkSize: DefaultChunkSize,
+	}
+}
+
+// command send null terminated command and return the reply without the terminator
+func (c *Client) command(cmd string, body io.Reader) (res string, err error) {
+	conn, err := net.DialTimeout("tcp", c.Address, c.Timeout)
+	if err != nil {
+		return res, ErrUnavailable
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.Timeout))
+
+	_, err = conn.Write([]byte("z" + cmd + "\x00"))
+	if err != nil {
+		return res, ErrUnavailable
+	}
+
+	if body != nil {
+		// Every chunk is prefixed with 4 bytes big-endian length, zero length chunk end the stream
+		chunk := make([]byte, c.ChunkSize)
+		size := make([]byte, 4)
+		for {
+			n, readErr := body.Read(chunk)
+			if n > 0 {
+				binary.BigEndian.PutUint32(size, uint32(n))
+				_, err = conn.Write(append(size, chunk[:n]...))
+				if err != nil {
+					return res, ErrUnavailable
+				}
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				return res, readErr
+			}
+		}
+		_, err = conn.Write([]byte{0, 0, 0, 0})
+		if err != nil {
+			return res, ErrUnavailable
+		}
+	}
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && err != io.EOF {
+		return res, ErrUnavailable
+	}
+
+	return string(bytes.TrimRight(reply, "\x00")), nil
+}
+
+// Ping ...
+func (c *Client) Ping() error {
+	reply, err := c.command("PING", nil)
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return ErrUnavailable
+	}
+
+	return nil
+}
+
+// Scan stream the content into clamd, reply is "stream: OK" or "stream: <signature> FOUND"
+func (c *Client) Scan(reader io.Reader) (res Result, err error) {
+	reply, err := c.command("INSTREAM", reader)
+	if err != nil {
+		return res, err
+	}
+
+	reply = strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case reply == "OK":
+		return res, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		res.Infected = true
+		res.Signature = strings.TrimSuffix(reply, " FOUND")
+		return res, nil
+	}
+
+	// ex: "INSTREAM size limit exceeded. ERROR"
+	return res, errors.New(reply)
+}
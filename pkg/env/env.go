@@ -6,7 +6,7 @@ import (
 
 // NewEnvConfig new instance of configuration
 func NewEnvConfig(configPath string) map[string]string {
-	myEnv, err := godotenv.Read("../.env")
+	myEnv, err := godotenv.Read(configPath)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,123 @@
+package event
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+const (
+	// Version current payload version, increase it on breaking change of the data
+	Version = 1
+
+	// UserCreated ...
+	UserCreated = "user.created"
+	// UserUpdated ...
+	UserUpdated = "user.updated"
+	// UserDeleted ...
+	UserDeleted = "user.deleted"
+	// UserLoginSucceeded ...
+	UserLoginSucceeded = "user.login_succeeded"
+	// UserLoginFailed ...
+	UserLoginFailed = "user.login_failed"
+	// RoleChanged ...
+	RoleChanged = "role.changed"
+)
+
+// Types every published event type
+var Types = []string{UserCreated, UserUpdated, UserDeleted, UserLoginSucceeded, UserLoginFailed, RoleChanged}
+
+// Envelope common part of every event, type is used as the routing key
+type Envelope struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	Version    int         `json:"version"`
+	OccurredAt string      `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// UserData the user is referred by id only, the payload is stored as is in the outbox
+type UserData struct {
+	ID       string `json:"id"`
+	RoleID   string `json:"role_id"`
+	IsActive bool   `json:"is_active"`
+}
+
+// FieldChange ...
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// UserUpdatedData secret and personal field is listed in changed fields without the values
+type UserUpdatedData struct {
+	ID            string                 `json:"id"`
+	ChangedFields []string               `json:"changed_fields"`
+	Changes       map[string]FieldChange `json:"changes"`
+}
+
+// UserDeletedData ...
+type UserDeletedData struct {
+	ID string `json:"id"`
+}
+
+// UserLoginSucceededData ...
+type UserLoginSucceededData struct {
+	ID       string `json:"id"`
+	DeviceID string `json:"device_id"`
+}
+
+// UserLoginFailedData id is empty when no user has the email
+type UserLoginFailedData struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// RoleChangedData ...
+type RoleChangedData struct {
+	UserID    string `json:"user_id"`
+	OldRoleID string `json:"old_role_id"`
+	NewRoleID string `json:"new_role_id"`
+}
+
+// New ...
+func New(types string, data interface{}, occurredAt time.Time) Envelope {
+	return Envelope{
+		ID:         xid.New().String(),
+		Type:       types,
+		Version:    Version,
+		OccurredAt: occurredAt.UTC().Format(time.RFC3339),
+		Data:       data,
+	}
+}
+
+// Map convert the envelope into generic map used by the publisher
+func (e Envelope) Map() (res map[string]interface{}) {
+	res = map[string]interface{}{}
+	body, _ := json.Marshal(e)
+	json.Unmarshal(body, &res)
+
+	return res
+}
+
+// Diff add the field into changes when the value is different
+func (d *UserUpdatedData) Diff(field string, old, new interface{}, secret bool) {
+	if old == new {
+		return
+	}
+
+	d.ChangedFields = append(d.ChangedFields, field)
+	if !secret {
+		d.Changes[field] = FieldChange{Old: old, New: new}
+	}
+}
+
+// NewUserUpdatedData ...
+func NewUserUpdatedData(id string) *UserUpdatedData {
+	return &UserUpdatedData{
+		ID:            id,
+		ChangedFields: []string{},
+		Changes:       map[string]FieldChange{},
+	}
+}
@@ -0,0 +1,117 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+)
+
+// Schema subset of json schema used by the event schemas:
+// type, required, properties, additionalProperties, items, enum and const
+type Schema struct {
+	Type                 string             `json:"type"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *json.RawMessage   `json:"additionalProperties"`
+	Items                *Schema            `json:"items"`
+	Enum                 []interface{}      `json:"enum"`
+	Const                interface{}        `json:"const"`
+}
+
+// SchemaFileName ex: user.created.v1.json
+func SchemaFileName(types string, version int) string {
+	return types + ".v" + strconv.Itoa(version) + ".json"
+}
+
+// LoadSchema ...
+func LoadSchema(dir, types string, version int) (res *Schema, err error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, SchemaFileName(types, version)))
+	if err != nil {
+		return res, err
+	}
+	res = &Schema{}
+	err = json.Unmarshal(data, res)
+
+	return res, err
+}
+
+// Validate validate the decoded json value against the schema
+func (s *Schema) Validate(value interface{}) error {
+	return s.validate("$", value)
+}
+
+func (s *Schema) validate(path string, value interface{}) error {
+	if s.Const != nil && fmt.Sprint(s.Const) != fmt.Sprint(value) {
+		return fmt.Errorf("%s: must be %v", path, s.Const)
+	}
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if fmt.Sprint(e) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not allowed", path, value)
+		}
+	}
+
+	switch s.Type {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be object", path)
+		}
+		for _, key := range s.Required {
+			if _, ok := obj[key]; !ok {
+				return fmt.Errorf("%s: %s is required", path, key)
+			}
+		}
+		additional := true
+		if s.AdditionalProperties != nil {
+			json.Unmarshal(*s.AdditionalProperties, &additional)
+		}
+		for key, v := range obj {
+			prop, ok := s.Properties[key]
+			if !ok {
+				if !additional {
+					return fmt.Errorf("%s: %s is not allowed", path, key)
+				}
+				continue
+			}
+			if err := prop.validate(path+"."+key, v); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%s: must be array", path)
+		}
+		if s.Items != nil {
+			for i, v := range arr {
+				if err := s.Items.validate(path+"["+strconv.Itoa(i)+"]", v); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: must be string", path)
+		}
+	case "integer":
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: must be integer", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: must be boolean", path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package event
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var schemaDir = filepath.Join("..", "..", "files", "schema", "event")
+
+// decode marshal the envelope and decode it, the same value received by the consumer
+func decode(t *testing.T, e Envelope) (res interface{}) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = json.Unmarshal(body, &res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// userUpdated every field diffed by the usecases, with the same value types
+func userUpdated() *UserUpdatedData {
+	res := NewUserUpdatedData("bq7b3hh6j1a5p0dbg2vg")
+	res.Diff("email", "old@mail.com", "new@mail.com", true)
+	res.Diff("username", "old", "new", true)
+	res.Diff("password", false, true, true)
+	res.Diff("role_id", "role-1", "role-2", false)
+	res.Diff("is_active", true, false, false)
+	res.Diff("profile_image_id", "", "file-1", false)
+	res.Diff("language", "", "id", false)
+
+	return res
+}
+
+func TestEventSchema(t *testing.T) {
+	cases := map[string][]interface{}{
+		UserCreated: {
+			UserData{ID: "bq7b3hh6j1a5p0dbg2vg", RoleID: "role-1", IsActive: true},
+			UserData{ID: "bq7b3hh6j1a5p0dbg2vg"},
+		},
+		UserUpdated: {
+			userUpdated(),
+			NewUserUpdatedData("bq7b3hh6j1a5p0dbg2vg"),
+		},
+		UserDeleted: {
+			UserDeletedData{ID: "bq7b3hh6j1a5p0dbg2vg"},
+		},
+		UserLoginSucceeded: {
+			UserLoginSucceededData{ID: "bq7b3hh6j1a5p0dbg2vg", DeviceID: "device-1"},
+		},
+		UserLoginFailed: {
+			UserLoginFailedData{ID: "bq7b3hh6j1a5p0dbg2vg", Reason: "invalid_credentials"},
+		},
+		RoleChanged: {
+			RoleChangedData{UserID: "bq7b3hh6j1a5p0dbg2vg", OldRoleID: "role-1", NewRoleID: "role-2"},
+		},
+	}
+
+	for _, types := range Types {
+		t.Run(types, func(t *testing.T) {
+			samples, ok := cases[types]
+			if !ok {
+				t.Fatal("missing sample")
+			}
+			schema, err := LoadSchema(schemaDir, types, Version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, data := range samples {
+				err = schema.Validate(decode(t, New(types, data, time.Now())))
+				if err != nil {
+					t.Fatalf("%+v: %v", data, err)
+				}
+			}
+
+			// The envelope type is part of the schema, the payload of other event must not pass
+			err = schema.Validate(decode(t, New("user.unknown", samples[0], time.Now())))
+			if err == nil {
+				t.Fatal("expected other event type to be rejected")
+			}
+		})
+	}
+}
+
+func TestEventSchemaRejectInvalidData(t *testing.T) {
+	schema, err := LoadSchema(schemaDir, UserUpdated, Version)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := decode(t, New(UserUpdated, userUpdated(), time.Now())).(map[string]interface{})
+	delete(value["data"].(map[string]interface{}), "changed_fields")
+	if schema.Validate(value) == nil {
+		t.Fatal("expected missing changed_fields to be rejected")
+	}
+
+	// the decoded json number is float64, so only the version constraint can reject it
+	value = decode(t, New(UserUpdated, userUpdated(), time.Now())).(map[string]interface{})
+	if err = schema.Validate(value); err != nil {
+		t.Fatal(err)
+	}
+	value["version"] = float64(Version + 1)
+	if schema.Validate(value) == nil {
+		t.Fatal("expected other version to be rejected")
+	}
+}
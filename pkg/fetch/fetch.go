@@ -0,0 +1,270 @@
+package fetch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+var (
+	// DefaultTimeout ...
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxSize ...
+	DefaultMaxSize int64 = 10 << 20
+	// DefaultMaxRedirects ...
+	DefaultMaxRedirects = 3
+
+	// ErrInvalidURL ...
+	ErrInvalidURL = errors.New("invalid_url")
+	// ErrBlockedHost target resolved into private, loopback, link-local or other non public address
+	ErrBlockedHost = errors.New("blocked_host")
+	// ErrTooManyRedirects ...
+	ErrTooManyRedirects = errors.New("too_many_redirects")
+	// ErrTimeout ...
+	ErrTimeout = errors.New("fetch_timeout")
+	// ErrTooLarge ...
+	ErrTooLarge = errors.New("response_too_large")
+	// ErrInvalidStatus ...
+	ErrInvalidStatus = errors.New("invalid_response_status")
+	// ErrInvalidContentType ...
+	ErrInvalidContentType = errors.New("invalid_content_type")
+
+	// blockedNetworks non public ip ranges
+	blockedNetworks = parseCIDRs(
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.0.0.0/24",
+		"192.0.2.0/24",
+		"192.88.99.0/24",
+		"192.168.0.0/16",
+		"198.18.0.0/15",
+		"198.51.100.0/24",
+		"203.0.113.0/24",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+		"::/128",
+		"::1/128",
+		"64:ff9b::/96",
+		"100::/64",
+		"2001:db8::/32",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	)
+)
+
+// parseCIDRs ...
+func parseCIDRs(cidrs ...string) (res []*net.IPNet) {
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		res = append(res, network)
+	}
+
+	return res
+}
+
+// IsPublicIP ...
+func IsPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Result ...
+type Result struct {
+	URL         string
+	ContentType string
+	Data        []byte
+}
+
+// Fetcher download remote file with time and size limit,
+// only public address is dialed so it is safe for user supplied url
+type Fetcher struct {
+	Timeout      time.Duration
+	MaxSize      int64
+	MaxRedirects int
+	// AllowedContentTypes sniffed content type which is accepted, empty means all
+	AllowedContentTypes []string
+
+	client *http.Client
+}
+
+// NewFetcher ...
+func NewFetcher(timeout time.Duration, maxSize int64, allowedContentTypes []string) *Fetcher {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+
+	f := &Fetcher{
+		Timeout:             timeout,
+		MaxSize:             maxSize,
+		MaxRedirects:        DefaultMaxRedirects,
+		AllowedContentTypes: allowedContentTypes,
+	}
+
+	// The address is checked after dns resolution, right before connecting,
+	// so every redirect and a rebinded dns answer is checked as well
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: dialControl,
+	}
+	f.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// Never go through the environment proxy, the proxy address would be checked instead of the target
+			Proxy:                 nil,
+			DialContext:           dialer.DialContext,
+			TLSHandshakeTimeout:   timeout,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          10,
+			IdleConnTimeout:       30 * time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > f.MaxRedirects {
+				return ErrTooManyRedirects
+			}
+
+			return checkURL(req.URL)
+		},
+	}
+
+	return f
+}
+
+// dialControl reject connection into non public address
+func dialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrBlockedHost
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !IsPublicIP(ip) {
+		return ErrBlockedHost
+	}
+
+	return nil
+}
+
+// checkURL only http and https with hostname is accepted
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrInvalidURL
+	}
+	if u.Hostname() == "" || u.User != nil {
+		return ErrInvalidURL
+	}
+
+	return nil
+}
+
+// Get download the url, the content type is sniffed from the first bytes before reading the rest
+func (f *Fetcher) Get(ctx context.Context, rawURL string) (res Result, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return res, ErrInvalidURL
+	}
+	err = checkURL(u)
+	if err != nil {
+		return res, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, f.Timeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return res, ErrInvalidURL
+	}
+	req = req.WithContext(ctx)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return res, unwrapError(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return res, ErrInvalidStatus
+	}
+	if resp.ContentLength > f.MaxSize {
+		return res, ErrTooLarge
+	}
+
+	// Sniff the content type before reading the whole body
+	body := io.LimitReader(resp.Body, f.MaxSize+1)
+	head := make([]byte, 512)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return res, unwrapError(err)
+	}
+	head = head[:n]
+	res.ContentType = http.DetectContentType(head)
+	if !f.allowed(res.ContentType) {
+		return res, ErrInvalidContentType
+	}
+
+	buf := bytes.NewBuffer(head)
+	_, err = io.Copy(buf, body)
+	if err != nil {
+		return res, unwrapError(err)
+	}
+	if int64(buf.Len()) > f.MaxSize {
+		return res, ErrTooLarge
+	}
+
+	res.URL = resp.Request.URL.String()
+	res.Data = buf.Bytes()
+
+	return res, nil
+}
+
+// allowed ...
+func (f *Fetcher) allowed(contentType string) bool {
+	if len(f.AllowedContentTypes) == 0 {
+		return true
+	}
+	for _, allowed := range f.AllowedContentTypes {
+		if allowed == contentType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unwrapError return the fetcher error hidden inside url and net error
+func unwrapError(err error) error {
+	for _, known := range []error{ErrBlockedHost, ErrInvalidURL, ErrTooManyRedirects} {
+		if errors.Is(err, known) {
+			return known
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrTimeout
+	}
+
+	return err
+}
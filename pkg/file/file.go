@@ -2,12 +2,12 @@ package file
 
 import (
 	"bufio"
+	"context"
 	"encoding/base64"
 	"errors"
-	"fmt"
 	"github.com/rs/xid"
-	"io"
 	"io/ioutil"
+	"kriyapeople/pkg/fetch"
 	"kriyapeople/pkg/str"
 	"net/http"
 	"os"
@@ -17,61 +17,40 @@ import (
 
 var (
 	imageContentTypeWhitelist = []string{"image/gif", "image/jpeg", "image/png"}
+
+	// downloader only fetch public address with default time and size limit
+	downloader      = fetch.NewFetcher(0, 0, nil)
+	imageDownloader = fetch.NewFetcher(0, 0, imageContentTypeWhitelist)
 )
 
+// ImageContentTypeWhitelist ...
+func ImageContentTypeWhitelist() []string {
+	return append([]string{}, imageContentTypeWhitelist...)
+}
+
 // Download ...
 func Download(url, uploadPath string) (filename string, err error) {
 	filename = uploadPath + "/" + xid.New().String() + filepath.Ext(url)
-	fmt.Println("Downloading ", url, " to ", filename)
-
-	resp, err := http.Get(url)
-	if err != nil {
-		return filename, err
-	}
-	defer resp.Body.Close()
 
-	f, err := os.Create(filename)
+	res, err := downloader.Get(context.Background(), url)
 	if err != nil {
 		return filename, err
 	}
-	defer f.Close()
+	err = ioutil.WriteFile(filename, res.Data, 0644)
 
-	_, err = io.Copy(f, resp.Body)
 	return filename, err
 }
 
-// DownloadImage ...
+// DownloadImage download only gif, jpeg and png image, content type is checked before writing the file
 func DownloadImage(url, uploadPath, name string) (filename, contentType string, err error) {
 	filename = uploadPath + "/" + name
 
-	// Get file from url
-	resp, err := http.Get(url)
-	if err != nil {
-		return filename, contentType, err
-	}
-	if resp.StatusCode != 200 {
-		return filename, contentType, errors.New(resp.Status)
-	}
-	defer resp.Body.Close()
-
-	// Create file in local directory
-	f, err := os.Create(filename)
-	if err != nil {
-		return filename, contentType, err
-	}
-	defer f.Close()
-	_, err = io.Copy(f, resp.Body)
-
-	// Get the content type
-	f, err = os.Open(filename)
+	res, err := imageDownloader.Get(context.Background(), url)
 	if err != nil {
 		return filename, contentType, err
 	}
-	contentType, err = GetFileContentType(f)
-	if err != nil {
-		return filename, contentType, err
-	}
-	defer f.Close()
+	contentType = res.ContentType
+	err = ioutil.WriteFile(filename, res.Data, 0644)
 
 	return filename, contentType, err
 }
@@ -129,8 +108,13 @@ func DecodeBase64(data, filePath, fileName string) (localPath string, contentTyp
 		return localPath, contentType, extention, err
 	}
 
+	return SaveImage(dec, filePath, fileName)
+}
+
+// SaveImage check the image content type and write it into file path with detected extention
+func SaveImage(data []byte, filePath, fileName string) (localPath string, contentType string, extention string, err error) {
 	// Get content type and file extention
-	contentType, extention, err = GetFileContentTypeAndExtention(dec)
+	contentType, extention, err = GetFileContentTypeAndExtention(data)
 	if err != nil {
 		return localPath, contentType, extention, err
 	}
@@ -145,7 +129,7 @@ func DecodeBase64(data, filePath, fileName string) (localPath string, contentTyp
 	}
 	defer f.Close()
 
-	if _, err := f.Write(dec); err != nil {
+	if _, err := f.Write(data); err != nil {
 		return localPath, contentType, extention, err
 	}
 	if err := f.Sync(); err != nil {
@@ -0,0 +1,28 @@
+package hmacsha
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+)
+
+// CanonicalRequest content signed by the partner client, one value per line:
+// method, path with query, unix timestamp, nonce and hex sha256 of the body
+func CanonicalRequest(method, path string, timestamp int64, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	return strings.Join([]string{
+		strings.ToUpper(method),
+		path,
+		strconv.FormatInt(timestamp, 10),
+		nonce,
+		hex.EncodeToString(bodyHash[:]),
+	}, "\n")
+}
+
+// Verify compare the hex signature of the data in constant time
+func (cred *Credential) Verify(data, signature string) bool {
+	return hmac.Equal([]byte(strings.ToLower(signature)), []byte(cred.Encrypt(data)))
+}
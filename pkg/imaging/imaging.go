@@ -0,0 +1,221 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"strings"
+)
+
+var (
+	// JpegQuality ...
+	JpegQuality = 90
+
+	// contentTypeFormat map image content type into go image format name
+	contentTypeFormat = map[string]string{
+		"image/jpeg": "jpeg",
+		"image/jpg":  "jpeg",
+		"image/png":  "png",
+		"image/gif":  "gif",
+	}
+	// extentionFormat map file extention into go image format name
+	extentionFormat = map[string]string{
+		"jpg":  "jpeg",
+		"jpeg": "jpeg",
+		"png":  "png",
+		"gif":  "gif",
+	}
+)
+
+// Result ...
+type Result struct {
+	Format     string
+	Original   []byte
+	Thumbnails map[int][]byte
+}
+
+// DeclaredFormat convert declared content type or file extention into image format name
+func DeclaredFormat(declared string) string {
+	declared = strings.ToLower(strings.TrimSpace(declared))
+	if i := strings.Index(declared, ";"); i >= 0 {
+		declared = strings.TrimSpace(declared[:i])
+	}
+	if format, ok := contentTypeFormat[declared]; ok {
+		return format
+	}
+
+	return extentionFormat[strings.TrimPrefix(declared, ".")]
+}
+
+// CheckFormat read the image header and make sure the real format is the declared one,
+// empty declared only check that the data is a supported image
+func CheckFormat(data []byte, declared string) (format string, err error) {
+	_, format, err = image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return format, errors.New("invalid_image")
+	}
+	if declared != "" && DeclaredFormat(declared) != format {
+		return format, errors.New("image_format_mismatch")
+	}
+
+	return format, err
+}
+
+// Process decode the image, normalize the orientation and re-encode it so every metadata
+// (exif, gps) is dropped, then generate thumbnail for each max size
+func Process(data []byte, sizes []int) (res Result, err error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return res, err
+	}
+	if format == "jpeg" {
+		img = Orient(img, Orientation(data))
+	}
+
+	res.Format = format
+	res.Original, err = Encode(img, format)
+	if err != nil {
+		return res, err
+	}
+
+	res.Thumbnails = map[int][]byte{}
+	for _, size := range sizes {
+		if size <= 0 {
+			continue
+		}
+		res.Thumbnails[size], err = Encode(Fit(img, size), format)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, err
+}
+
+// Encode ...
+func Encode(img image.Image, format string) (res []byte, err error) {
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: JpegQuality})
+	case "png":
+		err = png.Encode(&buf, img)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = errors.New("invalid_image_format")
+	}
+
+	return buf.Bytes(), err
+}
+
+// toRGBA ...
+func toRGBA(img image.Image) *image.RGBA {
+	b := img.Bounds()
+	res := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(res, res.Bounds(), img, b.Min, draw.Src)
+
+	return res
+}
+
+// Orient rotate and flip the image based on exif orientation value
+func Orient(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	src := toRGBA(img)
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			si := src.PixOffset(sx, sy)
+			di := dst.PixOffset(x, y)
+			copy(dst.Pix[di:di+4], src.Pix[si:si+4])
+		}
+	}
+
+	return dst
+}
+
+// Fit scale down the image so the longest side is maxSize, keeping the aspect ratio.
+// Smaller image is not scaled up.
+func Fit(img image.Image, maxSize int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSize && h <= maxSize {
+		return img
+	}
+
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	// Box filter, every destination pixel is the average of the source area it covers
+	src := toRGBA(img)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		sy0, sy1 := y*h/dh, (y+1)*h/dh
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			sx0, sx1 := x*w/dw, (x+1)*w/dw
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, bl, a, n int
+			for sy := sy0; sy < sy1; sy++ {
+				for sx := sx0; sx < sx1; sx++ {
+					i := src.PixOffset(sx, sy)
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					bl += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					n++
+				}
+			}
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(bl / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
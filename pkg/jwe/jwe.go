@@ -1,22 +1,61 @@
 package jwe
 
 import (
+	"crypto/rsa"
 	"encoding/json"
+	"errors"
+	"time"
+
 	"github.com/lestrrat/go-jwx/jwa"
 	"github.com/lestrrat/go-jwx/jwe"
 )
 
-// Credential ...
+var (
+	// KeyAlgorithm key encryption of the generated jwe
+	KeyAlgorithm = jwa.RSA_OAEP_256
+	// LegacyKeyAlgorithm accepted for decryption only until LegacyUntil, for jwe generated before RSA-OAEP-256
+	LegacyKeyAlgorithm = jwa.RSA1_5
+
+	// ErrNoKey credential is not created by NewCredential
+	ErrNoKey = errors.New("jwe private key is not loaded")
+)
+
+// Credential the private key is loaded once by NewCredential
 type Credential struct {
 	KeyLocation string
 	Passphrase  string
+	LegacyUntil time.Time
+
+	privateKey *rsa.PrivateKey
+}
+
+// NewCredential load the private key, zero legacyUntil reject every RSA1_5 jwe
+func NewCredential(keyLocation, passphrase string, legacyUntil time.Time) (res Credential, err error) {
+	res = Credential{KeyLocation: keyLocation, Passphrase: passphrase, LegacyUntil: legacyUntil}
+	res.privateKey, err = LoadPrivateKey(keyLocation, passphrase)
+
+	return res, err
+}
+
+// ParseLegacyUntil parse JWE_RSA1_5_UNTIL, empty value is zero time which reject every RSA1_5 jwe and any other
+// value must be RFC3339
+func ParseLegacyUntil(value string) (res time.Time, err error) {
+	if value == "" {
+		return res, err
+	}
+
+	return time.Parse(time.RFC3339, value)
+}
+
+// NewCredentialFromKey ...
+func NewCredentialFromKey(privateKey *rsa.PrivateKey, legacyUntil time.Time) Credential {
+	return Credential{LegacyUntil: legacyUntil, privateKey: privateKey}
 }
 
 // Generate ...
 func (cred *Credential) Generate(payload map[string]interface{}) (res string, err error) {
-	privkey, err := rsaConfigSetup(cred.KeyLocation, cred.Passphrase)
-	if err != nil {
-		return res, err
+	if cred.privateKey == nil {
+		return res, ErrNoKey
 	}
 
 	// Convert payload to string
@@ -26,7 +65,7 @@ func (cred *Credential) Generate(payload map[string]interface{}) (res string, er
 	}
 
 	// Generate JWE
-	jweRes, err := jwe.Encrypt([]byte(payloadString), jwa.RSA1_5, &privkey.PublicKey, jwa.A128CBC_HS256, jwa.Deflate)
+	jweRes, err := jwe.Encrypt([]byte(payloadString), KeyAlgorithm, &cred.privateKey.PublicKey, jwa.A128CBC_HS256, jwa.Deflate)
 	res = string(jweRes)
 
 	return res, err
@@ -34,12 +73,14 @@ func (cred *Credential) Generate(payload map[string]interface{}) (res string, er
 
 // Rollback ...
 func (cred *Credential) Rollback(userID string) (res map[string]interface{}, err error) {
-	privkey, err := rsaConfigSetup(cred.KeyLocation, cred.Passphrase)
-	if err != nil {
-		return res, err
+	if cred.privateKey == nil {
+		return res, ErrNoKey
 	}
 
-	decrypted, err := jwe.Decrypt([]byte(userID), jwa.RSA1_5, privkey)
+	decrypted, err := jwe.Decrypt([]byte(userID), KeyAlgorithm, cred.privateKey)
+	if err != nil && time.Now().Before(cred.LegacyUntil) {
+		decrypted, err = jwe.Decrypt([]byte(userID), LegacyKeyAlgorithm, cred.privateKey)
+	}
 	if err != nil {
 		return res, err
 	}
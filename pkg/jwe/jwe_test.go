@@ -0,0 +1,252 @@
+package jwe
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lestrrat/go-jwx/jwa"
+	jwx "github.com/lestrrat/go-jwx/jwe"
+)
+
+var benchPayload = map[string]interface{}{"id": "81b6e0e4-8be0-4656-aecf-e18a98c3a0a7", "role": "admin", "session": "bench"}
+
+// writeKey generate a key and write it as pkcs1 pem, the caller remove the directory
+func writeKey(b *testing.B) (dir, location string, key *rsa.PrivateKey) {
+	key, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		b.Fatal(err)
+	}
+	dir, err = ioutil.TempDir("", "jwe")
+	if err != nil {
+		b.Fatal(err)
+	}
+	location = writePem(b, dir, "key.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+
+	return dir, location, key
+}
+
+// writePem write the pem block into the directory and return its location
+func writePem(tb testing.TB, dir, name, types string, content []byte) string {
+	location := filepath.Join(dir, name)
+	err := ioutil.WriteFile(location, pem.EncodeToMemory(&pem.Block{Type: types, Bytes: content}), 0600)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return location
+}
+
+func TestNewCredential(t *testing.T) {
+	key, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shortKey, err := GenRSA(1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "jwe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	notPem := filepath.Join(dir, "not-pem.pem")
+	if err = ioutil.WriteFile(notPem, []byte("not a pem"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		location string
+		wantErr  bool
+	}{
+		"pkcs1 key":        {location: writePem(t, dir, "pkcs1.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))},
+		"pkcs8 key":        {location: writePem(t, dir, "pkcs8.pem", "PRIVATE KEY", pkcs8)},
+		"empty location":   {location: "", wantErr: true},
+		"missing file":     {location: filepath.Join(dir, "missing.pem"), wantErr: true},
+		"not pem":          {location: notPem, wantErr: true},
+		"wrong pem type":   {location: writePem(t, dir, "cert.pem", "CERTIFICATE", x509.MarshalPKCS1PrivateKey(key)), wantErr: true},
+		"unparsable key":   {location: writePem(t, dir, "garbage.pem", "RSA PRIVATE KEY", []byte("garbage")), wantErr: true},
+		"shorter than min": {location: writePem(t, dir, "short.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(shortKey)), wantErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cred, err := NewCredential(c.location, "", time.Time{})
+			if (err != nil) != c.wantErr {
+				t.Fatalf("expected error %v, got %v", c.wantErr, err)
+			}
+			if c.wantErr {
+				return
+			}
+			token, err := cred.Generate(benchPayload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err = cred.Rollback(token); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	var empty Credential
+	if _, err = empty.Generate(benchPayload); err != ErrNoKey {
+		t.Fatalf("expected %v, got %v", ErrNoKey, err)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	key, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generator := NewCredentialFromKey(key, time.Time{})
+	token, err := generator.Generate(benchPayload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := jwx.Encrypt([]byte(`{"id":"81b6e0e4-8be0-4656-aecf-e18a98c3a0a7"}`), LegacyKeyAlgorithm, &key.PublicKey, jwa.A128CBC_HS256, jwa.Deflate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		key         *rsa.PrivateKey
+		legacyUntil time.Time
+		token       string
+		wantErr     bool
+	}{
+		"oaep":                        {key: key, token: token},
+		"oaep with legacy window":     {key: key, legacyUntil: time.Now().Add(time.Hour), token: token},
+		"rsa1_5 before legacy until":  {key: key, legacyUntil: time.Now().Add(time.Hour), token: string(legacy)},
+		"rsa1_5 after legacy until":   {key: key, legacyUntil: time.Now().Add(-time.Second), token: string(legacy), wantErr: true},
+		"rsa1_5 without legacy until": {key: key, token: string(legacy), wantErr: true},
+		"other key":                   {key: otherKey, token: token, wantErr: true},
+		"tampered token":              {key: key, token: token[:len(token)-4] + "AAAA", wantErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cred := NewCredentialFromKey(c.key, c.legacyUntil)
+			res, err := cred.Rollback(c.token)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("expected error %v, got %v", c.wantErr, err)
+			}
+			if err == nil && res["id"] != benchPayload["id"] {
+				t.Fatalf("unexpected payload %v", res)
+			}
+		})
+	}
+}
+
+func TestParseLegacyUntil(t *testing.T) {
+	cases := map[string]struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		"empty":       {value: ""},
+		"rfc3339":     {value: "2026-11-01T00:00:00Z", want: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		"with offset": {value: "2026-11-01T07:00:00+07:00", want: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		"date only":   {value: "2026-11-01", wantErr: true},
+		"duration":    {value: "24h", wantErr: true},
+		"unix time":   {value: "1793491200", wantErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			res, err := ParseLegacyUntil(c.value)
+			if (err != nil) != c.wantErr {
+				t.Fatalf("expected error %v, got %v", c.wantErr, err)
+			}
+			if !res.Equal(c.want) {
+				t.Fatalf("expected %v, got %v", c.want, res)
+			}
+		})
+	}
+}
+
+func BenchmarkGenerate(b *testing.B) {
+	key, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cred := NewCredentialFromKey(key, time.Time{})
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = cred.Generate(benchPayload); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRollback(b *testing.B) {
+	key, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cred := NewCredentialFromKey(key, time.Time{})
+	token, err := cred.Generate(benchPayload)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = cred.Rollback(token); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRollbackRSA1_5(b *testing.B) {
+	key, err := GenRSA(MinRSAKeyBits)
+	if err != nil {
+		b.Fatal(err)
+	}
+	cred := NewCredentialFromKey(key, time.Now().Add(time.Hour))
+	legacy, err := jwx.Encrypt([]byte(`{"id":"bench"}`), LegacyKeyAlgorithm, &key.PublicKey, jwa.A128CBC_HS256, jwa.Deflate)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err = cred.Rollback(string(legacy)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkRollbackLoadKeyPerCall the cost before the key is loaded once at boot
+func BenchmarkRollbackLoadKeyPerCall(b *testing.B) {
+	dir, location, key := writeKey(b)
+	defer os.RemoveAll(dir)
+	generator := NewCredentialFromKey(key, time.Time{})
+	token, err := generator.Generate(benchPayload)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cred, err := NewCredential(location, "", time.Time{})
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = cred.Rollback(token); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
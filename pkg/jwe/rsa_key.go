@@ -5,51 +5,59 @@ import (
 	"crypto/rsa"
 	"crypto/x509"
 	"encoding/pem"
-	"fmt"
+	"errors"
 	"io/ioutil"
 )
 
-func rsaConfigSetup(rsaPrivateKeyLocation, rsaPrivateKeyPassword string) (*rsa.PrivateKey, error) {
+var (
+	// MinRSAKeyBits ...
+	MinRSAKeyBits = 2048
+)
+
+// LoadPrivateKey read the pem rsa private key, pkcs1 or pkcs8 and optionally encrypted with the passphrase.
+// Every problem is returned as error, the caller must not continue with another key
+func LoadPrivateKey(rsaPrivateKeyLocation, rsaPrivateKeyPassword string) (*rsa.PrivateKey, error) {
 	if rsaPrivateKeyLocation == "" {
-		fmt.Println("No RSA Key given, generating temp one")
-		return GenRSA(4096)
+		return nil, errors.New("rsa private key location is empty")
 	}
 
 	priv, err := ioutil.ReadFile(rsaPrivateKeyLocation)
 	if err != nil {
-		fmt.Println("No RSA private key found, generating temp one")
-		return GenRSA(4096)
+		return nil, err
 	}
 
 	privPem, _ := pem.Decode(priv)
-	var privPemBytes []byte
-	if privPem.Type != "RSA PRIVATE KEY" {
-		fmt.Println("RSA private key is of the wrong type")
+	if privPem == nil {
+		return nil, errors.New("rsa private key is not pem encoded")
+	}
+	if privPem.Type != "RSA PRIVATE KEY" && privPem.Type != "PRIVATE KEY" {
+		return nil, errors.New("rsa private key is of the wrong type: " + privPem.Type)
 	}
 
+	privPemBytes := privPem.Bytes
 	if rsaPrivateKeyPassword != "" {
 		privPemBytes, err = x509.DecryptPEMBlock(privPem, []byte(rsaPrivateKeyPassword))
-	} else {
-		privPemBytes = privPem.Bytes
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	var parsedKey interface{}
 	if parsedKey, err = x509.ParsePKCS1PrivateKey(privPemBytes); err != nil {
-		if parsedKey, err = x509.ParsePKCS8PrivateKey(privPemBytes); err != nil { // note this returns type `interface{}`
-			fmt.Println("Unable to parse RSA private key, generating a temp one")
-			return GenRSA(4096)
+		if parsedKey, err = x509.ParsePKCS8PrivateKey(privPemBytes); err != nil {
+			return nil, errors.New("unable to parse rsa private key: " + err.Error())
 		}
 	}
 
-	var privateKey *rsa.PrivateKey
-	var ok bool
-	privateKey, ok = parsedKey.(*rsa.PrivateKey)
+	privateKey, ok := parsedKey.(*rsa.PrivateKey)
 	if !ok {
-		fmt.Println("Unable to parse RSA private key, generating a temp one (2)")
-		return GenRSA(4096)
+		return nil, errors.New("private key is not rsa")
+	}
+	if privateKey.N.BitLen() < MinRSAKeyBits {
+		return nil, errors.New("rsa private key is shorter than the minimum bits")
 	}
 
-	return privateKey, nil
+	return privateKey, privateKey.Validate()
 }
 
 // GenRSA returns a new RSA key of bits length
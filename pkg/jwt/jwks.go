@@ -0,0 +1,70 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"sort"
+	"time"
+)
+
+// JWK public key in RFC 7517 format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	Y         string `json:"y,omitempty"`
+}
+
+// JWKS ...
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// encodeBigInt base64 url of the big endian bytes, left padded to size when size is not zero
+func encodeBigInt(value *big.Int, size int) string {
+	b := value.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// NewJWK ...
+func NewJWK(key *Key) (res JWK) {
+	res = JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Method.Alg()}
+	switch publicKey := key.Public().(type) {
+	case *rsa.PublicKey:
+		res.KeyType = "RSA"
+		res.N = encodeBigInt(publicKey.N, 0)
+		res.E = encodeBigInt(big.NewInt(int64(publicKey.E)), 0)
+	case *ecdsa.PublicKey:
+		size := (publicKey.Curve.Params().BitSize + 7) / 8
+		res.KeyType = "EC"
+		res.Curve = publicKey.Curve.Params().Name
+		res.X = encodeBigInt(publicKey.X, size)
+		res.Y = encodeBigInt(publicKey.Y, size)
+	}
+
+	return res
+}
+
+// JWKS public keys which can verify token at now, ordered by key id
+func (cred *Credential) JWKS(now time.Time) (res JWKS) {
+	res.Keys = []JWK{}
+	for _, key := range cred.VerifyingKeys(now) {
+		res.Keys = append(res.Keys, NewJWK(key))
+	}
+	sort.Slice(res.Keys, func(i, j int) bool {
+		return res.Keys[i].KeyID < res.Keys[j].KeyID
+	})
+
+	return res
+}
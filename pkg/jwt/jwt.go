@@ -1,57 +1,223 @@
 package jwt
 
 import (
-	jwt "github.com/dgrijalva/jwt-go"
+	"errors"
+	"strings"
 	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/rs/xid"
+)
+
+var (
+	// TypeAccess ...
+	TypeAccess = "access"
+	// TypeRefresh ...
+	TypeRefresh = "refresh"
+
+	// ErrUnknownKey token kid is not found or the key is not valid anymore
+	ErrUnknownKey = errors.New("unknown signing key")
+	// ErrInvalidType ...
+	ErrInvalidType = errors.New("invalid token type")
+	// ErrMissingClaim ...
+	ErrMissingClaim = errors.New("missing required claim")
+	// ErrInvalidIssuer ...
+	ErrInvalidIssuer = errors.New("invalid token issuer")
+	// ErrInvalidAudience ...
+	ErrInvalidAudience = errors.New("invalid token audience")
+	// ErrExpired ...
+	ErrExpired = errors.New("token is expired")
+	// ErrNotValidYet token nbf or iat is in the future
+	ErrNotValidYet = errors.New("token is not valid yet")
 )
 
-// Credential ...
+// Credential every key in Keys can verify token, SigningKeyID is the key used to sign. Retired key is kept
+// valid until the last token signed with it is expired. ClockSkew is the tolerance of exp, nbf and iat
 type Credential struct {
-	Secret           string
+	Keys             map[string]*Key
+	SigningKeyID     string
+	RetiredKeys      map[string]time.Time
+	Issuer           string
+	Audience         string
+	ClockSkew        time.Duration
 	ExpSecret        int
-	RefreshSecret    string
 	RefreshExpSecret int
 }
 
-// jwtClaims ...
-type jwtClaims struct {
-	jwt.StandardClaims
+// Claims registered claims of RFC 7519, Payload is the encrypted jwe payload
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+	NotBefore int64  `json:"nbf"`
+	IssuedAt  int64  `json:"iat"`
+	ID        string `json:"jti"`
+	Type      string `json:"typ"`
+	Payload   string `json:"payload"`
 }
 
-// GetToken ...
-func (cred *Credential) GetToken(id string) (string, string, error) {
-	expirationTime := time.Now().Add(time.Duration(cred.ExpSecret) * time.Hour).Unix()
+// Valid the claims are validated by Credential.validate with the clock skew
+func (claims *Claims) Valid() error {
+	return nil
+}
+
+// NewCredential load the keys of the directory, retiredKeys is comma separated [key id]@[RFC3339 retired time]
+func NewCredential(dir, signingKeyID, retiredKeys, issuer, audience string, clockSkew time.Duration, expSecret, refreshExpSecret int) (res Credential, err error) {
+	res = Credential{
+		SigningKeyID:     signingKeyID,
+		RetiredKeys:      map[string]time.Time{},
+		Issuer:           issuer,
+		Audience:         audience,
+		ClockSkew:        clockSkew,
+		ExpSecret:        expSecret,
+		RefreshExpSecret: refreshExpSecret,
+	}
+	if issuer == "" || audience == "" {
+		return res, errors.New("token issuer and audience are required")
+	}
+	if clockSkew < 0 {
+		return res, errors.New("token clock skew must not be negative")
+	}
+	res.Keys, err = LoadKeys(dir)
+	if err != nil {
+		return res, err
+	}
+	if _, ok := res.Keys[signingKeyID]; !ok {
+		return res, errors.New("signing key " + signingKeyID + " is not found in " + dir)
+	}
+
+	for _, retired := range strings.Split(retiredKeys, ",") {
+		retired = strings.TrimSpace(retired)
+		if retired == "" {
+			continue
+		}
+		parts := strings.SplitN(retired, "@", 2)
+		if len(parts) != 2 {
+			return res, errors.New("invalid retired key: " + retired)
+		}
+		res.RetiredKeys[parts[0]], err = time.Parse(time.RFC3339, parts[1])
+		if err != nil {
+			return res, errors.New("invalid retired time of key " + parts[0])
+		}
+		if parts[0] == signingKeyID {
+			return res, errors.New("signing key " + signingKeyID + " is retired")
+		}
+	}
+
+	return res, nil
+}
+
+// lifetime the longest token lifetime
+func (cred *Credential) lifetime() time.Duration {
+	exp := cred.ExpSecret
+	if cred.RefreshExpSecret > exp {
+		exp = cred.RefreshExpSecret
+	}
+
+	return time.Duration(exp) * time.Hour
+}
+
+// VerifyingKeys the signing key, the key which is not used yet and the retired key which still has valid token
+func (cred *Credential) VerifyingKeys(now time.Time) (res []*Key) {
+	for id, key := range cred.Keys {
+		retiredAt, ok := cred.RetiredKeys[id]
+		if ok && !now.Before(retiredAt.Add(cred.lifetime())) {
+			continue
+		}
+		res = append(res, key)
+	}
+
+	return res
+}
+
+// sign ...
+func (cred *Credential) sign(subject, payload, types string, exp int) (string, string, error) {
+	key, ok := cred.Keys[cred.SigningKeyID]
+	if !ok {
+		return "", "", ErrUnknownKey
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(time.Duration(exp) * time.Hour).Unix()
 
 	unixTimeUTC := time.Unix(expirationTime, 0)
 	unitTimeInRFC3339 := unixTimeUTC.UTC().Format(time.RFC3339)
 
-	claims := &jwtClaims{
-		jwt.StandardClaims{
-			Id:        id,
-			ExpiresAt: expirationTime,
-		},
+	claims := &Claims{
+		Issuer:    cred.Issuer,
+		Subject:   subject,
+		Audience:  cred.Audience,
+		ExpiresAt: expirationTime,
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        xid.New().String(),
+		Type:      types,
+		Payload:   payload,
 	}
-	rawToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := rawToken.SignedString([]byte(cred.Secret))
+	rawToken := jwt.NewWithClaims(key.Method, claims)
+	rawToken.Header["kid"] = key.ID
+	token, err := rawToken.SignedString(key.PrivateKey)
 
 	return token, unitTimeInRFC3339, err
 }
 
+// GetToken ...
+func (cred *Credential) GetToken(subject, payload string) (string, string, error) {
+	return cred.sign(subject, payload, TypeAccess, cred.ExpSecret)
+}
+
 // GetRefreshToken ...
-func (cred *Credential) GetRefreshToken(id string) (string, string, error) {
-	expirationTime := time.Now().Add(time.Duration(cred.RefreshExpSecret) * time.Hour).Unix()
+func (cred *Credential) GetRefreshToken(subject, payload string) (string, string, error) {
+	return cred.sign(subject, payload, TypeRefresh, cred.RefreshExpSecret)
+}
 
-	unixTimeUTC := time.Unix(expirationTime, 0)
-	unitTimeInRFC3339 := unixTimeUTC.UTC().Format(time.RFC3339) // converts utc time to RFC3339 format
+// validate ...
+func (cred *Credential) validate(claims *Claims, types string, now time.Time) error {
+	if claims.Subject == "" || claims.ID == "" || claims.Payload == "" ||
+		claims.ExpiresAt == 0 || claims.NotBefore == 0 || claims.IssuedAt == 0 {
+		return ErrMissingClaim
+	}
+	if claims.Type != types {
+		return ErrInvalidType
+	}
+	if claims.Issuer != cred.Issuer {
+		return ErrInvalidIssuer
+	}
+	if claims.Audience != cred.Audience {
+		return ErrInvalidAudience
+	}
+	if !now.Before(time.Unix(claims.ExpiresAt, 0).Add(cred.ClockSkew)) {
+		return ErrExpired
+	}
+	if now.Add(cred.ClockSkew).Before(time.Unix(claims.NotBefore, 0)) ||
+		now.Add(cred.ClockSkew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return ErrNotValidYet
+	}
+
+	return nil
+}
 
-	claims := &jwtClaims{
-		jwt.StandardClaims{
-			Id:        id,
-			ExpiresAt: expirationTime,
-		},
+// Parse verify the token by its kid and validate the claims, the algorithm must match the key
+func (cred *Credential) Parse(token, types string) (res Claims, err error) {
+	now := time.Now()
+	_, err = jwt.ParseWithClaims(token, &res, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		for _, key := range cred.VerifyingKeys(now) {
+			if key.ID != kid {
+				continue
+			}
+			if t.Method.Alg() != key.Method.Alg() {
+				return nil, errors.New("unexpected signing method: " + t.Method.Alg())
+			}
+			return key.Public(), nil
+		}
+
+		return nil, ErrUnknownKey
+	})
+	if err != nil {
+		return res, err
 	}
-	rawToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token, err := rawToken.SignedString([]byte(cred.RefreshSecret))
 
-	return token, unitTimeInRFC3339, err
+	return res, cred.validate(&res, types, now)
 }
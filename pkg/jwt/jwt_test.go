@@ -0,0 +1,235 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const (
+	issuer    = "kriyapeople"
+	audience  = "kriyapeople-admin"
+	clockSkew = 30 * time.Second
+
+	ecKeyID  = "ec"
+	rsaKeyID = "rsa"
+)
+
+// newCredential write a P-256 and a 2048 bits rsa key into a temporary directory, the ecdsa key sign
+func newCredential(t *testing.T, retiredKeys string) Credential {
+	dir, err := ioutil.TempDir("", "jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecBytes, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePem(t, filepath.Join(dir, ecKeyID+".pem"), "EC PRIVATE KEY", ecBytes)
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRSAKeyBits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePem(t, filepath.Join(dir, rsaKeyID+".pem"), "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaKey))
+
+	res, err := NewCredential(dir, ecKeyID, retiredKeys, issuer, audience, clockSkew, 1, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func writePem(t *testing.T, file, types string, content []byte) {
+	err := ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: types, Bytes: content}), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// validClaims access token claims issued at now
+func validClaims(now time.Time) Claims {
+	return Claims{
+		Issuer:    issuer,
+		Subject:   "81b6e0e4-8be0-4656-aecf-e18a98c3a0a7",
+		Audience:  audience,
+		ExpiresAt: now.Add(time.Hour).Unix(),
+		NotBefore: now.Unix(),
+		IssuedAt:  now.Unix(),
+		ID:        "bq7b3hh6j1a5p0dbg2vg",
+		Type:      TypeAccess,
+		Payload:   "payload",
+	}
+}
+
+// signClaims sign the claims with any method and kid, to build the token the credential does not issue
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims Claims) string {
+	rawToken := jwt.NewWithClaims(method, &claims)
+	if kid != "" {
+		rawToken.Header["kid"] = kid
+	}
+	token, err := rawToken.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// cause the key lookup error is wrapped in jwt.ValidationError
+func cause(err error) error {
+	if validationErr, ok := err.(*jwt.ValidationError); ok && validationErr.Inner != nil {
+		return validationErr.Inner
+	}
+
+	return err
+}
+
+func TestParse(t *testing.T) {
+	cred := newCredential(t, "")
+	now := time.Now()
+	ecKey := cred.Keys[ecKeyID].PrivateKey
+	rsaKey := cred.Keys[rsaKeyID].PrivateKey
+
+	accessToken, _, err := cred.GetToken("81b6e0e4-8be0-4656-aecf-e18a98c3a0a7", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	refreshToken, _, err := cred.GetRefreshToken("81b6e0e4-8be0-4656-aecf-e18a98c3a0a7", "payload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	withClaims := func(fn func(claims *Claims)) string {
+		claims := validClaims(now)
+		fn(&claims)
+		return signClaims(t, jwt.SigningMethodES256, ecKey, ecKeyID, claims)
+	}
+
+	cases := map[string]struct {
+		token   string
+		types   string
+		wantErr error
+		anyErr  bool
+	}{
+		"access token":             {token: accessToken, types: TypeAccess},
+		"refresh token":            {token: refreshToken, types: TypeRefresh},
+		"refresh token as access":  {token: refreshToken, types: TypeAccess, wantErr: ErrInvalidType},
+		"access token as refresh":  {token: accessToken, types: TypeRefresh, wantErr: ErrInvalidType},
+		"missing typ":              {token: withClaims(func(c *Claims) { c.Type = "" }), types: TypeAccess, wantErr: ErrInvalidType},
+		"unknown typ":              {token: withClaims(func(c *Claims) { c.Type = "id" }), types: TypeAccess, wantErr: ErrInvalidType},
+		"other issuer":             {token: withClaims(func(c *Claims) { c.Issuer = "other" }), types: TypeAccess, wantErr: ErrInvalidIssuer},
+		"other audience":           {token: withClaims(func(c *Claims) { c.Audience = "other" }), types: TypeAccess, wantErr: ErrInvalidAudience},
+		"missing sub":              {token: withClaims(func(c *Claims) { c.Subject = "" }), types: TypeAccess, wantErr: ErrMissingClaim},
+		"missing jti":              {token: withClaims(func(c *Claims) { c.ID = "" }), types: TypeAccess, wantErr: ErrMissingClaim},
+		"missing exp":              {token: withClaims(func(c *Claims) { c.ExpiresAt = 0 }), types: TypeAccess, wantErr: ErrMissingClaim},
+		"expired":                  {token: withClaims(func(c *Claims) { c.ExpiresAt = now.Add(-time.Minute).Unix() }), types: TypeAccess, wantErr: ErrExpired},
+		"not valid yet":            {token: withClaims(func(c *Claims) { c.NotBefore = now.Add(time.Minute).Unix() }), types: TypeAccess, wantErr: ErrNotValidYet},
+		"rsa key":                  {token: signClaims(t, jwt.SigningMethodRS256, rsaKey, rsaKeyID, validClaims(now)), types: TypeAccess},
+		"unknown kid":              {token: signClaims(t, jwt.SigningMethodES256, ecKey, "other", validClaims(now)), types: TypeAccess, wantErr: ErrUnknownKey},
+		"missing kid":              {token: signClaims(t, jwt.SigningMethodES256, ecKey, "", validClaims(now)), types: TypeAccess, wantErr: ErrUnknownKey},
+		"rs256 with the ecdsa kid": {token: signClaims(t, jwt.SigningMethodRS256, rsaKey, ecKeyID, validClaims(now)), types: TypeAccess, anyErr: true},
+		"es256 with the rsa kid":   {token: signClaims(t, jwt.SigningMethodES256, ecKey, rsaKeyID, validClaims(now)), types: TypeAccess, anyErr: true},
+		"hs256 with the ecdsa kid": {token: signClaims(t, jwt.SigningMethodHS256, []byte("secret"), ecKeyID, validClaims(now)), types: TypeAccess, anyErr: true},
+		"none alg with the ecdsa kid": {
+			token: signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, ecKeyID, validClaims(now)),
+			types: TypeAccess, anyErr: true,
+		},
+		"tampered signature": {token: accessToken[:len(accessToken)-4] + "AAAA", types: TypeAccess, anyErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims, err := cred.Parse(c.token, c.types)
+			switch {
+			case c.anyErr:
+				if err == nil {
+					t.Fatal("expected error")
+				}
+			case cause(err) != c.wantErr:
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			case err == nil && claims.Subject != "81b6e0e4-8be0-4656-aecf-e18a98c3a0a7":
+				t.Fatalf("unexpected subject %s", claims.Subject)
+			}
+		})
+	}
+}
+
+func TestValidateClockSkew(t *testing.T) {
+	cred := Credential{Issuer: issuer, Audience: audience, ClockSkew: clockSkew}
+	now := time.Unix(1790000000, 0)
+
+	cases := map[string]struct {
+		fn      func(claims *Claims)
+		wantErr error
+	}{
+		"exp within skew":     {fn: func(c *Claims) { c.ExpiresAt = now.Add(-clockSkew + time.Second).Unix() }},
+		"exp at skew":         {fn: func(c *Claims) { c.ExpiresAt = now.Add(-clockSkew).Unix() }, wantErr: ErrExpired},
+		"exp past skew":       {fn: func(c *Claims) { c.ExpiresAt = now.Add(-clockSkew - time.Second).Unix() }, wantErr: ErrExpired},
+		"nbf at skew":         {fn: func(c *Claims) { c.NotBefore = now.Add(clockSkew).Unix() }},
+		"nbf past skew":       {fn: func(c *Claims) { c.NotBefore = now.Add(clockSkew + time.Second).Unix() }, wantErr: ErrNotValidYet},
+		"iat at skew":         {fn: func(c *Claims) { c.IssuedAt = now.Add(clockSkew).Unix() }},
+		"iat past skew":       {fn: func(c *Claims) { c.IssuedAt = now.Add(clockSkew + time.Second).Unix() }, wantErr: ErrNotValidYet},
+		"missing nbf":         {fn: func(c *Claims) { c.NotBefore = 0 }, wantErr: ErrMissingClaim},
+		"missing iat":         {fn: func(c *Claims) { c.IssuedAt = 0 }, wantErr: ErrMissingClaim},
+		"missing payload":     {fn: func(c *Claims) { c.Payload = "" }, wantErr: ErrMissingClaim},
+		"valid at issue time": {fn: func(c *Claims) {}},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			claims := validClaims(now)
+			c.fn(&claims)
+			if err := cred.validate(&claims, TypeAccess, now); err != c.wantErr {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRetiredKey(t *testing.T) {
+	now := time.Now()
+	// the longest lifetime is the 24 hours refresh token
+	lifetime := 24 * time.Hour
+
+	cases := map[string]struct {
+		retiredAt time.Time
+		wantErr   error
+	}{
+		"retired within lifetime": {retiredAt: now.Add(-lifetime + time.Minute)},
+		"retired past lifetime":   {retiredAt: now.Add(-lifetime - time.Minute), wantErr: ErrUnknownKey},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			cred := newCredential(t, rsaKeyID+"@"+c.retiredAt.UTC().Format(time.RFC3339))
+			token := signClaims(t, jwt.SigningMethodRS256, cred.Keys[rsaKeyID].PrivateKey, rsaKeyID, validClaims(now))
+
+			_, err := cred.Parse(token, TypeAccess)
+			if cause(err) != c.wantErr {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+
+			published := false
+			for _, key := range cred.JWKS(now).Keys {
+				published = published || key.KeyID == rsaKeyID
+			}
+			if published != (c.wantErr == nil) {
+				t.Fatalf("unexpected jwks publication %v", published)
+			}
+		})
+	}
+}
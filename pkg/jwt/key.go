@@ -0,0 +1,102 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// MinRSAKeyBits ...
+	MinRSAKeyBits = 2048
+
+	keyIDRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]{1,64}$`)
+)
+
+// Key signing key, the algorithm follow the key type: RS256 for rsa and ES256 for ecdsa P-256
+type Key struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.Signer
+}
+
+// Public ...
+func (key *Key) Public() crypto.PublicKey {
+	return key.PrivateKey.Public()
+}
+
+// LoadKeys load every [key id].pem private key in the directory
+func LoadKeys(dir string) (res map[string]*Key, err error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return res, err
+	}
+
+	res = map[string]*Key{}
+	for _, file := range files {
+		key, err := LoadKey(file)
+		if err != nil {
+			return res, errors.New(file + ": " + err.Error())
+		}
+		res[key.ID] = key
+	}
+
+	return res, err
+}
+
+// LoadKey the key id is the file name without extension
+func LoadKey(file string) (res *Key, err error) {
+	id := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	if !keyIDRegex.MatchString(id) {
+		return res, errors.New("invalid key id")
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return res, err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return res, errors.New("key is not pem encoded")
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		parsed, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		err = errors.New("unsupported key type: " + block.Type)
+	}
+	if err != nil {
+		return res, err
+	}
+
+	switch privateKey := parsed.(type) {
+	case *rsa.PrivateKey:
+		if privateKey.N.BitLen() < MinRSAKeyBits {
+			return res, errors.New("rsa key is shorter than the minimum bits")
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, PrivateKey: privateKey}, privateKey.Validate()
+	case *ecdsa.PrivateKey:
+		if privateKey.Curve != elliptic.P256() {
+			return res, errors.New("only P-256 ecdsa key is supported")
+		}
+		return &Key{ID: id, Method: jwt.SigningMethodES256, PrivateKey: privateKey}, nil
+	}
+
+	return res, errors.New("unsupported private key")
+}
@@ -0,0 +1,62 @@
+package mail
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// fileDriver ...
+type fileDriver struct {
+	path string
+}
+
+// NewFileDriver every mail is written as .eml file into the path, or printed to stdout when the path is empty
+func NewFileDriver(path string) (IDriver, error) {
+	if path != "" {
+		err := os.MkdirAll(path, os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fileDriver{path: path}, nil
+}
+
+// Name ...
+func (d *fileDriver) Name() string {
+	return DriverFile
+}
+
+// Send ...
+func (d *fileDriver) Send(msg Message) (res Result, err error) {
+	messageID := newMessageID(msg.FromEmail)
+	body, err := buildMIME(msg, messageID, time.Now())
+	if err != nil {
+		return res, err
+	}
+
+	var writer io.Writer = os.Stdout
+	if d.path != "" {
+		name := strings.Trim(messageID, "<>") + ".eml"
+		f, err := os.Create(filepath.Join(d.path, name))
+		if err != nil {
+			return res, err
+		}
+		defer f.Close()
+		writer = f
+	}
+	_, err = writer.Write(body)
+	if err != nil {
+		return res, err
+	}
+
+	res = Result{
+		MessageID: messageID,
+		Status:    StatusSent,
+	}
+
+	return res, err
+}
@@ -0,0 +1,92 @@
+package mail
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// DriverMandrill ...
+	DriverMandrill = "mandrill"
+	// DriverSMTP ...
+	DriverSMTP = "smtp"
+	// DriverFile write the mail into a folder, or stdout when the path is empty, used for development
+	DriverFile = "file"
+
+	// StatusSent ...
+	StatusSent = "sent"
+	// StatusQueued accepted by the provider but not delivered yet
+	StatusQueued = "queued"
+	// StatusFailed ...
+	StatusFailed = "failed"
+)
+
+var (
+	// DefaultTimeout ...
+	DefaultTimeout = 30 * time.Second
+
+	// ErrRejected provider refused the recipient
+	ErrRejected = errors.New("mail_rejected")
+	// ErrInvalidDriver ...
+	ErrInvalidDriver = errors.New("invalid_mail_driver")
+)
+
+// Message ...
+type Message struct {
+	FromEmail string
+	FromName  string
+	ToEmail   string
+	ToName    string
+	Subject   string
+	HTML      string
+	Text      string
+	Tags      []string
+}
+
+// Result ...
+type Result struct {
+	MessageID string
+	Status    string
+}
+
+// IDriver ...
+type IDriver interface {
+	Name() string
+	Send(msg Message) (Result, error)
+}
+
+// Connection ...
+type Connection struct {
+	Driver  string
+	Timeout time.Duration
+
+	// Mandrill driver
+	MandrillKey string
+
+	// SMTP driver
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUser     string
+	SMTPPassword string
+
+	// File driver
+	Path string
+}
+
+// Connect return mail driver based on the driver name
+func (m Connection) Connect() (IDriver, error) {
+	if m.Timeout <= 0 {
+		m.Timeout = DefaultTimeout
+	}
+
+	switch m.Driver {
+	case DriverFile, "":
+		return NewFileDriver(m.Path)
+	case DriverMandrill:
+		return NewMandrillDriver(m.MandrillKey, m.Timeout), nil
+	case DriverSMTP:
+		return NewSMTPDriver(m.SMTPHost, m.SMTPPort, m.SMTPUser, m.SMTPPassword, m.Timeout), nil
+	}
+
+	return nil, ErrInvalidDriver
+}
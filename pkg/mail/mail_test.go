@@ -0,0 +1,219 @@
+package mail
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testMessage = Message{
+	FromEmail: "no-reply@kriyapeople.com",
+	FromName:  "Kriya People",
+	ToEmail:   "john.doe@example.com",
+	ToName:    "John Doe",
+	Subject:   "Aktivasi akun Anda",
+	HTML:      "<p>Klik <a href=\"http://127.0.0.1/activation?key=a=b\">tautan</a></p>",
+	Text:      "Klik tautan: http://127.0.0.1/activation?key=a=b",
+}
+
+func TestBuildMIME(t *testing.T) {
+	date := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		msg   Message
+		parts map[string]string
+	}{
+		"text and html": {
+			msg:   testMessage,
+			parts: map[string]string{"text/plain": testMessage.Text, "text/html": testMessage.HTML},
+		},
+		"text only": {
+			msg:   Message{FromEmail: testMessage.FromEmail, ToEmail: testMessage.ToEmail, Subject: "Halo", Text: "Halo"},
+			parts: map[string]string{"text/plain": "Halo"},
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			body, err := buildMIME(c.msg, "<id@kriyapeople.com>", date)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed, err := mail.ReadMessage(bytes.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+			if err != nil || subject != c.msg.Subject {
+				t.Fatalf("expected subject %q, got %q (%v)", c.msg.Subject, subject, err)
+			}
+			to, err := parsed.Header.AddressList("To")
+			if err != nil || len(to) != 1 || to[0].Address != c.msg.ToEmail || to[0].Name != c.msg.ToName {
+				t.Fatalf("unexpected to %v (%v)", to, err)
+			}
+			if parsed.Header.Get("Message-ID") != "<id@kriyapeople.com>" {
+				t.Fatalf("unexpected message id %q", parsed.Header.Get("Message-ID"))
+			}
+			if parsed.Header.Get("Date") != date.Format(time.RFC1123Z) {
+				t.Fatalf("unexpected date %q", parsed.Header.Get("Date"))
+			}
+
+			mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/alternative" {
+				t.Fatalf("unexpected content type %q (%v)", mediaType, err)
+			}
+			reader := multipart.NewReader(parsed.Body, params["boundary"])
+			found := map[string]string{}
+			for {
+				part, err := reader.NextRawPart()
+				if err != nil {
+					break
+				}
+				if part.Header.Get("Content-Transfer-Encoding") != "quoted-printable" {
+					t.Fatalf("unexpected transfer encoding %q", part.Header.Get("Content-Transfer-Encoding"))
+				}
+				content, err := ioutil.ReadAll(quotedprintable.NewReader(part))
+				if err != nil {
+					t.Fatal(err)
+				}
+				partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+				found[partType] = string(content)
+			}
+			if len(found) != len(c.parts) {
+				t.Fatalf("expected %d parts, got %d", len(c.parts), len(found))
+			}
+			for partType, content := range c.parts {
+				if found[partType] != content {
+					t.Fatalf("expected %s part %q, got %q", partType, content, found[partType])
+				}
+			}
+		})
+	}
+}
+
+// smtpServer in process smtp server which serve one session, the reply of MAIL, RCPT and the end of DATA
+// is taken from replies and 250 is used for the other command
+type smtpServer struct {
+	listener net.Listener
+	replies  map[string]string
+	data     chan string
+}
+
+func newSMTPServer(t *testing.T, replies map[string]string) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{listener: listener, replies: replies, data: make(chan string, 1)}
+	go s.serve()
+
+	return s
+}
+
+func (s *smtpServer) reply(command string) string {
+	if res, ok := s.replies[command]; ok {
+		return res
+	}
+
+	return "250 OK"
+}
+
+func (s *smtpServer) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := textproto.NewReader(bufio.NewReader(conn))
+	writer := textproto.NewWriter(bufio.NewWriter(conn))
+	writer.PrintfLine("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			writer.PrintfLine("250-localhost")
+			writer.PrintfLine("250 8BITMIME")
+		case "MAIL", "RCPT":
+			writer.PrintfLine(s.reply(command))
+		case "DATA":
+			writer.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			content, err := reader.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			s.data <- string(content)
+			writer.PrintfLine(s.reply("END"))
+		case "QUIT":
+			writer.PrintfLine("221 Bye")
+			return
+		default:
+			writer.PrintfLine("250 OK")
+		}
+	}
+}
+
+func (s *smtpServer) driver() IDriver {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	return NewSMTPDriver(host, portNumber, "", "", 5*time.Second)
+}
+
+func TestSMTPSend(t *testing.T) {
+	cases := map[string]struct {
+		replies      map[string]string
+		wantRejected bool
+		wantErr      bool
+	}{
+		"accepted":                 {},
+		"recipient rejected":       {replies: map[string]string{"RCPT": "550 5.1.1 User unknown"}, wantRejected: true},
+		"sender rejected":          {replies: map[string]string{"MAIL": "553 5.1.8 Sender address rejected"}, wantRejected: true},
+		"message rejected":         {replies: map[string]string{"END": "554 5.7.1 Message rejected"}, wantRejected: true},
+		"mailbox busy":             {replies: map[string]string{"RCPT": "450 4.2.1 Mailbox busy"}, wantErr: true},
+		"service not available":    {replies: map[string]string{"MAIL": "421 4.3.2 Service not available"}, wantErr: true},
+		"message temporary failed": {replies: map[string]string{"END": "451 4.3.0 Try again later"}, wantErr: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := newSMTPServer(t, c.replies)
+			defer server.listener.Close()
+
+			res, err := server.driver().Send(testMessage)
+			switch {
+			case c.wantRejected:
+				if err != ErrRejected {
+					t.Fatalf("expected %v, got %v", ErrRejected, err)
+				}
+			case c.wantErr:
+				if err == nil || err == ErrRejected {
+					t.Fatalf("expected temporary error, got %v", err)
+				}
+			default:
+				if err != nil {
+					t.Fatal(err)
+				}
+				if res.Status != StatusSent || res.MessageID == "" {
+					t.Fatalf("unexpected result %+v", res)
+				}
+				data := <-server.data
+				if !strings.Contains(data, "Message-ID: "+res.MessageID) {
+					t.Fatalf("message id %s is not sent", res.MessageID)
+				}
+			}
+		})
+	}
+}
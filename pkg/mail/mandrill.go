@@ -0,0 +1,61 @@
+package mail
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/keighl/mandrill"
+)
+
+// mandrillDriver ...
+type mandrillDriver struct {
+	client *mandrill.Client
+}
+
+// NewMandrillDriver ...
+func NewMandrillDriver(key string, timeout time.Duration) IDriver {
+	client := mandrill.ClientWithKey(key)
+	client.HTTPClient = &http.Client{Timeout: timeout}
+
+	return &mandrillDriver{client: client}
+}
+
+// Name ...
+func (d *mandrillDriver) Name() string {
+	return DriverMandrill
+}
+
+// Send send the message through messages/send api, rejected or invalid recipient return ErrRejected
+func (d *mandrillDriver) Send(msg Message) (res Result, err error) {
+	message := &mandrill.Message{
+		FromEmail: msg.FromEmail,
+		FromName:  msg.FromName,
+		Subject:   msg.Subject,
+		HTML:      msg.HTML,
+		Text:      msg.Text,
+		Tags:      msg.Tags,
+	}
+	message.AddRecipient(msg.ToEmail, msg.ToName, "to")
+
+	responses, err := d.client.MessagesSend(message)
+	if err != nil {
+		return res, err
+	}
+	if len(responses) == 0 {
+		return res, ErrRejected
+	}
+
+	res = Result{
+		MessageID: responses[0].Id,
+		Status:    StatusSent,
+	}
+	switch responses[0].Status {
+	case "queued", "scheduled":
+		res.Status = StatusQueued
+	case "rejected", "invalid":
+		res.Status = StatusFailed
+		return res, ErrRejected
+	}
+
+	return res, err
+}
@@ -0,0 +1,75 @@
+package mail
+
+import (
+	"bytes"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+// newMessageID ex: <bq7b3hh6j1a5p0dbg2vg@kriyapeople.com>
+func newMessageID(fromEmail string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(fromEmail, "@"); i >= 0 {
+		domain = fromEmail[i+1:]
+	}
+
+	return "<" + xid.New().String() + "@" + domain + ">"
+}
+
+// buildMIME build the raw mail with the text and html part as multipart/alternative
+func buildMIME(msg Message, messageID string, date time.Time) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+
+	from := mail.Address{Name: msg.FromName, Address: msg.FromEmail}
+	to := mail.Address{Name: msg.ToName, Address: msg.ToEmail}
+	header := []string{
+		"From: " + from.String(),
+		"To: " + to.String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject),
+		"Date: " + date.Format(time.RFC1123Z),
+		"Message-ID: " + messageID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	buf.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	parts := []struct {
+		contentType string
+		body        string
+	}{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	}
+	for _, p := range parts {
+		if p.body == "" {
+			continue
+		}
+		part, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(part)
+		_, err = qp.Write([]byte(p.body))
+		if err != nil {
+			return nil, err
+		}
+		err = qp.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	err := writer.Close()
+
+	return buf.Bytes(), err
+}
@@ -0,0 +1,108 @@
+package mail
+
+import (
+	"crypto/tls"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// smtpDriver ...
+type smtpDriver struct {
+	host     string
+	port     int
+	user     string
+	password string
+	timeout  time.Duration
+}
+
+// NewSMTPDriver STARTTLS is used when the server support it, auth is skipped when user is empty
+// so the driver can send to local smtp catcher
+func NewSMTPDriver(host string, port int, user, password string, timeout time.Duration) IDriver {
+	return &smtpDriver{
+		host:     host,
+		port:     port,
+		user:     user,
+		password: password,
+		timeout:  timeout,
+	}
+}
+
+// Name ...
+func (d *smtpDriver) Name() string {
+	return DriverSMTP
+}
+
+// rejected permanent failure reply (5xx) of the server is not retried
+func rejected(err error) error {
+	if protoErr, ok := err.(*textproto.Error); ok && protoErr.Code >= 500 {
+		return ErrRejected
+	}
+
+	return err
+}
+
+// Send the generated Message-ID is returned as the provider message id
+func (d *smtpDriver) Send(msg Message) (res Result, err error) {
+	messageID := newMessageID(msg.FromEmail)
+	body, err := buildMIME(msg, messageID, time.Now())
+	if err != nil {
+		return res, err
+	}
+
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(d.host, strconv.Itoa(d.port)), d.timeout)
+	if err != nil {
+		return res, err
+	}
+	conn.SetDeadline(time.Now().Add(d.timeout))
+	client, err := smtp.NewClient(conn, d.host)
+	if err != nil {
+		conn.Close()
+		return res, err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: d.host})
+		if err != nil {
+			return res, err
+		}
+	}
+	if d.user != "" {
+		err = client.Auth(smtp.PlainAuth("", d.user, d.password, d.host))
+		if err != nil {
+			return res, err
+		}
+	}
+
+	err = client.Mail(msg.FromEmail)
+	if err != nil {
+		return res, rejected(err)
+	}
+	err = client.Rcpt(msg.ToEmail)
+	if err != nil {
+		return res, rejected(err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return res, rejected(err)
+	}
+	_, err = writer.Write(body)
+	if err != nil {
+		return res, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return res, rejected(err)
+	}
+	client.Quit()
+
+	res = Result{
+		MessageID: messageID,
+		Status:    StatusSent,
+	}
+
+	return res, err
+}
@@ -0,0 +1,196 @@
+package mailtemplate
+
+import (
+	"bytes"
+	"errors"
+	htmltemplate "html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+)
+
+var (
+	// ErrInvalidTemplate template type is not found in any locale
+	ErrInvalidTemplate = errors.New("invalid_mail_template")
+	// ErrInvalidDict ...
+	ErrInvalidDict = errors.New("invalid_dict_call")
+)
+
+// Rendered ...
+type Rendered struct {
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// templates of one type and locale
+type templates struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// Engine render html and text mail per type and locale. The folder layout is:
+// layout/base.html|txt, partial/*.html|txt shared by every locale,
+// [locale]/partial/*.html|txt and [locale]/[type].html|txt. Type template define "subject" and "content"
+type Engine struct {
+	defaultLocale string
+	locales       []string
+	types         []string
+	templates     map[string]templates
+}
+
+// dict build a map from key value pairs, used to pass arguments into a partial
+func dict(values ...interface{}) (map[string]interface{}, error) {
+	if len(values)%2 != 0 {
+		return nil, ErrInvalidDict
+	}
+	res := map[string]interface{}{}
+	for i := 0; i < len(values); i += 2 {
+		key, ok := values[i].(string)
+		if !ok {
+			return nil, ErrInvalidDict
+		}
+		res[key] = values[i+1]
+	}
+
+	return res, nil
+}
+
+// New parse every template in the folder once, defaultLocale is used when the locale has no variant
+func New(dir, defaultLocale string) (res *Engine, err error) {
+	res = &Engine{
+		defaultLocale: defaultLocale,
+		templates:     map[string]templates{},
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	typeSet := map[string]bool{}
+	for _, entry := range entries {
+		if !entry.IsDir() || entry.Name() == "layout" || entry.Name() == "partial" {
+			continue
+		}
+		locale := entry.Name()
+		res.locales = append(res.locales, locale)
+
+		files, err := filepath.Glob(filepath.Join(dir, locale, "*.html"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			types := strings.TrimSuffix(filepath.Base(file), ".html")
+			t, err := parse(dir, locale, types)
+			if err != nil {
+				return nil, err
+			}
+			res.templates[key(locale, types)] = t
+			typeSet[types] = true
+		}
+	}
+	for types := range typeSet {
+		res.types = append(res.types, types)
+	}
+	sort.Strings(res.locales)
+	sort.Strings(res.types)
+
+	return res, err
+}
+
+func key(locale, types string) string {
+	return locale + "/" + types
+}
+
+// existFiles ...
+func existFiles(patterns ...string) (res []string, err error) {
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(pattern)
+		if err != nil {
+			return res, err
+		}
+		res = append(res, files...)
+	}
+
+	return res, err
+}
+
+// parse layout, shared partials, locale partials and the type template, the later one override the earlier
+func parse(dir, locale, types string) (res templates, err error) {
+	for _, ext := range []string{".html", ".txt"} {
+		files, err := existFiles(
+			filepath.Join(dir, "layout", "base"+ext),
+			filepath.Join(dir, "partial", "*"+ext),
+			filepath.Join(dir, locale, "partial", "*"+ext),
+		)
+		if err != nil {
+			return res, err
+		}
+		typeFile := filepath.Join(dir, locale, types+ext)
+		if _, err := os.Stat(typeFile); err != nil {
+			return res, err
+		}
+		files = append(files, typeFile)
+
+		if ext == ".html" {
+			res.html, err = htmltemplate.New("base" + ext).Funcs(htmltemplate.FuncMap{"dict": dict}).ParseFiles(files...)
+		} else {
+			res.text, err = texttemplate.New("base" + ext).Funcs(texttemplate.FuncMap{"dict": dict}).ParseFiles(files...)
+		}
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, err
+}
+
+// Locales ...
+func (e *Engine) Locales() []string {
+	return e.locales
+}
+
+// Types ...
+func (e *Engine) Types() []string {
+	return e.types
+}
+
+// Render render the type in the locale, falling back to the default locale
+func (e *Engine) Render(types, locale string, data interface{}) (res Rendered, err error) {
+	t, ok := e.templates[key(locale, types)]
+	if !ok {
+		locale = e.defaultLocale
+		t, ok = e.templates[key(locale, types)]
+		if !ok {
+			return res, ErrInvalidTemplate
+		}
+	}
+	res.Locale = locale
+
+	subject := &bytes.Buffer{}
+	err = t.text.ExecuteTemplate(subject, "subject", data)
+	if err != nil {
+		return res, err
+	}
+	res.Subject = strings.TrimSpace(subject.String())
+
+	html := &bytes.Buffer{}
+	err = t.html.ExecuteTemplate(html, "base.html", data)
+	if err != nil {
+		return res, err
+	}
+	res.HTML = html.String()
+
+	text := &bytes.Buffer{}
+	err = t.text.ExecuteTemplate(text, "base.txt", data)
+	if err != nil {
+		return res, err
+	}
+	res.Text = text.String()
+
+	return res, err
+}
@@ -0,0 +1,80 @@
+package mailtemplate
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	update = flag.Bool("update", false, "write the rendered mails into the golden files")
+
+	templateDir = filepath.Join("..", "..", "files", "mail", "template")
+	goldenDir   = filepath.Join("..", "..", "files", "mail", "golden")
+
+	// previewLinks link of every type, built from the MAIL_*_URL of env.example and the preview key
+	previewLinks = map[string]string{
+		"activation":     "http://127.0.0.1/activation?key=preview-key",
+		"reset_password": "http://127.0.0.1/reset-password?key=preview-key",
+		"change_email":   "http://127.0.0.1/change-email?key=preview-key",
+	}
+)
+
+// golden golden file content of the rendered mail, the subject is kept on the first line of the text
+func golden(rendered Rendered) map[string][]byte {
+	return map[string][]byte{
+		".html": []byte(rendered.HTML),
+		".txt":  []byte("Subject: " + rendered.Subject + "\n\n" + rendered.Text),
+	}
+}
+
+// TestGolden render every type and locale with the preview sample and compare it with the golden files,
+// run go test ./pkg/mailtemplate -update after an intended template change
+func TestGolden(t *testing.T) {
+	engine, err := New(templateDir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, locale := range engine.Locales() {
+		for _, types := range engine.Types() {
+			link, ok := previewLinks[types]
+			if !ok {
+				t.Fatalf("%s: missing preview link", types)
+			}
+			rendered, err := engine.Render(types, locale, map[string]interface{}{
+				"email":    "john.doe@example.com",
+				"username": "John Doe",
+				"key":      "preview-key",
+				"link":     link,
+			})
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, types, err)
+			}
+
+			for ext, content := range golden(rendered) {
+				path := filepath.Join(goldenDir, locale, types+ext)
+				if *update {
+					if err = os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+						t.Fatal(err)
+					}
+					if err = ioutil.WriteFile(path, content, 0644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+
+				expected, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(expected, content) {
+					t.Errorf("%s: rendered mail differ from the golden file, run with -update when the change is intended", path)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// localStorage store the object in local folder which is served by the app file server
+type localStorage struct {
+	Path    string
+	BaseURL string
+}
+
+// NewLocalStorage ...
+func NewLocalStorage(path, url string) (IStorage, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		err = os.MkdirAll(path, os.ModePerm)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &localStorage{Path: path, BaseURL: url}, nil
+}
+
+// fullPath ...
+func (s localStorage) fullPath(objectName string) string {
+	return filepath.Join(s.Path, filepath.FromSlash(filepath.Clean("/"+objectName)))
+}
+
+// Put ...
+func (s localStorage) Put(objectName string, reader io.Reader, size int64, contentType string) (err error) {
+	path := s.fullPath(objectName)
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, reader)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// Get ...
+func (s localStorage) Get(objectName string) (io.ReadCloser, error) {
+	return os.Open(s.fullPath(objectName))
+}
+
+// Exist ...
+func (s localStorage) Exist(objectName string) (bool, error) {
+	_, err := os.Stat(s.fullPath(objectName))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Delete ...
+func (s localStorage) Delete(objectName string) (err error) {
+	err = os.Remove(s.fullPath(objectName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// URL local file is public, so the expiry is ignored
+func (s localStorage) URL(objectName string, expiry time.Duration) (string, error) {
+	return s.BaseURL + "/" + objectName, nil
+}
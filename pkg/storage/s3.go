@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v6"
+)
+
+// s3Storage store the object in private S3 compatible bucket (AWS S3, MinIO)
+type s3Storage struct {
+	Client *minio.Client
+	Bucket string
+}
+
+// NewS3Storage ...
+func NewS3Storage(endpoint, accessKey, secretKey, bucket, region string, useSSL bool) (IStorage, error) {
+	client, err := minio.NewWithRegion(endpoint, accessKey, secretKey, useSSL, region)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the bucket when it is not exist yet
+	exist, err := client.BucketExists(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		err = client.MakeBucket(bucket, region)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &s3Storage{Client: client, Bucket: bucket}, nil
+}
+
+// Put ...
+func (s s3Storage) Put(objectName string, reader io.Reader, size int64, contentType string) (err error) {
+	_, err = s.Client.PutObject(s.Bucket, objectName, reader, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+
+	return err
+}
+
+// Get ...
+func (s s3Storage) Get(objectName string) (io.ReadCloser, error) {
+	return s.Client.GetObject(s.Bucket, objectName, minio.GetObjectOptions{})
+}
+
+// Exist ...
+func (s s3Storage) Exist(objectName string) (bool, error) {
+	_, err := s.Client.StatObject(s.Bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Delete ...
+func (s s3Storage) Delete(objectName string) error {
+	return s.Client.RemoveObject(s.Bucket, objectName)
+}
+
+// URL generate presigned time limited download url
+func (s s3Storage) URL(objectName string, expiry time.Duration) (string, error) {
+	u, err := s.Client.PresignedGetObject(s.Bucket, objectName, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return u.String(), nil
+}
@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+const (
+	// DriverLocal ...
+	DriverLocal = "local"
+	// DriverS3 ...
+	DriverS3 = "s3"
+)
+
+// IStorage ...
+type IStorage interface {
+	Put(objectName string, reader io.Reader, size int64, contentType string) error
+	Get(objectName string) (io.ReadCloser, error)
+	Exist(objectName string) (bool, error)
+	Delete(objectName string) error
+	URL(objectName string, expiry time.Duration) (string, error)
+}
+
+// Connection ...
+type Connection struct {
+	Driver string
+
+	// Local driver
+	Path string
+	URL  string
+
+	// S3 compatible driver
+	Endpoint  string
+	AccessKey string
+	SecretKey string
+	Bucket    string
+	Region    string
+	UseSSL    bool
+}
+
+// Connect return storage implementation based on the driver
+func (m Connection) Connect() (IStorage, error) {
+	switch m.Driver {
+	case DriverLocal, "":
+		return NewLocalStorage(m.Path, m.URL)
+	case DriverS3:
+		return NewS3Storage(m.Endpoint, m.AccessKey, m.SecretKey, m.Bucket, m.Region, m.UseSSL)
+	}
+
+	return nil, errors.New("invalid_storage_driver")
+}
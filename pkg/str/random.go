@@ -1,6 +1,8 @@
 package str
 
 import (
+	cryptoRand "crypto/rand"
+	"encoding/hex"
 	"math/rand"
 	"time"
 )
@@ -39,3 +41,13 @@ func RandomNumericString(l int) string {
 func randInt(min int, max int) int {
 	return min + rand.Intn(max-min)
 }
+
+// RandomSecureString return hex string from crypto random bytes, used for secret key
+func RandomSecureString(byteLength int) string {
+	b := make([]byte, byteLength)
+	if _, err := cryptoRand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
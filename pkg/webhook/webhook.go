@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"io"
+	"io/ioutil"
+	"kriyapeople/pkg/hmacsha"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// HeaderSignature hmac sha256 of the signed content, ex: sha256=5257a869...
+	HeaderSignature = "X-Signature"
+	// HeaderTimestamp unix second of the delivery attempt, part of the signed content
+	HeaderTimestamp = "X-Signature-Timestamp"
+	// HeaderDeliveryID id of the delivery, redelivery of the same event has new id
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	// HeaderEventID id of the event, the receiver can use it to drop duplicates
+	HeaderEventID = "X-Webhook-Event-ID"
+	// HeaderEventType ...
+	HeaderEventType = "X-Webhook-Event"
+
+	signaturePrefix = "sha256="
+	userAgent       = "kriyapeople-webhook/1"
+)
+
+var (
+	// MaxResponseBody response body kept in the delivery log
+	MaxResponseBody = 1024
+)
+
+// SignedContent content signed by the secret: [timestamp].[body]
+func SignedContent(timestamp int64, body []byte) string {
+	return strconv.FormatInt(timestamp, 10) + "." + string(body)
+}
+
+// Sign signature header value of the body
+func Sign(secret string, timestamp int64, body []byte) string {
+	cred := hmacsha.Credential{Key: secret}
+
+	return signaturePrefix + cred.Encrypt(SignedContent(timestamp, body))
+}
+
+// Verify check the signature and reject timestamp older or newer than tolerance, used by the receiver
+func Verify(secret, signature string, timestamp int64, body []byte, tolerance time.Duration, now time.Time) bool {
+	diff := now.Sub(time.Unix(timestamp, 0))
+	if diff > tolerance || diff < -tolerance {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
+
+// Request ...
+type Request struct {
+	URL        string
+	Secret     string
+	DeliveryID string
+	EventID    string
+	EventType  string
+	Body       []byte
+}
+
+// Response ...
+type Response struct {
+	StatusCode int
+	Body       string
+	Duration   time.Duration
+}
+
+// Success 2xx status is the only accepted delivery
+func (res Response) Success() bool {
+	return res.StatusCode >= 200 && res.StatusCode < 300
+}
+
+// Client ...
+type Client struct {
+	HTTP *http.Client
+}
+
+// NewClient redirect is not followed, the endpoint url must be the final one
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		HTTP: &http.Client{
+			Timeout: timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// Send post the signed body, error is returned only when no response is received
+func (c *Client) Send(data Request, now time.Time) (res Response, err error) {
+	req, err := http.NewRequest(http.MethodPost, data.URL, bytes.NewReader(data.Body))
+	if err != nil {
+		return res, err
+	}
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderSignature, Sign(data.Secret, timestamp, data.Body))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderDeliveryID, data.DeliveryID)
+	req.Header.Set(HeaderEventID, data.EventID)
+	req.Header.Set(HeaderEventType, data.EventType)
+
+	start := time.Now()
+	resp, err := c.HTTP.Do(req)
+	res.Duration = time.Since(start)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, int64(MaxResponseBody)))
+	res.StatusCode = resp.StatusCode
+	// The body is stored in text column, which accept neither invalid utf8 nor null byte
+	res.Body = strings.Replace(strings.ToValidUTF8(string(body), ""), "\x00", "", -1)
+
+	return res, nil
+}
@@ -4,6 +4,7 @@ import (
 	"kriyapeople/pkg/logruslogger"
 	api "kriyapeople/server/handler"
 	"kriyapeople/server/middleware"
+	"kriyapeople/usecase"
 
 	chimiddleware "github.com/go-chi/chi/middleware"
 
@@ -28,6 +29,18 @@ func (boot *Bootup) RegisterRoutes() {
 	mJwt := middleware.VerifyMiddlewareInit{
 		ContractUC: &boot.ContractUC,
 	}
+	mEncrypted := middleware.EncryptedPayloadInit{
+		ContractUC: &boot.ContractUC,
+	}
+	scope := func(menu string) middleware.VerifyPermissionInit {
+		return middleware.VerifyPermissionInit{ContractUC: &boot.ContractUC, Menu: menu}
+	}
+
+	metricHandler := api.MetricHandler{Handler: handlerType}
+	boot.R.Get("/metrics", metricHandler.MetricsHandler)
+
+	jwksHandler := api.JwksHandler{Handler: handlerType}
+	boot.R.Get("/.well-known/jwks.json", jwksHandler.JwksHandler)
 
 	boot.R.Route("/v1", func(r chi.Router) {
 		// Define a limit rate to 1000 requests per IP per request.
@@ -44,24 +57,114 @@ func (boot *Bootup) RegisterRoutes() {
 		r.Use(logruslogger.NewStructuredLogger(boot.EnvConfig["LOG_FILE_PATH"], boot.EnvConfig["LOG_DEFAULT"], boot.ContractUC.ReqID))
 		r.Use(chimiddleware.Recoverer)
 
+		// Opt in encrypted request and response body
+		r.Use(mEncrypted.EncryptedPayload)
+
 		// API ADMIN
 		r.Route("/api-admin", func(r chi.Router) {
+			encryptionSessionHandler := api.EncryptionSessionHandler{Handler: handlerType}
+			r.Route("/encryption", func(r chi.Router) {
+				// Every session is stored in redis, limit the creation per IP
+				sessionRate, err := limiter.NewRateFromFormatted(boot.EnvConfig["AES_FRONT_SESSION_RATE"])
+				if err != nil {
+					sessionRate, _ = limiter.NewRateFromFormatted("10-M")
+				}
+				sessionStore, _ := sredis.NewStoreWithOptions(boot.ContractUC.Redis, limiter.StoreOptions{
+					Prefix:   "limiter_encryption_session",
+					MaxRetry: 3,
+				})
+				r.Use(stdlib.NewMiddleware(limiter.New(sessionStore, sessionRate, limiter.WithTrustForwardHeader(true))).Handler)
+				r.Use(mEncrypted.RequireEncryptedPayload)
+				r.Post("/session", encryptionSessionHandler.CreateHandler)
+			})
+
 			adminHandler := api.AdminHandler{Handler: handlerType}
 			r.Route("/admin", func(r chi.Router) {
 				r.Group(func(r chi.Router) {
 					r.Post("/login", adminHandler.LoginHandler)
+					r.Post("/email/confirm/{key}", adminHandler.ConfirmChangeEmailHandler)
 				})
 				r.Group(func(r chi.Router) {
-					r.Use(mJwt.VerifySuperadminTokenCredential)
+					r.Use(scope(usecase.ScopeUserWrite).VerifyCredential)
 					r.Post("/", adminHandler.CreateHandler)
 					r.Put("/id/{id}", adminHandler.UpdateHandler)
 					r.Delete("/id/{id}", adminHandler.DeleteHandler)
 				})
 				r.Group(func(r chi.Router) {
-					r.Use(mJwt.VerifyAdminTokenCredential)
+					r.Use(scope(usecase.ScopeUserRead).VerifySignatureOrCredential)
 					r.Get("/", adminHandler.GetAllHandler)
 					r.Get("/id/{id}", adminHandler.GetByIDHandler)
 				})
+				r.Group(func(r chi.Router) {
+					r.Use(mJwt.VerifyAdminTokenCredential)
+					r.Get("/me", adminHandler.GetMeHandler)
+					r.Patch("/me", adminHandler.UpdateMeHandler)
+					r.Put("/me/password", adminHandler.ChangePasswordHandler)
+					r.Post("/me/email", adminHandler.ChangeEmailHandler)
+					r.Put("/me/profile-image", adminHandler.UpdateProfileImageHandler)
+				})
+			})
+
+			fileHandler := api.FileHandler{Handler: handlerType}
+			r.Route("/file", func(r chi.Router) {
+				r.Group(func(r chi.Router) {
+					r.Use(scope(usecase.ScopeFileUsage).VerifyCredential)
+					r.Get("/usage", fileHandler.UsageHandler)
+				})
+				r.Group(func(r chi.Router) {
+					r.Use(mJwt.VerifyAdminTokenCredential)
+					r.Post("/", fileHandler.UploadHandler)
+					r.Post("/base64", fileHandler.UploadBase64Handler)
+					r.Post("/url", fileHandler.UploadURLHandler)
+				})
+			})
+
+			queueHandler := api.QueueHandler{Handler: handlerType}
+			r.Route("/queue", func(r chi.Router) {
+				r.Use(scope(usecase.ScopeQueueManage).VerifyCredential)
+				r.Get("/{queue}/deadletter", queueHandler.DeadLetterHandler)
+				r.Post("/{queue}/deadletter/replay", queueHandler.ReplayDeadLetterHandler)
+				r.Post("/{queue}/deadletter/purge", queueHandler.PurgeDeadLetterHandler)
+			})
+
+			mailHandler := api.MailHandler{Handler: handlerType}
+			r.Route("/mail", func(r chi.Router) {
+				r.Use(scope(usecase.ScopeMailPreview).VerifyCredential)
+				r.Get("/template", mailHandler.TemplateHandler)
+				r.Get("/template/{type}/preview", mailHandler.PreviewHandler)
+			})
+
+			webhookHandler := api.WebhookHandler{Handler: handlerType}
+			r.Route("/webhook", func(r chi.Router) {
+				r.Use(scope(usecase.ScopeWebhookManage).VerifyCredential)
+				r.Get("/", webhookHandler.GetAllHandler)
+				r.Post("/", webhookHandler.CreateHandler)
+				r.Get("/{id}", webhookHandler.GetByIDHandler)
+				r.Put("/{id}", webhookHandler.UpdateHandler)
+				r.Delete("/{id}", webhookHandler.DeleteHandler)
+				r.Post("/{id}/secret", webhookHandler.RotateSecretHandler)
+				r.Get("/{id}/delivery", webhookHandler.GetAllDeliveryHandler)
+				r.Post("/{id}/delivery/{delivery_id}/redeliver", webhookHandler.RedeliverHandler)
+			})
+
+			partnerClientHandler := api.PartnerClientHandler{Handler: handlerType}
+			r.Route("/partner-client", func(r chi.Router) {
+				r.Use(mJwt.VerifySuperadminTokenCredential)
+				r.Get("/", partnerClientHandler.GetAllHandler)
+				r.Post("/", partnerClientHandler.CreateHandler)
+				r.Put("/{id}/status", partnerClientHandler.UpdateStatusHandler)
+				r.Post("/{id}/secret", partnerClientHandler.RotateSecretHandler)
+				r.Delete("/{id}", partnerClientHandler.DeleteHandler)
+			})
+
+			apiKeyHandler := api.APIKeyHandler{Handler: handlerType}
+			r.Route("/api-key", func(r chi.Router) {
+				r.Use(mJwt.VerifySuperadminTokenCredential)
+				r.Get("/", apiKeyHandler.GetAllHandler)
+				r.Get("/scope", apiKeyHandler.GetScopeHandler)
+				r.Post("/", apiKeyHandler.CreateHandler)
+				r.Get("/{id}", apiKeyHandler.GetByIDHandler)
+				r.Delete("/{id}", apiKeyHandler.RevokeHandler)
 			})
 
 			// adminResetPasswordHandler := api.AdminResetPasswordHandler{Handler: handlerType}
@@ -156,3 +156,138 @@ func (h *AdminHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	SendSuccess(w, res, nil)
 	return
 }
+
+// GetMeHandler ...
+func (h *AdminHandler) GetMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	adminUc := usecase.AdminUC{ContractUC: h.ContractUC}
+	res, err := adminUc.FindByID(userID, false)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UpdateMeHandler ...
+func (h *AdminHandler) UpdateMeHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	req := request.MeRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	adminUc := usecase.AdminUC{ContractUC: h.ContractUC}
+	res, err := adminUc.UpdateMe(userID, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// ChangePasswordHandler ...
+func (h *AdminHandler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+	deviceID := requestKeyFromContextInterface(r.Context(), "user", "device_id")
+
+	req := request.ChangePasswordRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	adminUc := usecase.AdminUC{ContractUC: h.ContractUC}
+	res, err := adminUc.ChangePassword(userID, deviceID, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// ChangeEmailHandler ...
+func (h *AdminHandler) ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	req := request.ChangeEmailRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	adminUc := usecase.AdminUC{ContractUC: h.ContractUC}
+	res, err := adminUc.RequestChangeEmail(userID, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// ConfirmChangeEmailHandler ...
+func (h *AdminHandler) ConfirmChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "key")
+	if key == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	adminUc := usecase.AdminUC{ContractUC: h.ContractUC}
+	res, err := adminUc.ConfirmChangeEmail(key)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UpdateProfileImageHandler ...
+func (h *AdminHandler) UpdateProfileImageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	req := request.ProfileImageRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	adminUc := usecase.AdminUC{ContractUC: h.ContractUC}
+	res, err := adminUc.UpdateProfileImage(userID, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"kriyapeople/server/request"
+	"kriyapeople/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// APIKeyHandler ...
+type APIKeyHandler struct {
+	Handler
+}
+
+// GetAllHandler ...
+func (h *APIKeyHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		SendBadRequest(w, "Invalid page value")
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		SendBadRequest(w, "Invalid limit value")
+		return
+	}
+
+	apiKeyUc := usecase.APIKeyUC{ContractUC: h.ContractUC}
+	res, p, err := apiKeyUc.FindAll(page, limit)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, p)
+	return
+}
+
+// GetScopeHandler ...
+func (h *APIKeyHandler) GetScopeHandler(w http.ResponseWriter, r *http.Request) {
+	apiKeyUc := usecase.APIKeyUC{ContractUC: h.ContractUC}
+	res := apiKeyUc.Scopes()
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// GetByIDHandler ...
+func (h *APIKeyHandler) GetByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	apiKeyUc := usecase.APIKeyUC{ContractUC: h.ContractUC}
+	res, err := apiKeyUc.FindByID(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// CreateHandler the key is returned only here
+func (h *APIKeyHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	req := request.APIKeyRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	apiKeyUc := usecase.APIKeyUC{ContractUC: h.ContractUC}
+	res, err := apiKeyUc.Create(userID, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// RevokeHandler ...
+func (h *APIKeyHandler) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	apiKeyUc := usecase.APIKeyUC{ContractUC: h.ContractUC}
+	res, err := apiKeyUc.Revoke(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"kriyapeople/usecase"
+	"net/http"
+)
+
+// EncryptionSessionHandler ...
+type EncryptionSessionHandler struct {
+	Handler
+}
+
+// CreateHandler the key is sent only in the encrypted response
+func (h *EncryptionSessionHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	encryptionSessionUc := usecase.EncryptionSessionUC{ContractUC: h.ContractUC}
+	res, err := encryptionSessionUc.Create()
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
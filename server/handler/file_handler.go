@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"io"
+	"io/ioutil"
+	"kriyapeople/helper"
+	"kriyapeople/pkg/str"
+	"kriyapeople/server/request"
+	"kriyapeople/usecase"
+	"net/http"
+
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// FileHandler ...
+type FileHandler struct {
+	Handler
+}
+
+// UploadHandler upload file using multipart form with "file" and "type" field
+func (h *FileHandler) UploadHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	// Give 1 MB tolerance for the rest of multipart body
+	maxUploadSize := int64(str.StringToInt(h.EnvConfig["FILE_MAX_UPLOAD_SIZE"]))
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize+(1<<20))
+	if err := r.ParseMultipartForm(maxUploadSize); err != nil {
+		SendBadRequest(w, helper.FileTooBig)
+		return
+	}
+
+	types := r.FormValue("type")
+	f, header, err := r.FormFile("file")
+	if err != nil {
+		SendBadRequest(w, helper.FileError)
+		return
+	}
+	defer f.Close()
+	if header.Size > maxUploadSize {
+		SendBadRequest(w, helper.FileTooBig)
+		return
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(f, maxUploadSize+1))
+	if err != nil {
+		SendBadRequest(w, helper.FileError)
+		return
+	}
+
+	fileUc := usecase.FileUC{ContractUC: h.ContractUC}
+	res, err := fileUc.Upload(userID, types, header.Header.Get("Content-Type"), data)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UploadBase64Handler ...
+func (h *FileHandler) UploadBase64Handler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	req := request.FileBase64Request{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	fileUc := usecase.FileUC{ContractUC: h.ContractUC}
+	res, err := fileUc.UploadBase64(userID, req.Type, req.ContentType, req.File)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UploadURLHandler import file from remote url
+func (h *FileHandler) UploadURLHandler(w http.ResponseWriter, r *http.Request) {
+	userID := requestKeyFromContextInterface(r.Context(), "user", "id")
+
+	req := request.FileURLRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	fileUc := usecase.FileUC{ContractUC: h.ContractUC}
+	res, err := fileUc.UploadFromURL(userID, req.Type, req.URL)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UsageHandler report storage usage per user and file type, filtered by user_id query
+func (h *FileHandler) UsageHandler(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+
+	fileUc := usecase.FileUC{ContractUC: h.ContractUC}
+	res, err := fileUc.Usage(userID)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// JwksHandler ...
+type JwksHandler struct {
+	Handler
+}
+
+// JwksHandler publish the public keys which can verify the token in RFC 7517 format
+func (h *JwksHandler) JwksHandler(w http.ResponseWriter, r *http.Request) {
+	res, err := json.Marshal(h.Jwt.JWKS(time.Now()))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Write(res)
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"kriyapeople/usecase"
+	"net/http"
+
+	"github.com/go-chi/chi"
+)
+
+// MailHandler ...
+type MailHandler struct {
+	Handler
+}
+
+// TemplateHandler list the mail template types and locales
+func (h *MailHandler) TemplateHandler(w http.ResponseWriter, r *http.Request) {
+	mailUc := usecase.MailUC{ContractUC: h.ContractUC}
+	res, err := mailUc.Templates()
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// PreviewHandler render the mail template with sample data, format=html return the html body as is
+func (h *MailHandler) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	types := chi.URLParam(r, "type")
+	locale := r.URL.Query().Get("locale")
+
+	mailUc := usecase.MailUC{ContractUC: h.ContractUC}
+	res, err := mailUc.Preview(types, locale)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(res.HTML))
+		return
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(res.Text))
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"kriyapeople/usecase"
+	"net/http"
+)
+
+// MetricHandler ...
+type MetricHandler struct {
+	Handler
+}
+
+// MetricsHandler expose the metrics in prometheus text format, protected by METRICS_TOKEN bearer token.
+// The endpoint is disabled when the token is not set
+func (h *MetricHandler) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	token := h.EnvConfig["METRICS_TOKEN"]
+	if token == "" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	outboxUc := usecase.OutboxUC{ContractUC: h.ContractUC}
+	outbox, err := outboxUc.Lag()
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	fmt.Fprintf(w, "# HELP outbox_pending_messages Outbox messages waiting to be published.\n")
+	fmt.Fprintf(w, "# TYPE outbox_pending_messages gauge\n")
+	fmt.Fprintf(w, "outbox_pending_messages %d\n", outbox.Pending)
+	fmt.Fprintf(w, "# HELP outbox_lag_seconds Age of the oldest outbox message waiting to be published.\n")
+	fmt.Fprintf(w, "# TYPE outbox_lag_seconds gauge\n")
+	fmt.Fprintf(w, "outbox_lag_seconds %f\n", outbox.LagSeconds)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"kriyapeople/server/request"
+	"kriyapeople/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// PartnerClientHandler ...
+type PartnerClientHandler struct {
+	Handler
+}
+
+// GetAllHandler ...
+func (h *PartnerClientHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		SendBadRequest(w, "Invalid page value")
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		SendBadRequest(w, "Invalid limit value")
+		return
+	}
+
+	partnerClientUc := usecase.PartnerClientUC{ContractUC: h.ContractUC}
+	res, p, err := partnerClientUc.FindAll(page, limit)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, p)
+	return
+}
+
+// CreateHandler the signing secret is returned only here and on rotation
+func (h *PartnerClientHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	req := request.PartnerClientRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	partnerClientUc := usecase.PartnerClientUC{ContractUC: h.ContractUC}
+	res, err := partnerClientUc.Create(&req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UpdateStatusHandler ...
+func (h *PartnerClientHandler) UpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	req := request.PartnerClientStatusRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	partnerClientUc := usecase.PartnerClientUC{ContractUC: h.ContractUC}
+	res, err := partnerClientUc.UpdateStatus(id, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// RotateSecretHandler ...
+func (h *PartnerClientHandler) RotateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	partnerClientUc := usecase.PartnerClientUC{ContractUC: h.ContractUC}
+	res, err := partnerClientUc.RotateSecret(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// DeleteHandler ...
+func (h *PartnerClientHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	partnerClientUc := usecase.PartnerClientUC{ContractUC: h.ContractUC}
+	res, err := partnerClientUc.Delete(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
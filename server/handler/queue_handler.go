@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"kriyapeople/pkg/str"
+	"kriyapeople/server/request"
+	"kriyapeople/usecase"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// QueueHandler ...
+type QueueHandler struct {
+	Handler
+}
+
+// DeadLetterHandler list the dead letter messages of the queue, limited by limit query
+func (h *QueueHandler) DeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "queue")
+	limit := str.StringToInt(r.URL.Query().Get("limit"))
+
+	queueUc := usecase.QueueUC{ContractUC: h.ContractUC}
+	res, err := queueUc.ListDeadLetter(name, limit)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// ReplayDeadLetterHandler ...
+func (h *QueueHandler) ReplayDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "queue")
+
+	req := request.DeadLetterReplayRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	queueUc := usecase.QueueUC{ContractUC: h.ContractUC}
+	res, err := queueUc.ReplayDeadLetter(name, req.IDs)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// PurgeDeadLetterHandler ...
+func (h *QueueHandler) PurgeDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "queue")
+
+	req := request.DeadLetterPurgeRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	queueUc := usecase.QueueUC{ContractUC: h.ContractUC}
+	res, err := queueUc.PurgeDeadLetter(name, req.IDs, req.All)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
@@ -0,0 +1,199 @@
+package handler
+
+import (
+	"kriyapeople/server/request"
+	"kriyapeople/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	validator "gopkg.in/go-playground/validator.v9"
+)
+
+// WebhookHandler ...
+type WebhookHandler struct {
+	Handler
+}
+
+// GetAllHandler ...
+func (h *WebhookHandler) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		SendBadRequest(w, "Invalid page value")
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		SendBadRequest(w, "Invalid limit value")
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, p, err := webhookUc.FindAll(page, limit)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, p)
+	return
+}
+
+// GetByIDHandler ...
+func (h *WebhookHandler) GetByIDHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, err := webhookUc.FindByID(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// CreateHandler the signing secret is returned only here and on rotation
+func (h *WebhookHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	req := request.WebhookEndpointRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, err := webhookUc.Create(&req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// UpdateHandler ...
+func (h *WebhookHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	req := request.WebhookEndpointRequest{}
+	if err := h.Handler.Bind(r, &req); err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+	if err := h.Handler.Validate.Struct(req); err != nil {
+		h.SendRequestValidationError(w, err.(validator.ValidationErrors))
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, err := webhookUc.Update(id, &req)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// RotateSecretHandler ...
+func (h *WebhookHandler) RotateSecretHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, err := webhookUc.RotateSecret(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// DeleteHandler ...
+func (h *WebhookHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, err := webhookUc.Delete(id)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
+
+// GetAllDeliveryHandler delivery log of the endpoint, filtered by status query
+func (h *WebhookHandler) GetAllDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	if id == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil {
+		SendBadRequest(w, "Invalid page value")
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil {
+		SendBadRequest(w, "Invalid limit value")
+		return
+	}
+	status := r.URL.Query().Get("status")
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, p, err := webhookUc.FindAllDelivery(id, status, page, limit)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, p)
+	return
+}
+
+// RedeliverHandler ...
+func (h *WebhookHandler) RedeliverHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	deliveryID := chi.URLParam(r, "delivery_id")
+	if id == "" || deliveryID == "" {
+		SendBadRequest(w, "Parameter must be filled")
+		return
+	}
+
+	webhookUc := usecase.WebhookUC{ContractUC: h.ContractUC}
+	res, err := webhookUc.Redeliver(id, deliveryID)
+	if err != nil {
+		SendBadRequest(w, err.Error())
+		return
+	}
+
+	SendSuccess(w, res, nil)
+	return
+}
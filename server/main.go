@@ -4,13 +4,19 @@ import (
 	"kriyapeople/pkg/aes"
 	"kriyapeople/pkg/aesfront"
 	"kriyapeople/pkg/amqp"
+	"kriyapeople/pkg/apple"
+	"kriyapeople/pkg/clamav"
 	"kriyapeople/pkg/env"
+	"kriyapeople/pkg/hmacsha"
 	"kriyapeople/pkg/interfacepkg"
 	"kriyapeople/pkg/jwe"
 	"kriyapeople/pkg/jwt"
 	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/mailtemplate"
 	"kriyapeople/pkg/pg"
+	"kriyapeople/pkg/storage"
 	"kriyapeople/pkg/str"
+	"kriyapeople/pkg/webhook"
 	boot "kriyapeople/server/bootstrap"
 	"kriyapeople/usecase"
 
@@ -24,6 +30,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-playground/locales/en"
@@ -91,59 +98,148 @@ func main() {
 	}
 	defer db.Close()
 
-	// Mqueue connection
-	amqpInfo := amqp.Connection{
-		URL: envConfig["AMQP_URL"],
+	// Mqueue publisher
+	confirmTimeout, _ := time.ParseDuration(envConfig["AMQP_CONFIRM_TIMEOUT"])
+	closeTimeout, _ := time.ParseDuration(envConfig["AMQP_CLOSE_TIMEOUT"])
+	publisher, err := amqp.NewPublisher(amqp.PublisherConfig{
+		URL:            envConfig["AMQP_URL"],
+		PoolSize:       str.StringToInt(envConfig["AMQP_PUBLISHER_POOL"]),
+		ConfirmTimeout: confirmTimeout,
+		BufferSize:     str.StringToInt(envConfig["AMQP_BUFFER_SIZE"]),
+		CloseTimeout:   closeTimeout,
+	})
+	if err != nil {
+		panic(err)
 	}
-	amqpConn, amqpChannel, err := amqpInfo.Connect()
+	defer publisher.Close()
+
+	// File storage connection
+	storageInfo := storage.Connection{
+		Driver:    envConfig["STORAGE_DRIVER"],
+		Path:      envConfig["FILE_STATIC_FILE"],
+		URL:       envConfig["APP_IMAGE_URL"] + envConfig["FILE_PATH"],
+		Endpoint:  envConfig["S3_ENDPOINT"],
+		AccessKey: envConfig["S3_ACCESS_KEY"],
+		SecretKey: envConfig["S3_SECRET_KEY"],
+		Bucket:    envConfig["S3_BUCKET"],
+		Region:    envConfig["S3_REGION"],
+		UseSSL:    str.StringToBool(envConfig["S3_USE_SSL"]),
+	}
+	fileStorage, err := storageInfo.Connect()
 	if err != nil {
 		panic(err)
 	}
-	usecase.AmqpConnection = amqpConn
-	usecase.AmqpChannel = amqpChannel
+	htmlStorage, err := storage.NewLocalStorage(envConfig["HTML_FILE_STATIC_FILE"], envConfig["APP_IMAGE_URL"]+envConfig["HTML_FILE_PATH"])
+	if err != nil {
+		panic(err)
+	}
+
+	// Antivirus scanner, disabled when the address is empty
+	var scanner clamav.IScanner
+	if envConfig["CLAMAV_ADDRESS"] != "" {
+		scanTimeout, _ := time.ParseDuration(envConfig["CLAMAV_TIMEOUT"])
+		scanner = clamav.NewClient(envConfig["CLAMAV_ADDRESS"], scanTimeout)
+	}
 
-	// JWT credential
-	jwtCredential := jwt.Credential{
-		Secret:           envConfig["TOKEN_SECRET"],
-		ExpSecret:        str.StringToInt(envConfig["TOKEN_EXP_SECRET"]),
-		RefreshSecret:    envConfig["TOKEN_REFRESH_SECRET"],
-		RefreshExpSecret: str.StringToInt(envConfig["TOKEN_EXP_REFRESH_SECRET"]),
+	// JWT credential, every key in TOKEN_KEY_PATH is published and can verify token
+	tokenClockSkew, err := time.ParseDuration(envConfig["TOKEN_CLOCK_SKEW"])
+	if err != nil {
+		panic(err)
+	}
+	jwtCredential, err := jwt.NewCredential(envConfig["TOKEN_KEY_PATH"], envConfig["TOKEN_SIGNING_KEY_ID"], envConfig["TOKEN_RETIRED_KEY_IDS"],
+		envConfig["TOKEN_ISSUER"], envConfig["TOKEN_AUDIENCE"], tokenClockSkew,
+		str.StringToInt(envConfig["TOKEN_EXP_SECRET"]), str.StringToInt(envConfig["TOKEN_EXP_REFRESH_SECRET"]))
+	if err != nil {
+		panic(err)
 	}
 
 	// JWE credential
-	jweCredential := jwe.Credential{
-		KeyLocation: envConfig["APP_PRIVATE_KEY_LOCATION"],
-		Passphrase:  envConfig["APP_PRIVATE_KEY_PASSPHRASE"],
+	jweLegacyUntil, err := jwe.ParseLegacyUntil(envConfig["JWE_RSA1_5_UNTIL"])
+	if err != nil {
+		panic(err)
+	}
+	jweCredential, err := jwe.NewCredential(envConfig["APP_PRIVATE_KEY_LOCATION"], envConfig["APP_PRIVATE_KEY_PASSPHRASE"], jweLegacyUntil)
+	if err != nil {
+		panic(err)
+	}
+
+	// AES credential, AES_KEY is kept only to decrypt the legacy ciphertext
+	aesCredential, err := aes.NewCredential(envConfig["AES_KEY"], envConfig["AES_KEYS"], envConfig["AES_PRIMARY_KEY_ID"])
+	if err != nil {
+		panic(err)
 	}
 
-	// AES credential
-	aesCredential := aes.Credential{
-		Key: envConfig["AES_KEY"],
+	// Blind index of the encrypted user data
+	blindIndexCredential := hmacsha.Credential{
+		Key: envConfig["USER_BLIND_INDEX_KEY"],
 	}
 
-	// AES Front credential
+	// AES Front credential, AES_FRONT_iV is the name before the rename. Empty iv would switch the static
+	// credential into random iv mode which the existing clients can not read
+	aesFrontIv := envConfig["AES_FRONT_IV"]
+	if aesFrontIv == "" {
+		aesFrontIv = envConfig["AES_FRONT_iV"]
+	}
+	if aesFrontIv == "" {
+		panic("AES_FRONT_IV is required")
+	}
 	aesFrontCredential := aesfront.Credential{
 		Key: envConfig["AES_FRONT_KEY"],
-		Iv:  envConfig["AES_FRONT_IV"],
+		Iv:  aesFrontIv,
+	}
+
+	// Apple sign in credential
+	appleJwksTTL, _ := time.ParseDuration(envConfig["APPLE_JWKS_TTL"])
+	appleCredential := apple.Credential{
+		ClientIDs: strings.Split(envConfig["APPLE_CLIENT_ID"], ","),
+		Keys:      apple.NewJWKSCache(apple.AuthKeysAPI, appleJwksTTL),
 	}
 
+	// Mail template, rendered on the preview endpoints
+	mailTemplate, err := mailtemplate.New(envConfig["MAIL_TEMPLATE_PATH"], envConfig["APP_LOCALE"])
+	if err != nil {
+		panic(err)
+	}
+
+	// Webhook client, redirect is not followed
+	webhookTimeout, _ := time.ParseDuration(envConfig["WEBHOOK_TIMEOUT"])
+	if webhookTimeout <= 0 {
+		webhookTimeout = usecase.DefaultWebhookTimeout
+	}
+	webhookClient := webhook.NewClient(webhookTimeout)
+
 	// Validator initialize
 	validatorInit()
 
 	// Load contract struct
 	contractUC := usecase.ContractUC{
-		ReqID:       xid.New().String(),
-		DB:          db,
-		AmqpConn:    amqpConn,
-		AmqpChannel: amqpChannel,
-		Redis:       redisClient,
-		EnvConfig:   envConfig,
-		Jwt:         jwtCredential,
-		Jwe:         jweCredential,
-		Aes:         aesCredential,
-		AesFront:    aesFrontCredential,
+		ReqID:         xid.New().String(),
+		DB:            db,
+		Publisher:     publisher,
+		Redis:         redisClient,
+		EnvConfig:     envConfig,
+		Jwt:           jwtCredential,
+		Jwe:           jweCredential,
+		Aes:           aesCredential,
+		AesFront:      aesFrontCredential,
+		BlindIndexKey: blindIndexCredential,
+		Apple:         appleCredential,
+		Storage:       fileStorage,
+		HTMLStorage:   htmlStorage,
+		Scanner:       scanner,
+		MailTemplate:  mailTemplate,
+		Webhook:       webhookClient,
 	}
 
+	// Unassigned uploaded file cleaner
+	go usecase.FileCleanScheduler(&contractUC)
+
+	// Outbox relay
+	go usecase.OutboxRelayScheduler(&contractUC)
+
+	// Webhook delivery
+	go usecase.WebhookDeliveryScheduler(&contractUC)
+
 	r := chi.NewRouter()
 	// Cors setup
 	r.Use(cors.New(cors.Options{
@@ -246,6 +342,11 @@ func fileServer(r chi.Router, path string, root http.FileSystem) {
 	path += "*"
 
 	r.Get(path, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Pending and quarantined objects are never served
+		if usecase.IsHiddenObject(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(path, "*"))) {
+			http.NotFound(w, r)
+			return
+		}
 		fs.ServeHTTP(w, r)
 	}))
 }
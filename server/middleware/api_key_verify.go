@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/str"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	apiHandler "kriyapeople/server/handler"
+	"kriyapeople/usecase"
+)
+
+const (
+	// HeaderAPIKey ...
+	HeaderAPIKey = "X-API-Key"
+)
+
+// clientIP remote address of the request. When API_KEY_TRUST_FORWARD_HEADER is set the address is read from
+// X-Forwarded-For, counted from the right by API_KEY_TRUSTED_PROXY_COUNT (default 1) because each trusted proxy
+// append the address it sees and the entries before are written by the client. X-Real-IP is not read
+func (m VerifyPermissionInit) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !str.StringToBool(m.ContractUC.EnvConfig["API_KEY_TRUST_FORWARD_HEADER"]) {
+		return host
+	}
+
+	var forwarded []string
+	for _, header := range r.Header["X-Forwarded-For"] {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	proxyCount := str.StringToInt(m.ContractUC.EnvConfig["API_KEY_TRUSTED_PROXY_COUNT"])
+	if proxyCount < 1 {
+		proxyCount = 1
+	}
+	if len(forwarded) < proxyCount {
+		return host
+	}
+	ip := net.ParseIP(strings.TrimSpace(forwarded[len(forwarded)-proxyCount]))
+	if ip == nil {
+		return host
+	}
+
+	return ip.String()
+}
+
+// verifyAPIKey ...
+func (m VerifyPermissionInit) verifyAPIKey(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		apiKeyUc := usecase.APIKeyUC{ContractUC: m.ContractUC}
+		key, err := apiKeyUc.Verify(r.Header.Get(HeaderAPIKey), m.clientIP(r), m.Menu, time.Now())
+		if err != nil {
+			code := 401
+			if err.Error() == helper.InsufficientScope || err.Error() == helper.ForbiddenIP {
+				code = 403
+			}
+			apiHandler.RespondWithJSON(w, code, code, err.Error(), []map[string]interface{}{}, []map[string]interface{}{})
+			return
+		}
+
+		ctx := userContextInterface(r.Context(), r, "api_key", map[string]interface{}{
+			"id":     key.ID,
+			"name":   key.Name,
+			"scopes": key.Scopes,
+			"role":   "api_key",
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// VerifyCredential request with api key header need the scope in Menu, any other request need the admin token
+// of the role mapped to the scope
+func (m VerifyPermissionInit) VerifyCredential(next http.Handler) http.Handler {
+	apiKey := m.verifyAPIKey(next)
+	mJwt := VerifyMiddlewareInit{ContractUC: m.ContractUC}
+	token := mJwt.VerifyAdminTokenCredential(next)
+	if usecase.APIKeyScopes[m.Menu] != model.RoleCodeAdmin {
+		token = mJwt.VerifySuperadminTokenCredential(next)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderAPIKey) != "" {
+			apiKey.ServeHTTP(w, r)
+			return
+		}
+
+		token.ServeHTTP(w, r)
+	})
+}
+
+// VerifySignatureOrCredential signed partner request is accepted too, see VerifyCredential for the rest
+func (m VerifyPermissionInit) VerifySignatureOrCredential(next http.Handler) http.Handler {
+	mJwt := VerifyMiddlewareInit{ContractUC: m.ContractUC}
+	signed := mJwt.VerifySignatureCredential(next)
+	credential := m.VerifyCredential(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderClientID) != "" {
+			signed.ServeHTTP(w, r)
+			return
+		}
+
+		credential.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"kriyapeople/usecase"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	cases := map[string]struct {
+		trust      string
+		proxyCount string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		"forward header not trusted":  {trust: "false", forwarded: []string{"203.0.113.7"}, want: "192.0.2.1"},
+		"one proxy":                   {trust: "true", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		"forged leading entry":        {trust: "true", forwarded: []string{"10.0.0.1, 203.0.113.7"}, want: "203.0.113.7"},
+		"forged header line":          {trust: "true", forwarded: []string{"10.0.0.1", "203.0.113.7"}, want: "203.0.113.7"},
+		"two proxies":                 {trust: "true", proxyCount: "2", forwarded: []string{"10.0.0.1, 203.0.113.7, 198.51.100.2"}, want: "203.0.113.7"},
+		"less entries than proxies":   {trust: "true", proxyCount: "2", forwarded: []string{"203.0.113.7"}, want: "192.0.2.1"},
+		"invalid entry":               {trust: "true", forwarded: []string{"10.0.0.1, unknown"}, want: "192.0.2.1"},
+		"missing forward header":      {trust: "true", want: "192.0.2.1"},
+		"real ip header is not read":  {trust: "true", realIP: "10.0.0.1", want: "192.0.2.1"},
+		"real ip with forward header": {trust: "true", forwarded: []string{"203.0.113.7"}, realIP: "10.0.0.1", want: "203.0.113.7"},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			m := VerifyPermissionInit{ContractUC: &usecase.ContractUC{EnvConfig: map[string]string{
+				"API_KEY_TRUST_FORWARD_HEADER": c.trust,
+				"API_KEY_TRUSTED_PROXY_COUNT":  c.proxyCount,
+			}}}
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.RemoteAddr = "192.0.2.1:41234"
+			for _, header := range c.forwarded {
+				r.Header.Add("X-Forwarded-For", header)
+			}
+			if c.realIP != "" {
+				r.Header.Set("X-Real-IP", c.realIP)
+			}
+
+			if ip := m.clientIP(r); ip != c.want {
+				t.Fatalf("expected %s, got %s", c.want, ip)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"kriyapeople/helper"
+	"kriyapeople/pkg/aesfront"
+	"kriyapeople/pkg/str"
+	"net/http"
+	"strconv"
+	"strings"
+
+	apiHandler "kriyapeople/server/handler"
+	"kriyapeople/usecase"
+)
+
+const (
+	// HeaderEncryptedPayload opt in the encrypted payload mode, ex: X-Encrypted-Payload: true
+	HeaderEncryptedPayload = "X-Encrypted-Payload"
+	// HeaderEncryptionSession id of the encryption session, the static AES_FRONT_KEY and AES_FRONT_IV is used without it
+	HeaderEncryptionSession = "X-Encryption-Session"
+)
+
+var (
+	// MaxEncryptedBody ...
+	MaxEncryptedBody int64 = 10 << 20
+)
+
+// EncryptedPayloadInit ...
+type EncryptedPayloadInit struct {
+	*usecase.ContractUC
+}
+
+// encryptedResponseWriter keep the response to encrypt it whole after the handler is done
+type encryptedResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader ...
+func (w *encryptedResponseWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+// Write ...
+func (w *encryptedResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return w.body.Write(b)
+}
+
+// credential ...
+func (m EncryptedPayloadInit) credential(r *http.Request) (res aesfront.Credential, err error) {
+	session := r.Header.Get(HeaderEncryptionSession)
+	if session == "" {
+		return m.ContractUC.AesFront, err
+	}
+
+	encryptionSessionUc := usecase.EncryptionSessionUC{ContractUC: m.ContractUC}
+	return encryptionSessionUc.Credential(session)
+}
+
+// EncryptedPayload request with the encrypted payload header has base64 ciphertext as body, it is decrypted
+// before the handler bind it. The response is encrypted back as base64 ciphertext with the same status code.
+// Multipart body is not encrypted, only its response is
+func (m EncryptedPayloadInit) EncryptedPayload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !str.StringToBool(r.Header.Get(HeaderEncryptedPayload)) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cred, err := m.credential(r)
+		if err != nil {
+			apiHandler.RespondWithJSON(w, 401, 401, err.Error(), []map[string]interface{}{}, []map[string]interface{}{})
+			return
+		}
+
+		if r.Body != nil && !strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			body, err := ioutil.ReadAll(io.LimitReader(r.Body, MaxEncryptedBody+1))
+			r.Body.Close()
+			if err != nil || int64(len(body)) > MaxEncryptedBody {
+				apiHandler.SendBadRequest(w, helper.InvalidEncryptedPayload)
+				return
+			}
+			plaintext, err := cred.Decrypt(strings.TrimSpace(string(body)))
+			if err != nil {
+				apiHandler.SendBadRequest(w, helper.InvalidEncryptedPayload)
+				return
+			}
+			r.Body = ioutil.NopCloser(strings.NewReader(plaintext))
+			r.ContentLength = int64(len(plaintext))
+			r.Header.Set("Content-Length", strconv.Itoa(len(plaintext)))
+			r.Header.Set("Content-Type", "application/json")
+		}
+
+		ew := &encryptedResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(ew, r)
+		if ew.status == 0 {
+			ew.status = http.StatusOK
+		}
+
+		res, err := cred.Encrypt(ew.body.String())
+		if err != nil {
+			apiHandler.RespondWithJSON(w, 500, 500, helper.InternalServer, []map[string]interface{}{}, []map[string]interface{}{})
+			return
+		}
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set(HeaderEncryptedPayload, "true")
+		w.WriteHeader(ew.status)
+		w.Write([]byte(res))
+	})
+}
+
+// RequireEncryptedPayload reject request without the encrypted payload header
+func (m EncryptedPayloadInit) RequireEncryptedPayload(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !str.StringToBool(r.Header.Get(HeaderEncryptedPayload)) {
+			apiHandler.SendBadRequest(w, helper.EncryptedPayloadRequired)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
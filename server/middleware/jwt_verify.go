@@ -3,23 +3,17 @@ package middleware
 import (
 	"context"
 	"errors"
-	"fmt"
 	"kriyapeople/model"
+	"kriyapeople/pkg/jwt"
+	"regexp"
 	"strings"
 
 	"net/http"
-	"time"
-
-	jwt "github.com/dgrijalva/jwt-go"
 
 	apiHandler "kriyapeople/server/handler"
 	"kriyapeople/usecase"
 )
 
-type jwtClaims struct {
-	jwt.StandardClaims
-}
-
 // VerifyMiddlewareInit ...
 type VerifyMiddlewareInit struct {
 	*usecase.ContractUC
@@ -31,40 +25,86 @@ type VerifyPermissionInit struct {
 	Menu string
 }
 
-func userContextInterface(ctx context.Context, req *http.Request, subject string, body map[string]interface{}) context.Context {
-	return context.WithValue(ctx, subject, body)
-}
+var (
+	// bearerRegex b64token of RFC 6750 section 2.1
+	bearerRegex = regexp.MustCompile(`^[A-Za-z0-9\-._~+/]+=*$`)
 
-func (m VerifyMiddlewareInit) verifyJWT(r *http.Request, role string, singleLogin bool) (res map[string]interface{}, err error) {
-	claims := &jwtClaims{}
+	errMissingBearer   = errors.New("Missing Token!")
+	errMalformedBearer = errors.New("Malformed Authorization Header!")
+)
 
-	tokenAuthHeader := r.Header.Get("Authorization")
-	if !strings.Contains(tokenAuthHeader, "Bearer") {
-		return res, errors.New("Invalid token")
+// bearerToken read the token of "Authorization: Bearer [token]", the scheme is case insensitive
+func bearerToken(r *http.Request) (res string, err error) {
+	headers := r.Header["Authorization"]
+	if len(headers) == 0 {
+		return res, errMissingBearer
+	}
+	if len(headers) > 1 {
+		return res, errMalformedBearer
 	}
-	tokenAuth := strings.Replace(tokenAuthHeader, "Bearer ", "", -1)
 
-	_, err = jwt.ParseWithClaims(tokenAuth, claims, func(token *jwt.Token) (interface{}, error) {
-		if jwt.SigningMethodHS256 != token.Method {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
+	parts := strings.SplitN(headers[0], " ", 2)
+	if !strings.EqualFold(parts[0], "Bearer") {
+		return res, errMissingBearer
+	}
+	if len(parts) != 2 {
+		return res, errMalformedBearer
+	}
+	// RFC 6750 allow 1*SP between the scheme and the token
+	token := strings.TrimLeft(parts[1], " ")
+	if !bearerRegex.MatchString(token) {
+		return res, errMalformedBearer
+	}
 
-		secret := m.ContractUC.EnvConfig["TOKEN_SECRET"]
-		return []byte(secret), nil
-	})
+	return token, nil
+}
+
+// respondBearerError respond with the WWW-Authenticate challenge of RFC 6750 section 3
+func (m VerifyMiddlewareInit) respondBearerError(w http.ResponseWriter, err error, code string) {
+	challenge := `Bearer realm="` + m.ContractUC.Jwt.Issuer + `"`
+	status := http.StatusUnauthorized
+	switch {
+	case err == errMissingBearer:
+	case err == errMalformedBearer:
+		challenge += `, error="invalid_request"`
+		status = http.StatusBadRequest
+	case code == "insufficient_scope":
+		challenge += `, error="insufficient_scope"`
+		status = http.StatusForbidden
+	default:
+		challenge += `, error="invalid_token"`
+	}
+
+	w.Header().Set("WWW-Authenticate", challenge)
+	apiHandler.RespondWithJSON(w, status, status, err.Error(), []map[string]interface{}{}, []map[string]interface{}{})
+}
+
+func userContextInterface(ctx context.Context, req *http.Request, subject string, body map[string]interface{}) context.Context {
+	return context.WithValue(ctx, subject, body)
+}
+
+func (m VerifyMiddlewareInit) verifyJWT(r *http.Request, role string, singleLogin bool) (res map[string]interface{}, err error) {
+	tokenAuth, err := bearerToken(r)
 	if err != nil {
-		return res, errors.New("Invalid Token!")
+		return res, err
 	}
 
-	if claims.ExpiresAt < time.Now().Unix() {
+	claims, err := m.ContractUC.Jwt.Parse(tokenAuth, jwt.TypeAccess)
+	if err == jwt.ErrExpired {
 		return res, errors.New("Expired Token!")
 	}
+	if err != nil {
+		return res, errors.New("Invalid Token!")
+	}
 
 	// Decrypt payload
-	res, err = m.ContractUC.Jwe.Rollback(claims.Id)
+	res, err = m.ContractUC.Jwe.Rollback(claims.Payload)
 	if err != nil {
 		return res, errors.New("Error when load the payload!")
 	}
+	if res["id"] != claims.Subject {
+		return res, errors.New("Invalid Token!")
+	}
 
 	// Check if the token provided has a valid role
 	if res["role"] == nil {
@@ -74,6 +114,15 @@ func (m VerifyMiddlewareInit) verifyJWT(r *http.Request, role string, singleLogi
 		return res, errors.New("Not an " + role + " token!")
 	}
 
+	// Check if the session is not revoked
+	userID, _ := res["id"].(string)
+	deviceID, _ := res["device_id"].(string)
+	jwtUc := usecase.JwtUC{ContractUC: m.ContractUC}
+	err = jwtUc.CheckSession(userID, deviceID)
+	if err != nil {
+		return res, errors.New("Expired Session!")
+	}
+
 	if singleLogin && role == "user" {
 		var deviceID string
 		err = m.ContractUC.GetFromRedis("userDeviceID"+res["id"].(string), &deviceID)
@@ -89,35 +138,27 @@ func (m VerifyMiddlewareInit) verifyJWT(r *http.Request, role string, singleLogi
 }
 
 func (m VerifyMiddlewareInit) verifyRefreshJWT(r *http.Request, role string) (res map[string]interface{}, err error) {
-	claims := &jwtClaims{}
-
-	tokenAuthHeader := r.Header.Get("Authorization")
-	if !strings.Contains(tokenAuthHeader, "Bearer") {
-		return res, errors.New("Invalid token")
-	}
-	tokenAuth := strings.Replace(tokenAuthHeader, "Bearer ", "", -1)
-
-	_, err = jwt.ParseWithClaims(tokenAuth, claims, func(token *jwt.Token) (interface{}, error) {
-		if jwt.SigningMethodHS256 != token.Method {
-			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-		}
-
-		secret := m.ContractUC.EnvConfig["TOKEN_REFRESH_SECRET"]
-		return []byte(secret), nil
-	})
+	tokenAuth, err := bearerToken(r)
 	if err != nil {
-		return res, errors.New("Invalid Token!")
+		return res, err
 	}
 
-	if claims.ExpiresAt < time.Now().Unix() {
+	claims, err := m.ContractUC.Jwt.Parse(tokenAuth, jwt.TypeRefresh)
+	if err == jwt.ErrExpired {
 		return res, errors.New("Expired Token!")
 	}
+	if err != nil {
+		return res, errors.New("Invalid Token!")
+	}
 
 	// Decrypt payload
-	res, err = m.ContractUC.Jwe.Rollback(claims.Id)
+	res, err = m.ContractUC.Jwe.Rollback(claims.Payload)
 	if err != nil {
 		return res, errors.New("Error when load the payload!")
 	}
+	if res["id"] != claims.Subject {
+		return res, errors.New("Invalid Token!")
+	}
 
 	// Check if the token provided has a valid role
 	if res["role"] == nil {
@@ -135,7 +176,7 @@ func (m VerifyMiddlewareInit) VerifySuperadminTokenCredential(next http.Handler)
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		jweRes, err := m.verifyJWT(r, "admin", false)
 		if err != nil {
-			apiHandler.RespondWithJSON(w, 401, 401, err.Error(), []map[string]interface{}{}, []map[string]interface{}{})
+			m.respondBearerError(w, err, "invalid_token")
 			return
 		}
 
@@ -143,11 +184,11 @@ func (m VerifyMiddlewareInit) VerifySuperadminTokenCredential(next http.Handler)
 		adminUc := usecase.AdminUC{ContractUC: m.ContractUC}
 		admin, err := adminUc.FindByID(jweRes["id"].(string), false)
 		if admin.ID == "" {
-			apiHandler.RespondWithJSON(w, 401, 401, "Not found!", []map[string]interface{}{}, []map[string]interface{}{})
+			m.respondBearerError(w, errors.New("Not found!"), "invalid_token")
 			return
 		}
 		if admin.RoleName != model.RoleCodeSuperadmin {
-			apiHandler.RespondWithJSON(w, 401, 401, "only admin can access", []map[string]interface{}{}, []map[string]interface{}{})
+			m.respondBearerError(w, errors.New("only admin can access"), "insufficient_scope")
 			return
 		}
 
@@ -164,7 +205,7 @@ func (m VerifyMiddlewareInit) VerifyAdminTokenCredential(next http.Handler) http
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		jweRes, err := m.verifyJWT(r, "admin", false)
 		if err != nil {
-			apiHandler.RespondWithJSON(w, 401, 401, err.Error(), []map[string]interface{}{}, []map[string]interface{}{})
+			m.respondBearerError(w, err, "invalid_token")
 			return
 		}
 
@@ -172,7 +213,7 @@ func (m VerifyMiddlewareInit) VerifyAdminTokenCredential(next http.Handler) http
 		adminUc := usecase.AdminUC{ContractUC: m.ContractUC}
 		admin, err := adminUc.FindByID(jweRes["id"].(string), false)
 		if admin.ID == "" {
-			apiHandler.RespondWithJSON(w, 401, 401, "Not found!", []map[string]interface{}{}, []map[string]interface{}{})
+			m.respondBearerError(w, errors.New("Not found!"), "invalid_token")
 			return
 		}
 
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"errors"
+	"kriyapeople/pkg/jwt"
+	"kriyapeople/usecase"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBearerToken(t *testing.T) {
+	cases := map[string]struct {
+		headers []string
+		token   string
+		wantErr error
+	}{
+		"bearer":                {headers: []string{"Bearer abc.DEF-ghi_~+/"}, token: "abc.DEF-ghi_~+/"},
+		"padded token":          {headers: []string{"Bearer abc=="}, token: "abc=="},
+		"lowercase scheme":      {headers: []string{"bearer abc"}, token: "abc"},
+		"uppercase scheme":      {headers: []string{"BEARER abc"}, token: "abc"},
+		"missing header":        {wantErr: errMissingBearer},
+		"basic scheme":          {headers: []string{"Basic dXNlcjpwYXNz"}, wantErr: errMissingBearer},
+		"token without scheme":  {headers: []string{"abc"}, wantErr: errMissingBearer},
+		"multiple headers":      {headers: []string{"Bearer abc", "Bearer def"}, wantErr: errMalformedBearer},
+		"scheme only":           {headers: []string{"Bearer"}, wantErr: errMalformedBearer},
+		"empty token":           {headers: []string{"Bearer "}, wantErr: errMalformedBearer},
+		"double space":          {headers: []string{"Bearer  abc"}, token: "abc"},
+		"token with space":      {headers: []string{"Bearer abc def"}, wantErr: errMalformedBearer},
+		"padding inside token":  {headers: []string{"Bearer ab=c"}, wantErr: errMalformedBearer},
+		"invalid b64token char": {headers: []string{"Bearer abc,def"}, wantErr: errMalformedBearer},
+		"quoted token":          {headers: []string{`Bearer "abc"`}, wantErr: errMalformedBearer},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			for _, header := range c.headers {
+				r.Header.Add("Authorization", header)
+			}
+
+			token, err := bearerToken(r)
+			if err != c.wantErr {
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+			if token != c.token {
+				t.Fatalf("expected token %q, got %q", c.token, token)
+			}
+		})
+	}
+}
+
+func TestRespondBearerError(t *testing.T) {
+	m := VerifyMiddlewareInit{ContractUC: &usecase.ContractUC{Jwt: jwt.Credential{Issuer: "kriyapeople"}}}
+
+	cases := map[string]struct {
+		err       error
+		code      string
+		status    int
+		challenge string
+	}{
+		"missing token": {
+			err: errMissingBearer, code: "invalid_token",
+			status: http.StatusUnauthorized, challenge: `Bearer realm="kriyapeople"`,
+		},
+		"malformed header": {
+			err: errMalformedBearer, code: "invalid_token",
+			status: http.StatusBadRequest, challenge: `Bearer realm="kriyapeople", error="invalid_request"`,
+		},
+		"invalid token": {
+			err: errors.New("Invalid Token!"), code: "invalid_token",
+			status: http.StatusUnauthorized, challenge: `Bearer realm="kriyapeople", error="invalid_token"`,
+		},
+		"insufficient scope": {
+			err: errors.New("only admin can access"), code: "insufficient_scope",
+			status: http.StatusForbidden, challenge: `Bearer realm="kriyapeople", error="insufficient_scope"`,
+		},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			m.respondBearerError(w, c.err, c.code)
+
+			if w.Code != c.status {
+				t.Fatalf("expected status %d, got %d", c.status, w.Code)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); challenge != c.challenge {
+				t.Fatalf("expected challenge %s, got %s", c.challenge, challenge)
+			}
+		})
+	}
+}
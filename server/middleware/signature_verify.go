@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"kriyapeople/helper"
+	"net/http"
+	"strconv"
+	"time"
+
+	apiHandler "kriyapeople/server/handler"
+	"kriyapeople/usecase"
+)
+
+const (
+	// HeaderClientID partner client id
+	HeaderClientID = "X-Client-ID"
+	// HeaderTimestamp unix second when the request is signed
+	HeaderTimestamp = "X-Timestamp"
+	// HeaderNonce random value used once per request
+	HeaderNonce = "X-Nonce"
+	// HeaderSignature hex hmac sha256 of the canonical request
+	HeaderSignature = "X-Signature"
+)
+
+var (
+	// MaxSignedBody the body is read whole to verify its hash
+	MaxSignedBody int64 = 10 << 20
+	// MinNonceLength ...
+	MinNonceLength = 16
+	// MaxNonceLength ...
+	MaxNonceLength = 128
+)
+
+func (m VerifyMiddlewareInit) verifySignature(r *http.Request) (res map[string]interface{}, err error) {
+	clientID := r.Header.Get(HeaderClientID)
+	signature := r.Header.Get(HeaderSignature)
+	nonce := r.Header.Get(HeaderNonce)
+	timestamp, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if clientID == "" || signature == "" || err != nil {
+		return res, errors.New(helper.InvalidSignature)
+	}
+	if len(nonce) < MinNonceLength || len(nonce) > MaxNonceLength {
+		return res, errors.New(helper.InvalidSignature)
+	}
+
+	// Put the body back for the handler after it is hashed
+	body := []byte{}
+	if r.Body != nil {
+		body, err = ioutil.ReadAll(io.LimitReader(r.Body, MaxSignedBody+1))
+		r.Body.Close()
+		if err != nil || int64(len(body)) > MaxSignedBody {
+			return res, errors.New(helper.InvalidSignature)
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	partnerClientUc := usecase.PartnerClientUC{ContractUC: m.ContractUC}
+	client, err := partnerClientUc.Verify(usecase.SignedRequest{
+		ClientID:  clientID,
+		Signature: signature,
+		Timestamp: timestamp,
+		Nonce:     nonce,
+		Method:    r.Method,
+		Path:      r.URL.RequestURI(),
+		Body:      body,
+	}, time.Now())
+	if err != nil {
+		return res, err
+	}
+
+	res = map[string]interface{}{
+		"id":        client.ID,
+		"client_id": client.ClientID,
+		"name":      client.Name,
+		"role":      "partner",
+	}
+
+	return res, nil
+}
+
+// VerifySignatureCredential accept only request signed by active partner client
+func (m VerifyMiddlewareInit) VerifySignatureCredential(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientRes, err := m.verifySignature(r)
+		if err != nil {
+			apiHandler.RespondWithJSON(w, 401, 401, err.Error(), []map[string]interface{}{}, []map[string]interface{}{})
+			return
+		}
+
+		ctx := userContextInterface(r.Context(), r, "partner", clientRes)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -23,6 +23,7 @@ type UserDataRequest struct {
 	Status   StatusRequest `json:"status"`
 	Password string        `json:"password"`
 	UserName string        `json:"username"`
+	Language string        `json:"language,omitempty" validate:"omitempty,oneof=en id"`
 }
 
 // StatusRequest ...
@@ -35,3 +36,27 @@ type UserLoginRequest struct {
 	Email    string `json:"email" validate:"email"`
 	Password string `json:"password" validate:"required"`
 }
+
+// MeRequest ...
+type MeRequest struct {
+	UserName string `json:"username" validate:"required"`
+	Language string `json:"language" validate:"omitempty,oneof=en id"`
+}
+
+// ChangePasswordRequest ...
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=500"`
+}
+
+// ChangeEmailRequest ...
+type ChangeEmailRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+// ProfileImageRequest ...
+type ProfileImageRequest struct {
+	ProfileImageID  string `json:"profile_image_id" validate:"required_without=ProfileImageURL"`
+	ProfileImageURL string `json:"profile_image_url" validate:"omitempty,url,max=2000"`
+}
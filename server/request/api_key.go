@@ -0,0 +1,9 @@
+package request
+
+// APIKeyRequest expires_at is RFC3339, allowed_ips accept ip or cidr, empty means any ip
+type APIKeyRequest struct {
+	Name       string   `json:"name" validate:"required,max=255"`
+	Scopes     []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt  string   `json:"expires_at"`
+	AllowedIPs []string `json:"allowed_ips" validate:"max=50,dive,ip|cidr"`
+}
@@ -0,0 +1,14 @@
+package request
+
+// FileBase64Request ...
+type FileBase64Request struct {
+	Type        string `json:"type" validate:"required"`
+	File        string `json:"file" validate:"required"`
+	ContentType string `json:"content_type"`
+}
+
+// FileURLRequest ...
+type FileURLRequest struct {
+	Type string `json:"type" validate:"required"`
+	URL  string `json:"url" validate:"required,url,max=2000"`
+}
@@ -0,0 +1,11 @@
+package request
+
+// PartnerClientRequest ...
+type PartnerClientRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+// PartnerClientStatusRequest ...
+type PartnerClientStatusRequest struct {
+	IsActive bool `json:"is_active"`
+}
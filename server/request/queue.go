@@ -0,0 +1,12 @@
+package request
+
+// DeadLetterReplayRequest ...
+type DeadLetterReplayRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=1000"`
+}
+
+// DeadLetterPurgeRequest every message is purged when all is true
+type DeadLetterPurgeRequest struct {
+	IDs []string `json:"ids" validate:"required_without=All,max=1000"`
+	All bool     `json:"all"`
+}
@@ -0,0 +1,9 @@
+package request
+
+// WebhookEndpointRequest events is the list of subscribed event types, ex: user.created
+type WebhookEndpointRequest struct {
+	URL         string   `json:"url" validate:"required,url,max=2000"`
+	Events      []string `json:"events" validate:"required,min=1,dive,required"`
+	Description string   `json:"description" validate:"max=255"`
+	IsActive    bool     `json:"is_active"`
+}
@@ -1,10 +1,14 @@
 package usecase
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"kriyapeople/helper"
 	"kriyapeople/model"
+	"kriyapeople/pkg/amqp"
 	"kriyapeople/pkg/bcrypt"
+	"kriyapeople/pkg/event"
 	"kriyapeople/pkg/interfacepkg"
 	"kriyapeople/pkg/logruslogger"
 	"kriyapeople/pkg/str"
@@ -14,6 +18,13 @@ import (
 	"time"
 )
 
+var (
+	// ChangeEmailRedisKey ...
+	ChangeEmailRedisKey = "changeEmail"
+	// ChangeEmailExp ...
+	ChangeEmailExp = "24h"
+)
+
 // AdminUC ...
 type AdminUC struct {
 	*ContractUC
@@ -22,13 +33,20 @@ type AdminUC struct {
 // BuildBody ...
 func (uc AdminUC) BuildBody(data *model.UserEntity, res *viewmodel.UserVM, isShowPassword bool) {
 
+	piiUc := UserPIIUC{ContractUC: uc.ContractUC}
 	res.ID = data.ID
-	res.Information.UserName = data.UserName.String
-	res.Information.Email = data.Email.String
+	res.Information.UserName = piiUc.Decrypt(data.UserName, data.UserNameEnc)
+	res.Information.Email = piiUc.Decrypt(data.Email, data.EmailEnc)
+	res.Information.Language = data.Language.String
 	res.Information.Password = str.ShowString(isShowPassword, data.Password.String)
 	res.RoleID = data.RoleID.String
 	res.RoleName = data.Role.Name.String
 	res.Information.Status.IsActive = data.Status.Bool
+	res.ProfileImageID = data.ProfileImageID.String
+	if data.ProfileImageStatus.String == model.FileStatusProcessed {
+		fileUc := FileUC{ContractUC: uc.ContractUC}
+		res.ProfileImageURL = fileUc.GetURL(model.FileAdminProfile, data.ProfileImageURL.String)
+	}
 	res.CreatedAt = data.CreatedAt
 	res.UpdatedAt = data.UpdatedAt
 	res.DeletedAt = data.DeletedAt.String
@@ -46,17 +64,20 @@ func (uc AdminUC) Login(data request.UserLoginRequest) (res viewmodel.JwtVM, err
 	admin, err := uc.FindByEmail(data.Email, true)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_by_email", uc.ReqID)
+		uc.addLoginFailedEvent("", helper.InvalidCredentials)
 		return res, errors.New(helper.InvalidCredentials)
 	}
 
 	isMatch := bcrypt.CheckPasswordHash(data.Password, admin.Information.Password)
 	if !isMatch {
 		logruslogger.Log(logruslogger.WarnLevel, "", ctx, "invalid_password", uc.ReqID)
+		uc.addLoginFailedEvent(admin.ID, helper.InvalidCredentials)
 		return res, errors.New(helper.InvalidCredentials)
 	}
 
 	if !admin.Information.Status.IsActive {
 		logruslogger.Log(logruslogger.WarnLevel, "", ctx, "inactive_admin", uc.ReqID)
+		uc.addLoginFailedEvent(admin.ID, helper.InactiveAdmin)
 		return res, errors.New(helper.InactiveAdmin)
 	}
 
@@ -72,7 +93,20 @@ func (uc AdminUC) Login(data request.UserLoginRequest) (res viewmodel.JwtVM, err
 		return res, errors.New(helper.InternalServer)
 	}
 
-	return res, err
+	uc.addEvent(nil, event.UserLoginSucceeded, event.UserLoginSucceededData{
+		ID:       admin.ID,
+		DeviceID: interfacepkg.InterfaceStringToString(payload, "device_id"),
+	})
+
+	return res, nil
+}
+
+// addLoginFailedEvent id is empty when the email is not found
+func (uc AdminUC) addLoginFailedEvent(id, reason string) {
+	uc.addEvent(nil, event.UserLoginFailed, event.UserLoginFailedData{
+		ID:     id,
+		Reason: reason,
+	})
 }
 
 // FindAll ...
@@ -89,8 +123,10 @@ func (uc AdminUC) FindAll(search string, page, limit int, by, sort string) (res
 	limit = uc.LimitMax(limit)
 	limit, offset := uc.PaginationPageOffset(page, limit)
 
+	piiUc := UserPIIUC{ContractUC: uc.ContractUC}
 	m := model.NewAdminModel(uc.DB)
-	data, count, err := m.FindAll(search, offset, limit, by, sort)
+	data, count, err := m.FindAll(search, piiUc.BlindIndex("email", search), piiUc.BlindIndex("username", search),
+		offset, limit, by, sort)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
 		return res, pagination, err
@@ -125,8 +161,9 @@ func (uc AdminUC) FindByID(id string, isShowPassword bool) (res viewmodel.UserVM
 func (uc AdminUC) FindByEmail(email string, isShowPassword bool) (res viewmodel.UserVM, err error) {
 	ctx := "AdminUC.FindByEmail"
 
+	piiUc := UserPIIUC{ContractUC: uc.ContractUC}
 	m := model.NewAdminModel(uc.DB)
-	data, err := m.FindByEmail(email)
+	data, err := m.FindByEmail(email, piiUc.BlindIndex("email", email))
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
 		return res, err
@@ -180,18 +217,36 @@ func (uc AdminUC) Create(data *request.UserRequest) (res viewmodel.UserVM, err e
 	information := viewmodel.UserDataVM{
 		UserName: data.Information.UserName,
 		Email:    data.Information.Email,
+		Language: data.Information.Language,
+	}
+
+	body, err := uc.encryptData(data)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "encrypt_data", uc.ReqID)
+		return res, err
 	}
 
 	now := time.Now().UTC()
 	res = viewmodel.UserVM{
 		RoleID:      data.RoleID,
 		Information: information,
-		Data:        interfacepkg.Marshall(data.Information),
+		Data:        body,
 		CreatedAt:   now.Format(time.RFC3339),
 		UpdatedAt:   now.Format(time.RFC3339),
 	}
-	m := model.NewAdminModel(uc.DB)
-	res.ID, err = m.Store(res, now)
+	err = uc.WithTx(func(tx *sql.Tx) (err error) {
+		m := model.NewAdminModelTx(tx)
+		res.ID, err = m.Store(res, now)
+		if err != nil {
+			return err
+		}
+
+		return uc.addEvent(tx, event.UserCreated, event.UserData{
+			ID:       res.ID,
+			RoleID:   data.RoleID,
+			IsActive: data.Information.Status.IsActive,
+		})
+	})
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
 		return res, err
@@ -210,26 +265,62 @@ func (uc AdminUC) Update(id string, data *request.UserRequest) (res viewmodel.Us
 		return res, err
 	}
 
+	passwordChanged := data.Information.Password != ""
 	err = uc.CheckDetails(data, &oldData)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "check_details", uc.ReqID)
 		return res, err
 	}
 
+	changes := event.NewUserUpdatedData(id)
+	changes.Diff("email", oldData.Information.Email, data.Information.Email, true)
+	changes.Diff("username", oldData.Information.UserName, data.Information.UserName, true)
+	changes.Diff("password", false, passwordChanged, true)
+	changes.Diff("role_id", oldData.RoleID, data.RoleID, false)
+	changes.Diff("is_active", oldData.Information.Status.IsActive, data.Information.Status.IsActive, false)
+
+	// Language is kept when it is not sent
+	if data.Information.Language == "" {
+		data.Information.Language = oldData.Information.Language
+	}
+	changes.Diff("language", oldData.Information.Language, data.Information.Language, false)
+
 	information := viewmodel.UserDataVM{
 		UserName: data.Information.UserName,
 		Email:    data.Information.Email,
+		Language: data.Information.Language,
+	}
+
+	body, err := uc.encryptData(data)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "encrypt_data", uc.ReqID)
+		return res, err
 	}
 
 	now := time.Now().UTC()
 	res = viewmodel.UserVM{
 		RoleID:      data.RoleID,
 		Information: information,
-		Data:        interfacepkg.Marshall(data.Information),
+		Data:        body,
 		UpdatedAt:   now.Format(time.RFC3339),
 	}
-	m := model.NewAdminModel(uc.DB)
-	res.ID, err = m.Update(id, res, now)
+	err = uc.WithTx(func(tx *sql.Tx) (err error) {
+		m := model.NewAdminModelTx(tx)
+		res.ID, err = m.Update(id, res, now)
+		if err != nil {
+			return err
+		}
+
+		if oldData.RoleID != data.RoleID {
+			roleUc := RoleUC{ContractUC: uc.ContractUC}
+			err = roleUc.AddChangedEvent(tx, id, oldData.RoleID, data.RoleID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return uc.addEvent(tx, event.UserUpdated, changes)
+	})
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
 		return res, err
@@ -243,12 +334,263 @@ func (uc AdminUC) Delete(id string) (res viewmodel.UserVM, err error) {
 	ctx := "AdminUC.Delete"
 
 	now := time.Now().UTC()
-	m := model.NewAdminModel(uc.DB)
-	res.ID, err = m.Destroy(id, now)
+	err = uc.WithTx(func(tx *sql.Tx) (err error) {
+		m := model.NewAdminModelTx(tx)
+		res.ID, err = m.Destroy(id, now)
+		if err != nil {
+			return err
+		}
+
+		return uc.addEvent(tx, event.UserDeleted, event.UserDeletedData{ID: res.ID})
+	})
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return res, err
+}
+
+// addEvent write the domain event into outbox inside the transaction
+func (uc AdminUC) addEvent(tx *sql.Tx, types string, data interface{}) error {
+	outboxUc := OutboxUC{ContractUC: uc.ContractUC}
+
+	return outboxUc.AddEvent(tx, event.New(types, data, time.Now()))
+}
+
+// encryptData data json of the request with the pii fields encrypted
+func (uc AdminUC) encryptData(data *request.UserRequest) (res string, err error) {
+	piiUc := UserPIIUC{ContractUC: uc.ContractUC}
+	body, err := piiUc.Encrypt(interfacepkg.UnmarshallMap(interfacepkg.Marshall(data.Information)))
+	if err != nil {
+		return res, err
+	}
+
+	return interfacepkg.MarshallMap(body), err
+}
+
+// updateData merge the data and write user.updated event in one transaction
+func (uc AdminUC) updateData(id string, body map[string]interface{}, changes *event.UserUpdatedData) error {
+	piiUc := UserPIIUC{ContractUC: uc.ContractUC}
+	body, err := piiUc.Encrypt(body)
+	if err != nil {
+		return err
+	}
+
+	return uc.WithTx(func(tx *sql.Tx) (err error) {
+		m := model.NewAdminModelTx(tx)
+		_, err = m.UpdateData(id, interfacepkg.Marshall(body), time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		return uc.addEvent(tx, event.UserUpdated, changes)
+	})
+}
+
+// UpdateMe update own account data of logged in admin
+func (uc AdminUC) UpdateMe(id string, data *request.MeRequest) (res viewmodel.UserVM, err error) {
+	ctx := "AdminUC.UpdateMe"
+
+	oldData, err := uc.FindByID(id, false)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_user", uc.ReqID)
+		return res, err
+	}
+
+	body := map[string]interface{}{
+		"username": data.UserName,
+	}
+	changes := event.NewUserUpdatedData(id)
+	changes.Diff("username", oldData.Information.UserName, data.UserName, true)
+	if data.Language != "" {
+		body["language"] = data.Language
+		changes.Diff("language", oldData.Information.Language, data.Language, false)
+	}
+	err = uc.updateData(id, body, changes)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return uc.FindByID(id, false)
+}
+
+// ChangePassword change own password and revoke every other session
+func (uc AdminUC) ChangePassword(id, deviceID string, data *request.ChangePasswordRequest) (res viewmodel.UserVM, err error) {
+	ctx := "AdminUC.ChangePassword"
+
+	admin, err := uc.FindByID(id, true)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_user", uc.ReqID)
+		return res, err
+	}
+	if !bcrypt.CheckPasswordHash(data.CurrentPassword, admin.Information.Password) {
+		logruslogger.Log(logruslogger.WarnLevel, id, ctx, "invalid_password", uc.ReqID)
+		return res, errors.New(helper.InvalidPassword)
+	}
+
+	password, err := bcrypt.HashPassword(data.NewPassword)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "encrypt_password", uc.ReqID)
+		return res, err
+	}
+	body := map[string]interface{}{
+		"password": password,
+	}
+	changes := event.NewUserUpdatedData(id)
+	changes.Diff("password", false, true, true)
+	err = uc.updateData(id, body, changes)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
 		return res, err
 	}
 
+	jwtUc := JwtUC{ContractUC: uc.ContractUC}
+	err = jwtUc.RevokeSessions(id, deviceID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "revoke_sessions", uc.ReqID)
+		return res, errors.New(helper.InternalServer)
+	}
+
+	return uc.FindByID(id, false)
+}
+
+// RequestChangeEmail send confirmation key to the new email, the email is changed after confirmed
+func (uc AdminUC) RequestChangeEmail(id string, data *request.ChangeEmailRequest) (res viewmodel.UserVM, err error) {
+	ctx := "AdminUC.RequestChangeEmail"
+
+	res, err = uc.FindByID(id, true)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_user", uc.ReqID)
+		return res, err
+	}
+	if !bcrypt.CheckPasswordHash(data.Password, res.Information.Password) {
+		logruslogger.Log(logruslogger.WarnLevel, id, ctx, "invalid_password", uc.ReqID)
+		return res, errors.New(helper.InvalidPassword)
+	}
+	res.Information.Password = ""
+
+	if strings.ToLower(data.Email) == strings.ToLower(res.Information.Email) {
+		logruslogger.Log(logruslogger.WarnLevel, data.Email, ctx, "same_email", uc.ReqID)
+		return res, errors.New(helper.SameEmail)
+	}
+	admin, _ := uc.FindByEmail(data.Email, false)
+	if admin.ID != "" {
+		logruslogger.Log(logruslogger.WarnLevel, data.Email, ctx, "duplicate_email", uc.ReqID)
+		return res, errors.New(helper.DuplicateEmail)
+	}
+
+	key := str.RandomSecureString(32)
+	redisBody := map[string]interface{}{
+		"id":    id,
+		"email": data.Email,
+	}
+	err = uc.StoreToRedisExp(ChangeEmailRedisKey+key, redisBody, ChangeEmailExp)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "store_key", uc.ReqID)
+		return res, errors.New(helper.InternalServer)
+	}
+
+	queueBody := map[string]interface{}{
+		"email":    data.Email,
+		"username": res.Information.UserName,
+		"language": res.Information.Language,
+		"key":      key,
+	}
+	err = uc.Publish(context.Background(), amqp.ChangeEmailMail, amqp.ChangeEmailMailDeadLetter, queueBody)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "push_queue", uc.ReqID)
+		return res, errors.New(helper.SendMail)
+	}
+
 	return res, err
 }
+
+// ConfirmChangeEmail switch the email after the new address is confirmed
+func (uc AdminUC) ConfirmChangeEmail(key string) (res viewmodel.UserVM, err error) {
+	ctx := "AdminUC.ConfirmChangeEmail"
+
+	// The key is consumed before the update, so the same key can not be applied twice
+	redisBody := map[string]interface{}{}
+	err = uc.PopFromRedis(ChangeEmailRedisKey+key, &redisBody)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "invalid_key", uc.ReqID)
+		return res, errors.New(helper.InvalidEmailKey)
+	}
+	id := interfacepkg.InterfaceStringToString(redisBody, "id")
+	email := interfacepkg.InterfaceStringToString(redisBody, "email")
+
+	admin, _ := uc.FindByEmail(email, false)
+	if admin.ID != "" && admin.ID != id {
+		logruslogger.Log(logruslogger.WarnLevel, email, ctx, "duplicate_email", uc.ReqID)
+		return res, errors.New(helper.DuplicateEmail)
+	}
+
+	oldData, err := uc.FindByID(id, false)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_user", uc.ReqID)
+		return res, err
+	}
+
+	body := map[string]interface{}{
+		"email": email,
+	}
+	changes := event.NewUserUpdatedData(id)
+	changes.Diff("email", oldData.Information.Email, email, true)
+	err = uc.updateData(id, body, changes)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return uc.FindByID(id, false)
+}
+
+// UpdateProfileImage assign unassigned uploaded file as profile image,
+// or import the image from profile image url when it is given
+func (uc AdminUC) UpdateProfileImage(id string, data *request.ProfileImageRequest) (res viewmodel.UserVM, err error) {
+	ctx := "AdminUC.UpdateProfileImage"
+
+	fileUc := FileUC{ContractUC: uc.ContractUC}
+	if data.ProfileImageURL != "" {
+		imported, err := fileUc.UploadFromURL(id, model.FileAdminProfile, data.ProfileImageURL)
+		if err != nil {
+			return res, err
+		}
+		data.ProfileImageID = imported.ID
+	}
+	profileImage, err := fileUc.FindUnassignedByID(data.ProfileImageID, model.FileAdminProfile, id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_file", uc.ReqID)
+		return res, errors.New(helper.InvalidProfileImage)
+	}
+	if profileImage.Status == model.FileStatusQuarantined || profileImage.Status == model.FileStatusFailed {
+		logruslogger.Log(logruslogger.WarnLevel, profileImage.Status, ctx, "file_status", uc.ReqID)
+		return res, errors.New(helper.InvalidProfileImage)
+	}
+
+	oldData, err := uc.FindByID(id, false)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_user", uc.ReqID)
+		return res, err
+	}
+
+	changes := event.NewUserUpdatedData(id)
+	changes.Diff("profile_image_id", oldData.ProfileImageID, profileImage.ID, false)
+	err = uc.WithTx(func(tx *sql.Tx) (err error) {
+		m := model.NewAdminModelTx(tx)
+		_, err = m.UpdateProfileImage(id, profileImage.ID, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+
+		return uc.addEvent(tx, event.UserUpdated, changes)
+	})
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return uc.FindByID(id, false)
+}
@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"database/sql"
+	"kriyapeople/model"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/usecase/viewmodel"
+)
+
+var (
+	// DefaultAesReencryptBatch ...
+	DefaultAesReencryptBatch = 100
+)
+
+// AesUC ...
+type AesUC struct {
+	*ContractUC
+}
+
+// Reencrypt encrypt again every legacy cfb value and value of old key with the primary key. Value which can
+// not be decrypted is counted as failed and left as is, value changed by other process is counted as changed
+func (uc AesUC) Reencrypt(dryRun bool, batch int) (res []viewmodel.AesReencryptVM, err error) {
+	if batch <= 0 {
+		batch = DefaultAesReencryptBatch
+	}
+
+	for _, column := range model.EncryptedColumns {
+		report, err := uc.reencryptColumn(column, dryRun, batch)
+		res = append(res, report)
+		if err != nil {
+			return res, err
+		}
+	}
+
+	return res, err
+}
+
+// reencryptColumn ...
+func (uc AesUC) reencryptColumn(column model.EncryptedColumn, dryRun bool, batch int) (res viewmodel.AesReencryptVM, err error) {
+	ctx := "AesUC.reencryptColumn"
+
+	res = viewmodel.AesReencryptVM{Table: column.Table, Column: column.Column, Field: column.Field}
+	m := model.NewEncryptedColumnModel(uc.DB, column)
+	lastID := ""
+	for {
+		data, err := m.FindAll(lastID, batch)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+			return res, err
+		}
+		if len(data) == 0 {
+			break
+		}
+
+		for _, row := range data {
+			lastID = row.ID
+			res.Checked++
+			if !uc.Aes.NeedReencrypt(row.Value) {
+				continue
+			}
+
+			plaintext, err := uc.Aes.Decrypt(row.Value)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, column.Table+" "+row.ID+" "+err.Error(), ctx, "decrypt", uc.ReqID)
+				res.Failed++
+				continue
+			}
+			if dryRun {
+				res.Reencrypted++
+				continue
+			}
+
+			value, err := uc.Aes.Encrypt(plaintext)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "encrypt", uc.ReqID)
+				return res, err
+			}
+			_, err = m.Update(row.ID, row.Value, value)
+			if err == sql.ErrNoRows {
+				res.Changed++
+				continue
+			}
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "update", uc.ReqID)
+				return res, err
+			}
+			res.Reencrypted++
+		}
+	}
+
+	return res, nil
+}
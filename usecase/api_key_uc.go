@@ -0,0 +1,253 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/str"
+	"kriyapeople/server/request"
+	"kriyapeople/usecase/viewmodel"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ScopeUserRead ...
+	ScopeUserRead = "user:read"
+	// ScopeUserWrite ...
+	ScopeUserWrite = "user:write"
+	// ScopeFileUsage ...
+	ScopeFileUsage = "file:usage"
+	// ScopeQueueManage ...
+	ScopeQueueManage = "queue:manage"
+	// ScopeMailPreview ...
+	ScopeMailPreview = "mail:preview"
+	// ScopeWebhookManage ...
+	ScopeWebhookManage = "webhook:manage"
+
+	// APIKeyScopes scope of the api key and the role which has the same access with admin token
+	APIKeyScopes = map[string]string{
+		ScopeUserRead:      model.RoleCodeAdmin,
+		ScopeUserWrite:     model.RoleCodeSuperadmin,
+		ScopeFileUsage:     model.RoleCodeSuperadmin,
+		ScopeQueueManage:   model.RoleCodeSuperadmin,
+		ScopeMailPreview:   model.RoleCodeSuperadmin,
+		ScopeWebhookManage: model.RoleCodeSuperadmin,
+	}
+
+	// DefaultAPIKeyLastUsedInterval the last used time is written at most once per interval
+	DefaultAPIKeyLastUsedInterval = time.Minute
+
+	apiKeyPrefix       = "kp_"
+	apiKeyPrefixLength = 12
+)
+
+// APIKeyUC ...
+type APIKeyUC struct {
+	*ContractUC
+}
+
+// HashAPIKey the key is random with 256 bit entropy, plain sha256 is enough to store it
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// BuildBody ...
+func (uc APIKeyUC) BuildBody(data *model.APIKeyEntity, res *viewmodel.APIKeyVM) {
+	res.ID = data.ID
+	res.Name = data.Name
+	res.KeyPrefix = data.KeyPrefix
+	res.Scopes = []string{}
+	json.Unmarshal([]byte(data.Scopes), &res.Scopes)
+	res.AllowedIPs = []string{}
+	json.Unmarshal([]byte(data.AllowedIPs), &res.AllowedIPs)
+	if data.ExpiresAt.Valid {
+		res.ExpiresAt = data.ExpiresAt.Time.UTC().Format(time.RFC3339)
+	}
+	res.LastUsedAt = data.LastUsedAt.String
+	res.LastUsedIP = data.LastUsedIP.String
+	res.CreatedBy = data.CreatedBy.String
+	res.RevokedAt = data.RevokedAt.String
+	res.CreatedAt = data.CreatedAt
+	res.UpdatedAt = data.UpdatedAt
+}
+
+// Scopes list every scope with its role
+func (uc APIKeyUC) Scopes() (res []viewmodel.APIKeyScopeVM) {
+	for scope, role := range APIKeyScopes {
+		res = append(res, viewmodel.APIKeyScopeVM{Scope: scope, Role: role})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Scope < res[j].Scope
+	})
+
+	return res
+}
+
+// FindAll ...
+func (uc APIKeyUC) FindAll(page, limit int) (res []viewmodel.APIKeyVM, pagination viewmodel.PaginationVM, err error) {
+	ctx := "APIKeyUC.FindAll"
+
+	limit = uc.LimitMax(limit)
+	limit, offset := uc.PaginationPageOffset(page, limit)
+
+	m := model.NewAPIKeyModel(uc.DB)
+	data, count, err := m.FindAll(offset, limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, pagination, err
+	}
+	pagination = PaginationRes(page, count, limit)
+
+	for _, r := range data {
+		temp := viewmodel.APIKeyVM{}
+		uc.BuildBody(&r, &temp)
+		res = append(res, temp)
+	}
+
+	return res, pagination, err
+}
+
+// FindByID ...
+func (uc APIKeyUC) FindByID(id string) (res viewmodel.APIKeyVM, err error) {
+	ctx := "APIKeyUC.FindByID"
+
+	m := model.NewAPIKeyModel(uc.DB)
+	data, err := m.FindByID(id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+	uc.BuildBody(&data, &res)
+
+	return res, err
+}
+
+// Create the key is returned only once, only its hash is stored
+func (uc APIKeyUC) Create(userID string, data *request.APIKeyRequest) (res viewmodel.APIKeyVM, err error) {
+	ctx := "APIKeyUC.Create"
+
+	scopes := str.Unique(data.Scopes)
+	for _, scope := range scopes {
+		if _, ok := APIKeyScopes[scope]; !ok {
+			logruslogger.Log(logruslogger.WarnLevel, scope, ctx, "invalid_scope", uc.ReqID)
+			return res, errors.New(helper.InvalidScope)
+		}
+	}
+
+	now := time.Now().UTC()
+	expiresAt := sql.NullTime{}
+	if data.ExpiresAt != "" {
+		expiresAt.Time, err = time.Parse(time.RFC3339, data.ExpiresAt)
+		if err != nil || !expiresAt.Time.After(now) {
+			logruslogger.Log(logruslogger.WarnLevel, data.ExpiresAt, ctx, "invalid_expires_at", uc.ReqID)
+			return res, errors.New(helper.InvalidExpiredAt)
+		}
+		expiresAt.Time = expiresAt.Time.UTC()
+		expiresAt.Valid = true
+	}
+
+	key := apiKeyPrefix + str.RandomSecureString(32)
+	m := model.NewAPIKeyModel(uc.DB)
+	id, err := m.Store(model.APIKeyEntity{
+		Name:       data.Name,
+		KeyPrefix:  key[:apiKeyPrefixLength],
+		KeyHash:    HashAPIKey(key),
+		Scopes:     interfacepkg.Marshall(scopes),
+		AllowedIPs: interfacepkg.Marshall(str.Unique(data.AllowedIPs)),
+		ExpiresAt:  expiresAt,
+		CreatedBy:  sql.NullString{String: userID, Valid: userID != ""},
+	}, now)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res, err = uc.FindByID(id)
+	res.Key = key
+
+	return res, err
+}
+
+// Revoke the key is rejected on the next request
+func (uc APIKeyUC) Revoke(id string) (res viewmodel.APIKeyVM, err error) {
+	ctx := "APIKeyUC.Revoke"
+
+	m := model.NewAPIKeyModel(uc.DB)
+	_, err = m.Revoke(id, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return uc.FindByID(id)
+}
+
+// allowedIP empty list allow any ip
+func (uc APIKeyUC) allowedIP(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, a := range allowed {
+		if strings.Contains(a, "/") {
+			_, network, err := net.ParseCIDR(a)
+			if err == nil && network.Contains(parsed) {
+				return true
+			}
+			continue
+		}
+		if allowedIP := net.ParseIP(a); allowedIP != nil && allowedIP.Equal(parsed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Verify check the key is active, not expired, used from the allowed ip and has the scope.
+// The key is read from database on every request so revocation take effect right away
+func (uc APIKeyUC) Verify(key, ip, scope string, now time.Time) (res viewmodel.APIKeyVM, err error) {
+	ctx := "APIKeyUC.Verify"
+
+	m := model.NewAPIKeyModel(uc.DB)
+	data, err := m.FindByHash(HashAPIKey(key))
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, ip, ctx, "invalid_api_key", uc.ReqID)
+		return res, errors.New(helper.InvalidAPIKey)
+	}
+	uc.BuildBody(&data, &res)
+
+	if data.ExpiresAt.Valid && !now.Before(data.ExpiresAt.Time) {
+		logruslogger.Log(logruslogger.WarnLevel, data.ID, ctx, "expired_api_key", uc.ReqID)
+		return res, errors.New(helper.ExpiredAPIKey)
+	}
+	if !uc.allowedIP(res.AllowedIPs, ip) {
+		logruslogger.Log(logruslogger.WarnLevel, data.ID+" "+ip, ctx, "forbidden_ip", uc.ReqID)
+		return res, errors.New(helper.ForbiddenIP)
+	}
+	if !str.Contains(res.Scopes, scope) {
+		logruslogger.Log(logruslogger.WarnLevel, data.ID+" "+scope, ctx, "insufficient_scope", uc.ReqID)
+		return res, errors.New(helper.InsufficientScope)
+	}
+
+	err = m.UpdateLastUsed(data.ID, ip, now.UTC(), DefaultAPIKeyLastUsedInterval)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "update_last_used", uc.ReqID)
+	}
+
+	return res, nil
+}
@@ -1,20 +1,29 @@
 package usecase
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"kriyapeople/model"
 	"kriyapeople/pkg/aesfront"
+	"kriyapeople/pkg/amqp"
+	"kriyapeople/pkg/apple"
+	"kriyapeople/pkg/clamav"
+	"kriyapeople/pkg/hmacsha"
 	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/mail"
+	"kriyapeople/pkg/mailtemplate"
+	"kriyapeople/pkg/webhook"
 	"time"
 
 	"database/sql"
 	"kriyapeople/pkg/aes"
 	"kriyapeople/pkg/jwe"
 	"kriyapeople/pkg/jwt"
+	"kriyapeople/pkg/storage"
 	"kriyapeople/usecase/viewmodel"
 
 	"github.com/go-redis/redis/v7"
-	"github.com/streadway/amqp"
 )
 
 var (
@@ -32,25 +41,28 @@ var (
 	DescSort = "desc"
 	// SortWhitelist ...
 	SortWhitelist = []string{AscSort, DescSort}
-	// AmqpConnection ...
-	AmqpConnection *amqp.Connection
-	// AmqpChannel ...
-	AmqpChannel *amqp.Channel
 )
 
 // ContractUC ...
 type ContractUC struct {
-	ReqID       string
-	DB          *sql.DB
-	Tx          *sql.Tx
-	AmqpConn    *amqp.Connection
-	AmqpChannel *amqp.Channel
-	Redis       *redis.Client
-	EnvConfig   map[string]string
-	Jwt         jwt.Credential
-	Jwe         jwe.Credential
-	Aes         aes.Credential
-	AesFront    aesfront.Credential
+	ReqID         string
+	DB            *sql.DB
+	Tx            *sql.Tx
+	Publisher     amqp.IPublisher
+	Redis         *redis.Client
+	EnvConfig     map[string]string
+	Jwt           jwt.Credential
+	Jwe           jwe.Credential
+	Aes           aes.Credential
+	AesFront      aesfront.Credential
+	BlindIndexKey hmacsha.Credential
+	Apple         apple.Credential
+	Storage       storage.IStorage
+	HTMLStorage   storage.IStorage
+	Scanner       clamav.IScanner
+	Mailer        mail.IDriver
+	MailTemplate  *mailtemplate.Engine
+	Webhook       *webhook.Client
 }
 
 // StoreToRedis save data to redis with key key
@@ -121,6 +133,31 @@ func (uc ContractUC) GetFromRedis(key string, cb interface{}) error {
 	return err
 }
 
+// PopFromRedis get and remove the value in one step, only one caller can get the value of the key
+func (uc ContractUC) PopFromRedis(key string, cb interface{}) error {
+	ctx := "ContractUC.PopFromRedis"
+
+	script := `local res = redis.call("GET", KEYS[1]) if res then redis.call("DEL", KEYS[1]) end return res`
+	res, err := uc.Redis.Eval(script, []string{key}).Text()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "redis_eval", uc.ReqID)
+		return err
+	}
+
+	if res == "" {
+		logruslogger.Log(logruslogger.WarnLevel, "", ctx, "redis_empty", uc.ReqID)
+		return errors.New("[Redis] Value of " + key + " is empty.")
+	}
+
+	err = json.Unmarshal([]byte(res), &cb)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "json_unmarshal", uc.ReqID)
+		return err
+	}
+
+	return err
+}
+
 // GetAllStringFromRedis get all value from redis by key
 func (uc ContractUC) GetAllStringFromRedis(key string) (res []viewmodel.RedisStringValueVM, err error) {
 	ctx := "ContractUC.GetAllStringFromRedis"
@@ -254,3 +291,52 @@ func (uc ContractUC) ResetByKey(key string) (err error) {
 
 	return err
 }
+
+// Lock acquire distributed lock using redis, return false when the lock is held by other process
+func (uc ContractUC) Lock(key, token string, duration time.Duration) (bool, error) {
+	ctx := "ContractUC.Lock"
+
+	ok, err := uc.Redis.SetNX(key, token, duration).Result()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "redis_setnx", uc.ReqID)
+		return false, err
+	}
+
+	return ok, err
+}
+
+// Unlock release the lock only when it is still held by the token
+func (uc ContractUC) Unlock(key, token string) error {
+	ctx := "ContractUC.Unlock"
+
+	script := `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+	err := uc.Redis.Eval(script, []string{key}, token).Err()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "redis_eval", uc.ReqID)
+		return err
+	}
+
+	return err
+}
+
+// Publish publish the body into the queue with broker confirmation
+func (uc ContractUC) Publish(ctx context.Context, queueName, deadLetterKey string, body map[string]interface{}) error {
+	if uc.Publisher == nil {
+		return errors.New("amqp_publisher_not_configured")
+	}
+
+	return uc.Publisher.Publish(ctx, queueName, deadLetterKey, body)
+}
+
+// WithTx run fn inside a database transaction, commit when fn return nil and rollback otherwise
+func (uc ContractUC) WithTx(fn func(tx *sql.Tx) error) error {
+	tx, err := uc.DB.Begin()
+	if err != nil {
+		return err
+	}
+	sct := model.SQLConnTx{DB: tx}
+
+	return sct.TxEnd(func() error {
+		return fn(tx)
+	})
+}
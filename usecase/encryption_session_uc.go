@@ -0,0 +1,81 @@
+package usecase
+
+import (
+	"errors"
+	"kriyapeople/helper"
+	"kriyapeople/pkg/aesfront"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase/viewmodel"
+	"time"
+)
+
+var (
+	// DefaultEncryptionSessionTTL ...
+	DefaultEncryptionSessionTTL = 24 * time.Hour
+	// EncryptionSessionRedisKey prefix of redis key for every encryption session, followed by session id
+	EncryptionSessionRedisKey = "encryptionSession"
+)
+
+// EncryptionSessionUC per session key of the encrypted payload mode, used instead of the static AES_FRONT_IV
+type EncryptionSessionUC struct {
+	*ContractUC
+}
+
+// ttl ...
+func (uc EncryptionSessionUC) ttl() time.Duration {
+	res, err := time.ParseDuration(uc.EnvConfig["AES_FRONT_SESSION_TTL"])
+	if err != nil || res <= 0 {
+		return DefaultEncryptionSessionTTL
+	}
+
+	return res
+}
+
+// Create new random key, the key is stored encrypted and expired after AES_FRONT_SESSION_TTL
+func (uc EncryptionSessionUC) Create() (res viewmodel.EncryptionSessionVM, err error) {
+	ctx := "EncryptionSessionUC.Create"
+
+	ttl := uc.ttl()
+	res = viewmodel.EncryptionSessionVM{
+		ID:        str.RandomSecureString(16),
+		Key:       str.RandomSecureString(16),
+		ExpiresAt: time.Now().Add(ttl).UTC().Format(time.RFC3339),
+	}
+	if res.ID == "" || res.Key == "" {
+		logruslogger.Log(logruslogger.WarnLevel, "", ctx, "random", uc.ReqID)
+		return res, errors.New(helper.InternalServer)
+	}
+
+	key, err := uc.Aes.Encrypt(res.Key)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "encrypt", uc.ReqID)
+		return res, err
+	}
+	err = uc.StoreToRedisExp(EncryptionSessionRedisKey+res.ID, key, ttl.String())
+	if err != nil {
+		return res, err
+	}
+
+	return res, err
+}
+
+// Credential AES-GCM credential of the session, each message has its own random nonce
+func (uc EncryptionSessionUC) Credential(id string) (res aesfront.Credential, err error) {
+	ctx := "EncryptionSessionUC.Credential"
+
+	var key string
+	err = uc.GetFromRedis(EncryptionSessionRedisKey+id, &key)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, id, ctx, "expired_session", uc.ReqID)
+		return res, errors.New(helper.ExpiredEncryptionSession)
+	}
+	key, err = uc.Aes.Decrypt(key)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "decrypt", uc.ReqID)
+		return res, errors.New(helper.ExpiredEncryptionSession)
+	}
+	res.Key = key
+
+	return res, err
+}
@@ -0,0 +1,660 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/amqp"
+	"kriyapeople/pkg/amqpconsumer"
+	"kriyapeople/pkg/clamav"
+	"kriyapeople/pkg/fetch"
+	"kriyapeople/pkg/file"
+	"kriyapeople/pkg/imaging"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/storage"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase/viewmodel"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/xid"
+	streadway "github.com/streadway/amqp"
+)
+
+var (
+	// DefaultFileURLExp ...
+	DefaultFileURLExp = 15 * time.Minute
+	// FilePendingPrefix object prefix of uploaded file which is not processed yet
+	FilePendingPrefix = "pending/"
+	// FileQuarantinePrefix object prefix of infected file
+	FileQuarantinePrefix = "quarantine/"
+	// FileHiddenPrefixes object prefixes which must not be served
+	FileHiddenPrefixes = []string{FilePendingPrefix, FileQuarantinePrefix}
+	// FileCleanLockKey redis key of the lock, so only one replica clean the files at the same time
+	FileCleanLockKey = "fileCleanLock"
+	// DefaultFileCleanInterval ...
+	DefaultFileCleanInterval = time.Hour
+	// DefaultFileCleanTTL ...
+	DefaultFileCleanTTL = 24 * time.Hour
+	// DefaultFileCleanLimit ...
+	DefaultFileCleanLimit = 100
+)
+
+// FileUC ...
+type FileUC struct {
+	*ContractUC
+}
+
+// BuildBody ...
+func (uc FileUC) BuildBody(data *model.FileEntity, res *viewmodel.FileVM) {
+	res.ID = data.ID
+	res.Type = data.Type.String
+	res.URL = data.URL.String
+	res.UserUpload = data.UserUpload.String
+	res.Status = data.Status.String
+	res.Thumbnails = map[string]string{}
+	res.Size = data.Size.Int64
+	res.CreatedAt = data.CreatedAt
+	res.UpdatedAt = data.UpdatedAt
+	res.DeletedAt = data.DeletedAt.String
+
+	// Only processed file is ready to be downloaded
+	if res.Status == model.FileStatusProcessed {
+		res.TempURL = uc.GetURL(res.Type, res.URL)
+
+		thumbnails := map[string]string{}
+		interfacepkg.UnmarshallCb(data.Thumbnails.String, &thumbnails)
+		for size, url := range thumbnails {
+			res.Thumbnails[size] = uc.GetURL(res.Type, url)
+		}
+	}
+}
+
+// IsHiddenObject check if the object is pending or quarantined
+func IsHiddenObject(objectName string) bool {
+	objectName = strings.TrimLeft(path.Clean("/"+objectName), "/")
+	for _, prefix := range FileHiddenPrefixes {
+		if strings.HasPrefix(objectName+"/", prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scan send the file into antivirus scanner, CLAMAV_FAIL_OPEN decide if the upload is accepted
+// when the scanner is not available
+func (uc FileUC) scan(data []byte) (res clamav.Result, err error) {
+	ctx := "FileUC.scan"
+
+	if uc.Scanner == nil {
+		return res, nil
+	}
+
+	res, err = uc.Scanner.Scan(bytes.NewReader(data))
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "scan", uc.ReqID)
+		if str.StringToBool(uc.EnvConfig["CLAMAV_FAIL_OPEN"]) {
+			return clamav.Result{}, nil
+		}
+		return res, errors.New(helper.ScannerUnavailable)
+	}
+
+	return res, nil
+}
+
+// quarantine keep the infected file in quarantine folder for investigation
+func (uc FileUC) quarantine(userUpload, types, objectName, contentType, signature string, data []byte) (err error) {
+	ctx := "FileUC.quarantine"
+
+	logruslogger.Log(logruslogger.WarnLevel, signature, ctx, "infected_file", uc.ReqID)
+	err = uc.storageByType(types).Put(FileQuarantinePrefix+objectName, bytes.NewReader(data), int64(len(data)), contentType)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_put", uc.ReqID)
+		return err
+	}
+
+	now := time.Now().UTC()
+	m := model.NewFileModel(uc.DB)
+	_, err = m.Store(viewmodel.FileVM{
+		Type:       types,
+		URL:        objectName,
+		UserUpload: userUpload,
+		Status:     model.FileStatusQuarantined,
+		Thumbnails: map[string]string{},
+		Size:       int64(len(data)),
+	}, now)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return err
+	}
+
+	return err
+}
+
+// storageByType html picture is stored in public html picture folder
+func (uc FileUC) storageByType(types string) storage.IStorage {
+	if types == model.FileHTMLPicture && uc.HTMLStorage != nil {
+		return uc.HTMLStorage
+	}
+
+	return uc.Storage
+}
+
+// GetURL generate url of stored file, private storage give time limited url
+func (uc FileUC) GetURL(types, url string) string {
+	ctx := "FileUC.GetURL"
+
+	if url == "" {
+		return ""
+	}
+
+	exp, err := time.ParseDuration(uc.EnvConfig["FILE_URL_EXP"])
+	if err != nil {
+		exp = DefaultFileURLExp
+	}
+	res, err := uc.storageByType(types).URL(url, exp)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_url", uc.ReqID)
+		return ""
+	}
+
+	return res
+}
+
+// FindByID ...
+func (uc FileUC) FindByID(id string) (res viewmodel.FileVM, err error) {
+	ctx := "FileUC.FindByID"
+
+	m := model.NewFileModel(uc.DB)
+	data, err := m.FindByID(id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+	uc.BuildBody(&data, &res)
+
+	return res, err
+}
+
+// FindUnassignedByID ...
+func (uc FileUC) FindUnassignedByID(id, types, userUpload string) (res viewmodel.FileVM, err error) {
+	ctx := "FileUC.FindUnassignedByID"
+
+	m := model.NewFileModel(uc.DB)
+	data, err := m.FindUnassignedByID(id, types, userUpload)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+	uc.BuildBody(&data, &res)
+
+	return res, err
+}
+
+// UploadBase64 ...
+func (uc FileUC) UploadBase64(userUpload, types, contentType, data string) (res viewmodel.FileVM, err error) {
+	ctx := "FileUC.UploadBase64"
+
+	dec, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "decode_base64", uc.ReqID)
+		return res, errors.New(helper.FileError)
+	}
+
+	return uc.Upload(userUpload, types, contentType, dec)
+}
+
+// checkQuota check if the upload is still under the byte and file count quota of the user role,
+// the usage is read with m so it can be checked inside the transaction holding the user lock
+func (uc FileUC) checkQuota(m model.IFile, userUpload string, size int64) (err error) {
+	ctx := "FileUC.checkQuota"
+
+	roleModel := model.NewRoleModel(uc.DB)
+	quota, err := roleModel.FindQuotaByUserID(userUpload)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_quota", uc.ReqID)
+		return errors.New(helper.UploadFileError)
+	}
+	if quota.MaxBytes.Int64 <= 0 && quota.MaxFiles.Int64 <= 0 {
+		return nil
+	}
+
+	usage, err := m.UsageByUserID(userUpload)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "usage", uc.ReqID)
+		return errors.New(helper.UploadFileError)
+	}
+	if quota.MaxFiles.Int64 > 0 && usage.Files+1 > quota.MaxFiles.Int64 {
+		logruslogger.Log(logruslogger.WarnLevel, userUpload, ctx, "file_count_quota", uc.ReqID)
+		return errors.New(helper.FileCountQuotaExceeded)
+	}
+	if quota.MaxBytes.Int64 > 0 && usage.Bytes+size > quota.MaxBytes.Int64 {
+		logruslogger.Log(logruslogger.WarnLevel, userUpload, ctx, "storage_quota", uc.ReqID)
+		return errors.New(helper.StorageQuotaExceeded)
+	}
+
+	return nil
+}
+
+// Usage report the storage usage per user and per file type, empty userID report every user
+func (uc FileUC) Usage(userID string) (res []viewmodel.FileUsageVM, err error) {
+	ctx := "FileUC.Usage"
+
+	m := model.NewFileModel(uc.DB)
+	data, err := m.SelectUsage(userID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res = []viewmodel.FileUsageVM{}
+	index := map[string]int{}
+	piiUc := UserPIIUC{ContractUC: uc.ContractUC}
+	for _, d := range data {
+		i, ok := index[d.UserUpload.String]
+		if !ok {
+			usage := viewmodel.FileUsageVM{
+				UserID:   d.UserUpload.String,
+				UserName: piiUc.Decrypt(d.UserName, d.UserNameEnc),
+				Types:    map[string]viewmodel.FileUsageTypeVM{},
+			}
+			for _, types := range model.FileWhitelist {
+				usage.Types[types] = viewmodel.FileUsageTypeVM{}
+			}
+			usage.MaxFiles = d.MaxFiles.Int64
+			usage.MaxBytes = d.MaxBytes.Int64
+
+			res = append(res, usage)
+			i = len(res) - 1
+			index[d.UserUpload.String] = i
+		}
+
+		res[i].Files += d.Files
+		res[i].Bytes += d.Bytes
+		res[i].Types[d.Type.String] = viewmodel.FileUsageTypeVM{Files: d.Files, Bytes: d.Bytes}
+	}
+
+	return res, nil
+}
+
+// UploadFromURL download the image from public url and continue with the usual upload
+func (uc FileUC) UploadFromURL(userUpload, types, url string) (res viewmodel.FileVM, err error) {
+	ctx := "FileUC.UploadFromURL"
+
+	timeout, err := time.ParseDuration(uc.EnvConfig["FILE_FETCH_TIMEOUT"])
+	if err != nil {
+		timeout = fetch.DefaultTimeout
+	}
+	maxUploadSize := int64(str.StringToInt(uc.EnvConfig["FILE_MAX_UPLOAD_SIZE"]))
+	fetcher := fetch.NewFetcher(timeout, maxUploadSize, file.ImageContentTypeWhitelist())
+
+	remote, err := fetcher.Get(context.Background(), url)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, url+" "+err.Error(), ctx, "fetch", uc.ReqID)
+		switch err {
+		case fetch.ErrInvalidURL, fetch.ErrBlockedHost, fetch.ErrTooManyRedirects:
+			return res, errors.New(helper.InvalidFileURL)
+		case fetch.ErrTooLarge:
+			return res, errors.New(helper.FileTooBig)
+		case fetch.ErrInvalidContentType:
+			return res, errors.New(helper.InvalidImageType)
+		}
+		return res, errors.New(helper.DownloadFileError)
+	}
+
+	return uc.Upload(userUpload, types, remote.ContentType, remote.Data)
+}
+
+// Upload check the file size and the real image format against the declared content type,
+// store it as pending object and push image processing job to the queue
+func (uc FileUC) Upload(userUpload, types, contentType string, data []byte) (res viewmodel.FileVM, err error) {
+	ctx := "FileUC.Upload"
+
+	if !str.Contains(model.FileWhitelist, types) {
+		logruslogger.Log(logruslogger.WarnLevel, types, ctx, "invalid_type", uc.ReqID)
+		return res, errors.New(helper.InvalidFileType)
+	}
+
+	maxUploadSize := str.StringToInt(uc.EnvConfig["FILE_MAX_UPLOAD_SIZE"])
+	if len(data) > maxUploadSize {
+		logruslogger.Log(logruslogger.WarnLevel, "", ctx, "file_too_big", uc.ReqID)
+		return res, errors.New(helper.FileTooBig)
+	}
+
+	realContentType, extention, err := file.GetFileContentTypeAndExtention(data)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "content_type", uc.ReqID)
+		return res, errors.New(helper.InvalidImageType)
+	}
+	_, err = imaging.CheckFormat(data, contentType)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "check_format", uc.ReqID)
+		return res, errors.New(helper.ImageFormatMismatch)
+	}
+
+	// Early rejection, the quota is checked again while storing the file
+	err = uc.checkQuota(model.NewFileModel(uc.DB), userUpload, int64(len(data)))
+	if err != nil {
+		return res, err
+	}
+
+	objectName := xid.New().String() + "." + extention
+	scanResult, err := uc.scan(data)
+	if err != nil {
+		return res, err
+	}
+	if scanResult.Infected {
+		err = uc.quarantine(userUpload, types, objectName, realContentType, scanResult.Signature, data)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, objectName+" "+scanResult.Signature+" "+err.Error(), ctx, "quarantine", uc.ReqID)
+			return res, errors.New(helper.InternalServer)
+		}
+		return res, errors.New(helper.InfectedFile)
+	}
+
+	fileStorage := uc.storageByType(types)
+	err = fileStorage.Put(FilePendingPrefix+objectName, bytes.NewReader(data), int64(len(data)), realContentType)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_put", uc.ReqID)
+		return res, errors.New(helper.UploadFileError)
+	}
+
+	now := time.Now().UTC()
+	res = viewmodel.FileVM{
+		Type:       types,
+		URL:        objectName,
+		UserUpload: userUpload,
+		Status:     model.FileStatusPending,
+		Thumbnails: map[string]string{},
+		Size:       int64(len(data)),
+		CreatedAt:  now.Format(time.RFC3339),
+		UpdatedAt:  now.Format(time.RFC3339),
+	}
+	// Concurrent uploads of the user wait for the lock, so the usage can not pass the quota
+	err = uc.WithTx(func(tx *sql.Tx) (err error) {
+		m := model.NewFileModelTx(tx)
+		err = m.LockUser(userUpload)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "lock_user", uc.ReqID)
+			return errors.New(helper.UploadFileError)
+		}
+		err = uc.checkQuota(m, userUpload, int64(len(data)))
+		if err != nil {
+			return err
+		}
+		res.ID, err = m.Store(res, now)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+			return errors.New(helper.UploadFileError)
+		}
+
+		return err
+	})
+	if err != nil {
+		fileStorage.Delete(FilePendingPrefix + objectName)
+		return res, err
+	}
+
+	queueBody := map[string]interface{}{
+		"id": res.ID,
+	}
+	err = uc.Publish(context.Background(), amqp.ImageProcess, amqp.ImageProcessDeadLetter, queueBody)
+	if err != nil {
+		// Do not lose the upload when the queue is down, process it right away
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "push_queue", uc.ReqID)
+		err = uc.Process(res.ID)
+		if err != nil {
+			return res, errors.New(helper.UploadFileError)
+		}
+		return uc.FindByID(res.ID)
+	}
+
+	return res, err
+}
+
+// thumbnailSizes read IMAGE_THUMBNAIL_SIZES config
+func (uc FileUC) thumbnailSizes() (res []int) {
+	for _, size := range strings.Split(uc.EnvConfig["IMAGE_THUMBNAIL_SIZES"], ",") {
+		if s := str.StringToInt(strings.TrimSpace(size)); s > 0 {
+			res = append(res, s)
+		}
+	}
+
+	return res
+}
+
+// Process decode pending file, strip the metadata, normalize the orientation and generate the thumbnails
+func (uc FileUC) Process(id string) (err error) {
+	ctx := "FileUC.Process"
+
+	m := model.NewFileModel(uc.DB)
+	data, err := m.FindByID(id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return err
+	}
+	if data.Status.String != model.FileStatusPending {
+		return nil
+	}
+
+	fileStorage := uc.storageByType(data.Type.String)
+	objectName := data.URL.String
+	reader, err := fileStorage.Get(FilePendingPrefix + objectName)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_get", uc.ReqID)
+		return err
+	}
+	original, err := ioutil.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_read", uc.ReqID)
+		return err
+	}
+
+	status := model.FileStatusProcessed
+	thumbnails := map[string]string{}
+	storedSize := int64(0)
+	result, err := imaging.Process(original, uc.thumbnailSizes())
+	if err != nil {
+		// The upload is not a valid image, processing it again will not succeed
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "process", uc.ReqID)
+		status = model.FileStatusFailed
+	} else {
+		// Storage failure keep the pending upload so the job can be retried
+		contentType := "image/" + result.Format
+		err = fileStorage.Put(objectName, bytes.NewReader(result.Original), int64(len(result.Original)), contentType)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_put", uc.ReqID)
+			return err
+		}
+		storedSize += int64(len(result.Original))
+		for size, thumbnail := range result.Thumbnails {
+			thumbnailName := uc.thumbnailName(objectName, size)
+			err = fileStorage.Put(thumbnailName, bytes.NewReader(thumbnail), int64(len(thumbnail)), contentType)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "storage_put_thumbnail", uc.ReqID)
+				return err
+			}
+			thumbnails[strconv.Itoa(size)] = thumbnailName
+			storedSize += int64(len(thumbnail))
+		}
+	}
+
+	_, err = m.UpdateProcessed(id, status, interfacepkg.Marshall(thumbnails), storedSize, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "update_status", uc.ReqID)
+		return err
+	}
+	fileStorage.Delete(FilePendingPrefix + objectName)
+
+	return err
+}
+
+// thumbnailName thumbnail is stored alongside the original, ex: abc.jpeg -> abc_128.jpeg
+func (uc FileUC) thumbnailName(objectName string, size int) string {
+	i := strings.LastIndex(objectName, ".")
+	if i < 0 {
+		return objectName + "_" + strconv.Itoa(size)
+	}
+
+	return objectName[:i] + "_" + strconv.Itoa(size) + objectName[i:]
+}
+
+// ImageProcessConsumer process the image processing job of the delivery,
+// database or storage failure is retried before dead lettered
+func ImageProcessConsumer(d streadway.Delivery, contractUC *ContractUC) error {
+	ctx := "ImageProcessConsumer"
+
+	body := map[string]interface{}{}
+	err := json.Unmarshal(d.Body, &body)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "json_unmarshal", contractUC.ReqID)
+		return err
+	}
+
+	uc := FileUC{ContractUC: contractUC}
+	err = uc.Process(interfacepkg.InterfaceStringToString(body, "id"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return err
+		}
+		return amqpconsumer.Requeue(err)
+	}
+
+	return err
+}
+
+// objectNames every stored object of the file: original, thumbnails and the pending upload
+func (uc FileUC) objectNames(data *model.FileEntity) (res []string) {
+	if data.URL.String == "" {
+		return res
+	}
+	res = append(res, data.URL.String, FilePendingPrefix+data.URL.String, FileQuarantinePrefix+data.URL.String)
+
+	thumbnails := map[string]string{}
+	interfacepkg.UnmarshallCb(data.Thumbnails.String, &thumbnails)
+	for _, url := range thumbnails {
+		res = append(res, url)
+	}
+
+	return res
+}
+
+// CleanUnassigned remove the uploaded files which are not assigned to any user for longer than ttl
+// from the storage and soft delete them, quarantined files are kept. Dry run only report the files
+func (uc FileUC) CleanUnassigned(ttl time.Duration, limit int, dryRun bool) (res viewmodel.FileCleanReportVM, err error) {
+	ctx := "FileUC.CleanUnassigned"
+
+	before := time.Now().UTC().Add(-ttl)
+	res = viewmodel.FileCleanReportVM{
+		DryRun:  dryRun,
+		Before:  before.Format(time.RFC3339),
+		Deleted: []string{},
+		Failed:  []string{},
+	}
+
+	m := model.NewFileModel(uc.DB)
+	data, err := m.FindAllUnassignedBefore(model.FileAssignableWhitelist, before, limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res.Count = len(data)
+	for i := range data {
+		if dryRun {
+			res.Deleted = append(res.Deleted, data[i].ID)
+			continue
+		}
+
+		// Remove the objects first so the file is picked again on the next run when a delete fails
+		fileStorage := uc.storageByType(data[i].Type.String)
+		deleted := true
+		for _, objectName := range uc.objectNames(&data[i]) {
+			err = fileStorage.Delete(objectName)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, objectName+" "+err.Error(), ctx, "storage_delete", uc.ReqID)
+				deleted = false
+			}
+		}
+		if !deleted {
+			res.Failed = append(res.Failed, data[i].ID)
+			continue
+		}
+
+		_, err = m.Destroy(data[i].ID, time.Now().UTC())
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, data[i].ID+" "+err.Error(), ctx, "destroy", uc.ReqID)
+			res.Failed = append(res.Failed, data[i].ID)
+			continue
+		}
+		res.Deleted = append(res.Deleted, data[i].ID)
+	}
+
+	return res, nil
+}
+
+// CleanUnassignedWithLock run CleanUnassigned with FILE_CLEAN_* config while holding the lock,
+// skipped when other replica is running it
+func (uc FileUC) CleanUnassignedWithLock(dryRun bool) (res viewmodel.FileCleanReportVM, ok bool, err error) {
+	ctx := "FileUC.CleanUnassignedWithLock"
+
+	ttl, err := time.ParseDuration(uc.EnvConfig["FILE_CLEAN_TTL"])
+	if err != nil || ttl <= 0 {
+		ttl = DefaultFileCleanTTL
+	}
+	limit := str.StringToInt(uc.EnvConfig["FILE_CLEAN_LIMIT"])
+	if limit <= 0 {
+		limit = DefaultFileCleanLimit
+	}
+	interval, err := time.ParseDuration(uc.EnvConfig["FILE_CLEAN_INTERVAL"])
+	if err != nil || interval <= 0 {
+		interval = DefaultFileCleanInterval
+	}
+
+	token := xid.New().String()
+	ok, err = uc.Lock(FileCleanLockKey, token, interval)
+	if err != nil || !ok {
+		return res, false, err
+	}
+	defer uc.Unlock(FileCleanLockKey, token)
+
+	res, err = uc.CleanUnassigned(ttl, limit, dryRun)
+	if err != nil {
+		return res, true, err
+	}
+	logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(res), ctx, "report", uc.ReqID)
+
+	return res, true, err
+}
+
+// FileCleanScheduler clean the unassigned files every FILE_CLEAN_INTERVAL
+func FileCleanScheduler(contractUC *ContractUC) {
+	ctx := "FileCleanScheduler"
+
+	interval, err := time.ParseDuration(contractUC.EnvConfig["FILE_CLEAN_INTERVAL"])
+	if err != nil || interval <= 0 {
+		interval = DefaultFileCleanInterval
+	}
+	dryRun := str.StringToBool(contractUC.EnvConfig["FILE_CLEAN_DRY_RUN"])
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		uc := FileUC{ContractUC: contractUC}
+		_, _, err = uc.CleanUnassignedWithLock(dryRun)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "clean", contractUC.ReqID)
+		}
+	}
+}
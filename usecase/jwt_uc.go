@@ -2,10 +2,19 @@ package usecase
 
 import (
 	"errors"
+	"github.com/go-redis/redis/v7"
 	"github.com/rs/xid"
 	"kriyapeople/helper"
 	"kriyapeople/pkg/logruslogger"
 	"kriyapeople/usecase/viewmodel"
+	"time"
+)
+
+var (
+	// SessionRedisKey prefix of redis key for every logged in device, followed by user id and device id
+	SessionRedisKey = "userSession"
+	// SessionListRedisKey prefix of redis set of the device id of every session, followed by user id
+	SessionListRedisKey = "userSessionList"
 )
 
 // JwtUC ...
@@ -24,18 +33,28 @@ func (uc JwtUC) GenerateToken(payload map[string]interface{}, res *viewmodel.Jwt
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "device_id", uc.ReqID)
 		return errors.New(helper.InternalServer)
 	}
+	err = uc.StoreToRedisExp(uc.sessionKey(payload["id"].(string), deviceID), deviceID, uc.EnvConfig["TOKEN_EXP_REFRESH_SECRET"]+"h")
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "session", uc.ReqID)
+		return errors.New(helper.InternalServer)
+	}
+	err = uc.addSession(payload["id"].(string), deviceID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "session_list", uc.ReqID)
+		return errors.New(helper.InternalServer)
+	}
 
 	jwePayload, err := uc.ContractUC.Jwe.Generate(payload)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "jwe", uc.ReqID)
 		return errors.New(helper.JWT)
 	}
-	res.Token, res.ExpiredDate, err = uc.ContractUC.Jwt.GetToken(jwePayload)
+	res.Token, res.ExpiredDate, err = uc.ContractUC.Jwt.GetToken(payload["id"].(string), jwePayload)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "jwt", uc.ReqID)
 		return errors.New(helper.JWT)
 	}
-	res.RefreshToken, res.RefreshExpiredDate, err = uc.ContractUC.Jwt.GetRefreshToken(jwePayload)
+	res.RefreshToken, res.RefreshExpiredDate, err = uc.ContractUC.Jwt.GetRefreshToken(payload["id"].(string), jwePayload)
 	if err != nil {
 		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "refresh_jwt", uc.ReqID)
 		return errors.New(helper.JWT)
@@ -43,3 +62,66 @@ func (uc JwtUC) GenerateToken(payload map[string]interface{}, res *viewmodel.Jwt
 
 	return err
 }
+
+// sessionKey ...
+func (uc JwtUC) sessionKey(userID, deviceID string) string {
+	return SessionRedisKey + userID + ":" + deviceID
+}
+
+// addSession add the device into the session list, the list lives as long as the last session
+func (uc JwtUC) addSession(userID, deviceID string) (err error) {
+	exp, err := time.ParseDuration(uc.EnvConfig["TOKEN_EXP_REFRESH_SECRET"] + "h")
+	if err != nil {
+		return err
+	}
+
+	_, err = uc.Redis.TxPipelined(func(pipe redis.Pipeliner) error {
+		pipe.SAdd(SessionListRedisKey+userID, deviceID)
+		pipe.Expire(SessionListRedisKey+userID, exp)
+		return nil
+	})
+
+	return err
+}
+
+// CheckSession check if the device session is still active
+func (uc JwtUC) CheckSession(userID, deviceID string) (err error) {
+	ctx := "JwtUC.CheckSession"
+
+	var session string
+	err = uc.GetFromRedis(uc.sessionKey(userID, deviceID), &session)
+	if err != nil || session != deviceID {
+		logruslogger.Log(logruslogger.WarnLevel, userID, ctx, "revoked_session", uc.ReqID)
+		return errors.New(helper.ExpKey)
+	}
+
+	return nil
+}
+
+// RevokeSessions remove every session of user except the device id in exceptDeviceID
+func (uc JwtUC) RevokeSessions(userID, exceptDeviceID string) (err error) {
+	ctx := "JwtUC.RevokeSessions"
+
+	deviceIDs, err := uc.Redis.SMembers(SessionListRedisKey + userID).Result()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "redis_members", uc.ReqID)
+		return err
+	}
+
+	for _, deviceID := range deviceIDs {
+		if deviceID == exceptDeviceID {
+			continue
+		}
+		_, err = uc.Redis.TxPipelined(func(pipe redis.Pipeliner) error {
+			pipe.Del(uc.sessionKey(userID, deviceID))
+			pipe.SRem(SessionListRedisKey+userID, deviceID)
+			return nil
+		})
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "redis_delete", uc.ReqID)
+			return err
+		}
+	}
+
+	return err
+}
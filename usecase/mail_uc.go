@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/amqpconsumer"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/mail"
+	"kriyapeople/pkg/mailtemplate"
+	"kriyapeople/usecase/viewmodel"
+	"time"
+
+	streadway "github.com/streadway/amqp"
+)
+
+var (
+	// MailTemplateActivation ...
+	MailTemplateActivation = "activation"
+	// MailTemplateResetPassword ...
+	MailTemplateResetPassword = "reset_password"
+	// MailTemplateChangeEmail ...
+	MailTemplateChangeEmail = "change_email"
+)
+
+// mailLinkEnvs link of the template, the key of the job is appended
+var mailLinkEnvs = map[string]string{
+	MailTemplateActivation:    "MAIL_ACTIVATION_URL",
+	MailTemplateResetPassword: "MAIL_RESET_PASSWORD_URL",
+	MailTemplateChangeEmail:   "MAIL_CHANGE_EMAIL_URL",
+}
+
+// MailPreviewBody sample job used by the preview, keep it in sync with the golden file test
+var MailPreviewBody = map[string]interface{}{
+	"email":    "john.doe@example.com",
+	"username": "John Doe",
+	"key":      "preview-key",
+}
+
+// MailUC ...
+type MailUC struct {
+	*ContractUC
+}
+
+// language language of the recipient: the job, the stored preference, then APP_LOCALE
+func (uc MailUC) language(body map[string]interface{}) string {
+	if language := interfacepkg.InterfaceStringToString(body, "language"); language != "" {
+		return language
+	}
+	adminUc := AdminUC{ContractUC: uc.ContractUC}
+	admin, err := adminUc.FindByEmail(interfacepkg.InterfaceStringToString(body, "email"), false)
+	if err == nil && admin.Information.Language != "" {
+		return admin.Information.Language
+	}
+
+	return uc.EnvConfig["APP_LOCALE"]
+}
+
+// Render render the template of the job in the language, the link is built from the key
+func (uc MailUC) Render(template, language string, body map[string]interface{}) (res mailtemplate.Rendered, err error) {
+	linkEnv, ok := mailLinkEnvs[template]
+	if !ok || uc.MailTemplate == nil {
+		return res, errors.New(helper.InvalidMailTemplate)
+	}
+
+	data := map[string]interface{}{}
+	for k, v := range body {
+		data[k] = v
+	}
+	data["link"] = uc.EnvConfig[linkEnv] + interfacepkg.InterfaceStringToString(body, "key")
+
+	return uc.MailTemplate.Render(template, language, data)
+}
+
+// build ...
+func (uc MailUC) build(template string, body map[string]interface{}) (res mail.Message, err error) {
+	rendered, err := uc.Render(template, uc.language(body), body)
+	if err != nil {
+		return res, err
+	}
+
+	res = mail.Message{
+		FromEmail: uc.EnvConfig["MAIL_FROM_EMAIL"],
+		FromName:  uc.EnvConfig["MAIL_FROM_NAME"],
+		ToEmail:   interfacepkg.InterfaceStringToString(body, "email"),
+		ToName:    interfacepkg.InterfaceStringToString(body, "username"),
+		Subject:   rendered.Subject,
+		Text:      rendered.Text,
+		HTML:      rendered.HTML,
+		Tags:      []string{template, rendered.Locale},
+	}
+
+	return res, err
+}
+
+// Templates list the template types and locales
+func (uc MailUC) Templates() (res viewmodel.MailTemplateVM, err error) {
+	if uc.MailTemplate == nil {
+		return res, errors.New(helper.InvalidMailTemplate)
+	}
+
+	res = viewmodel.MailTemplateVM{
+		Types:         uc.MailTemplate.Types(),
+		Locales:       uc.MailTemplate.Locales(),
+		DefaultLocale: uc.EnvConfig["APP_LOCALE"],
+	}
+
+	return res, err
+}
+
+// Preview render the template with sample data
+func (uc MailUC) Preview(template, locale string) (res mailtemplate.Rendered, err error) {
+	ctx := "MailUC.Preview"
+
+	if locale == "" {
+		locale = uc.EnvConfig["APP_LOCALE"]
+	}
+	res, err = uc.Render(template, locale, MailPreviewBody)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "render", uc.ReqID)
+		return res, errors.New(helper.InvalidMailTemplate)
+	}
+
+	return res, err
+}
+
+// Deliver send the queued mail through the mail driver and record the attempt. Mail accepted by the provider
+// on the previous attempt is skipped, provider failure is retried and rejected recipient is dead lettered
+func (uc MailUC) Deliver(template, queueMessageID string, attempt int, body map[string]interface{}) (err error) {
+	ctx := "MailUC.Deliver"
+
+	if interfacepkg.InterfaceStringToString(body, "email") == "" {
+		logruslogger.Log(logruslogger.WarnLevel, template, ctx, "empty_email", uc.ReqID)
+		return errors.New(helper.InvalidEmail)
+	}
+	msg, err := uc.build(template, body)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "build", uc.ReqID)
+		return err
+	}
+	if uc.Mailer == nil {
+		logruslogger.Log(logruslogger.WarnLevel, "", ctx, "mailer_not_configured", uc.ReqID)
+		return amqpconsumer.Requeue(errors.New(helper.SendMail))
+	}
+
+	m := model.NewMailDeliveryModel(uc.DB)
+	delivered, err := m.ExistDelivered(queueMessageID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "exist_delivered", uc.ReqID)
+		return amqpconsumer.Requeue(err)
+	}
+	if delivered {
+		return nil
+	}
+
+	result, sendErr := uc.Mailer.Send(msg)
+	delivery := model.MailDeliveryEntity{
+		QueueMessageID:    queueMessageID,
+		Template:          template,
+		Recipient:         msg.ToEmail,
+		Driver:            uc.Mailer.Name(),
+		Status:            model.MailDeliveryStatusSent,
+		ProviderMessageID: sql.NullString{String: result.MessageID, Valid: result.MessageID != ""},
+		Attempt:           attempt,
+	}
+	if result.Status == mail.StatusQueued {
+		delivery.Status = model.MailDeliveryStatusQueued
+	}
+	if sendErr != nil {
+		delivery.Status = model.MailDeliveryStatusFailed
+		delivery.Error = sql.NullString{String: sendErr.Error(), Valid: true}
+	}
+	_, err = m.Store(delivery, time.Now().UTC())
+	if err != nil {
+		// The mail is sent already, losing the record is better than sending it twice
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "store_delivery", uc.ReqID)
+	}
+
+	if sendErr != nil {
+		logruslogger.Log(logruslogger.WarnLevel, sendErr.Error(), ctx, "send", uc.ReqID)
+		if sendErr == mail.ErrRejected {
+			return sendErr
+		}
+		return amqpconsumer.Requeue(sendErr)
+	}
+
+	return nil
+}
+
+// mailConsumer decode the mail job of the delivery, invalid job is dead lettered right away
+func mailConsumer(d streadway.Delivery, contractUC *ContractUC, template string) error {
+	ctx := "mailConsumer"
+
+	body := map[string]interface{}{}
+	err := json.Unmarshal(d.Body, &body)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "json_unmarshal", contractUC.ReqID)
+		return err
+	}
+
+	uc := MailUC{ContractUC: contractUC}
+
+	return uc.Deliver(template, amqpconsumer.MessageID(d), amqpconsumer.RetryCount(d.Headers)+1, body)
+}
+
+// ActivationMailConsumer ...
+func ActivationMailConsumer(d streadway.Delivery, contractUC *ContractUC) error {
+	return mailConsumer(d, contractUC, MailTemplateActivation)
+}
+
+// ResetPasswordMailConsumer ...
+func ResetPasswordMailConsumer(d streadway.Delivery, contractUC *ContractUC) error {
+	return mailConsumer(d, contractUC, MailTemplateResetPassword)
+}
+
+// ChangeEmailMailConsumer ...
+func ChangeEmailMailConsumer(d streadway.Delivery, contractUC *ContractUC) error {
+	return mailConsumer(d, contractUC, MailTemplateChangeEmail)
+}
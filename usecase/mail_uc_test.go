@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"kriyapeople/pkg/amqpconsumer"
+	"kriyapeople/pkg/mail"
+	"kriyapeople/pkg/mailtemplate"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// deliveryDB fake database of the mail deliveries, EXISTS query answer delivered and the other
+// query return a new id. Every query is recorded
+type deliveryDB struct {
+	mu        sync.Mutex
+	delivered bool
+	queries   []string
+}
+
+var (
+	deliveryDBMu sync.Mutex
+	// deliveryDBs database of every test case by the dsn
+	deliveryDBs = map[string]*deliveryDB{}
+)
+
+func init() {
+	sql.Register("mail_delivery_fake", deliveryDriver{})
+}
+
+// deliveryDriver open the database of the dsn
+type deliveryDriver struct{}
+
+func (deliveryDriver) Open(name string) (driver.Conn, error) {
+	deliveryDBMu.Lock()
+	defer deliveryDBMu.Unlock()
+
+	return deliveryConn{db: deliveryDBs[name]}, nil
+}
+
+func (db *deliveryDB) inserted() bool {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	for _, query := range db.queries {
+		if strings.Contains(query, "INSERT") {
+			return true
+		}
+	}
+
+	return false
+}
+
+type deliveryConn struct {
+	db *deliveryDB
+}
+
+func (c deliveryConn) Prepare(query string) (driver.Stmt, error) {
+	return deliveryStmt{db: c.db, query: query}, nil
+}
+
+func (c deliveryConn) Close() error {
+	return nil
+}
+
+func (c deliveryConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transaction is not supported")
+}
+
+type deliveryStmt struct {
+	db    *deliveryDB
+	query string
+}
+
+func (s deliveryStmt) Close() error {
+	return nil
+}
+
+func (s deliveryStmt) NumInput() int {
+	return -1
+}
+
+func (s deliveryStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, errors.New("exec is not supported")
+}
+
+func (s deliveryStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.queries = append(s.db.queries, s.query)
+
+	var value driver.Value = "delivery-1"
+	if strings.Contains(s.query, "EXISTS") {
+		value = s.db.delivered
+	}
+
+	return &deliveryRows{value: value}, nil
+}
+
+type deliveryRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *deliveryRows) Columns() []string {
+	return []string{"res"}
+}
+
+func (r *deliveryRows) Close() error {
+	return nil
+}
+
+func (r *deliveryRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+
+	return nil
+}
+
+// mailer fake mail driver which count the sent mail
+type mailer struct {
+	err  error
+	sent int
+}
+
+func (m *mailer) Name() string {
+	return "fake"
+}
+
+func (m *mailer) Send(msg mail.Message) (res mail.Result, err error) {
+	m.sent++
+	if m.err != nil {
+		return res, m.err
+	}
+
+	return mail.Result{MessageID: "<1@kriyapeople.com>", Status: mail.StatusSent}, nil
+}
+
+func TestMailDeliver(t *testing.T) {
+	engine, err := mailtemplate.New(filepath.Join("..", "files", "mail", "template"), "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]struct {
+		delivered   bool
+		sendErr     error
+		wantSent    int
+		wantStored  bool
+		wantRequeue bool
+		wantErr     error
+	}{
+		"already delivered": {delivered: true, wantSent: 0},
+		"sent":              {wantSent: 1, wantStored: true},
+		"rejected":          {sendErr: mail.ErrRejected, wantSent: 1, wantStored: true, wantErr: mail.ErrRejected},
+		"temporary failure": {sendErr: errors.New("451 try again"), wantSent: 1, wantStored: true, wantRequeue: true},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			db := &deliveryDB{delivered: c.delivered}
+			deliveryDBMu.Lock()
+			deliveryDBs[name] = db
+			deliveryDBMu.Unlock()
+			conn, err := sql.Open("mail_delivery_fake", name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			fake := &mailer{err: c.sendErr}
+			uc := MailUC{ContractUC: &ContractUC{
+				DB:           conn,
+				Mailer:       fake,
+				MailTemplate: engine,
+				EnvConfig:    map[string]string{"APP_LOCALE": "en", "MAIL_FROM_EMAIL": "no-reply@kriyapeople.com"},
+			}}
+			body := map[string]interface{}{"email": "john.doe@example.com", "username": "John Doe", "key": "k", "language": "en"}
+
+			err = uc.Deliver(MailTemplateActivation, "queue-message-1", 1, body)
+			switch {
+			case c.wantRequeue:
+				if !amqpconsumer.IsRequeue(err) {
+					t.Fatalf("expected requeue, got %v", err)
+				}
+			case err != c.wantErr:
+				t.Fatalf("expected %v, got %v", c.wantErr, err)
+			}
+			if fake.sent != c.wantSent {
+				t.Fatalf("expected %d sent mail, got %d", c.wantSent, fake.sent)
+			}
+			if db.inserted() != c.wantStored {
+				t.Fatalf("expected stored delivery %v", c.wantStored)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"kriyapeople/model"
+	"kriyapeople/pkg/amqp"
+	"kriyapeople/pkg/event"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase/viewmodel"
+	"time"
+)
+
+var (
+	// DefaultOutboxRelayInterval ...
+	DefaultOutboxRelayInterval = time.Second
+	// DefaultOutboxRelayLimit ...
+	DefaultOutboxRelayLimit = 100
+	// DefaultOutboxBackoffMin ...
+	DefaultOutboxBackoffMin = time.Second
+	// DefaultOutboxBackoffMax ...
+	DefaultOutboxBackoffMax = 10 * time.Minute
+)
+
+// OutboxUC ...
+type OutboxUC struct {
+	*ContractUC
+}
+
+// AddEvent write the domain event into outbox and queue its webhook deliveries, nil tx is used for event
+// without business change
+func (uc OutboxUC) AddEvent(tx *sql.Tx, e event.Envelope) (err error) {
+	err = uc.store(tx, amqp.EventExchange, e.Type, "", e.Map())
+	if err != nil {
+		return err
+	}
+
+	webhookUc := WebhookUC{ContractUC: uc.ContractUC}
+
+	return webhookUc.AddDeliveries(tx, e)
+}
+
+// store ...
+func (uc OutboxUC) store(tx *sql.Tx, exchange, routingKey, deadLetterKey string, payload map[string]interface{}) (err error) {
+	ctx := "OutboxUC.store"
+
+	m := model.NewOutboxModel(uc.DB)
+	if tx != nil {
+		m = model.NewOutboxModelTx(tx)
+	}
+	_, err = m.Store(exchange, routingKey, deadLetterKey, interfacepkg.Marshall(payload), time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return err
+	}
+
+	return err
+}
+
+// backoff exponential delay of the next attempt, capped by OUTBOX_BACKOFF_MAX
+func (uc OutboxUC) backoff(attempts int) time.Duration {
+	min, err := time.ParseDuration(uc.EnvConfig["OUTBOX_BACKOFF_MIN"])
+	if err != nil || min <= 0 {
+		min = DefaultOutboxBackoffMin
+	}
+	max, err := time.ParseDuration(uc.EnvConfig["OUTBOX_BACKOFF_MAX"])
+	if err != nil || max <= 0 {
+		max = DefaultOutboxBackoffMax
+	}
+
+	res := min
+	for i := 0; i < attempts && res < max; i++ {
+		res *= 2
+	}
+	if res > max {
+		res = max
+	}
+
+	return res
+}
+
+// lease time given to publish count rows before they can be claimed again, a publish can wait for a pool
+// channel and then for the confirmation
+func (uc OutboxUC) lease(count int) time.Duration {
+	confirmTimeout, err := time.ParseDuration(uc.EnvConfig["AMQP_CONFIRM_TIMEOUT"])
+	if err != nil || confirmTimeout <= 0 {
+		confirmTimeout = amqp.DefaultConfirmTimeout
+	}
+
+	return time.Duration(count+1) * 2 * confirmTimeout
+}
+
+// Relay claim the due outbox rows and publish them outside of the claiming transaction, the row is marked
+// as sent only after the broker confirm it, so the message can be published more than once but never lost
+func (uc OutboxUC) Relay(limit int) (claimed int, err error) {
+	ctx := "OutboxUC.Relay"
+
+	m := model.NewOutboxModel(uc.DB)
+	data, err := m.Claim(time.Now().UTC(), uc.lease(limit), limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "claim", uc.ReqID)
+		return claimed, err
+	}
+
+	for _, d := range data {
+		body := map[string]interface{}{}
+		err = json.Unmarshal([]byte(d.Payload), &body)
+		if err == nil {
+			err = uc.publishRow(&d, body)
+		}
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, d.ID+" "+err.Error(), ctx, "publish", uc.ReqID)
+			_, err = m.MarkRetry(d.ID, err.Error(), time.Now().UTC().Add(uc.backoff(d.Attempts)))
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, d.ID+" "+err.Error(), ctx, "mark_retry", uc.ReqID)
+			}
+			continue
+		}
+
+		_, err = m.MarkSent(d.ID, time.Now().UTC())
+		if err != nil {
+			// The row is published again after the lease, the consumer detect it by the message id
+			logruslogger.Log(logruslogger.WarnLevel, d.ID+" "+err.Error(), ctx, "mark_sent", uc.ReqID)
+		}
+	}
+
+	return len(data), nil
+}
+
+// publishRow the row id is the message id, so the duplicate of the same row can be detected by the consumer
+func (uc OutboxUC) publishRow(d *model.OutboxEntity, body map[string]interface{}) error {
+	ctx := amqp.WithMessageID(amqp.WithoutBuffer(context.Background()), d.ID)
+	if d.Exchange != "" {
+		if uc.Publisher == nil {
+			return errors.New("amqp_publisher_not_configured")
+		}
+		return uc.Publisher.PublishExchange(ctx, d.Exchange, d.RoutingKey, body)
+	}
+
+	return uc.Publish(ctx, d.RoutingKey, d.DeadLetterKey, body)
+}
+
+// Lag ...
+func (uc OutboxUC) Lag() (res viewmodel.OutboxLagVM, err error) {
+	ctx := "OutboxUC.Lag"
+
+	m := model.NewOutboxModel(uc.DB)
+	data, err := m.Lag(time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+	res = viewmodel.OutboxLagVM{
+		Pending:    data.Pending,
+		LagSeconds: data.LagSeconds,
+	}
+
+	return res, err
+}
+
+// OutboxRelayScheduler relay the outbox every OUTBOX_RELAY_INTERVAL, a full batch is followed right away
+func OutboxRelayScheduler(contractUC *ContractUC) {
+	interval, err := time.ParseDuration(contractUC.EnvConfig["OUTBOX_RELAY_INTERVAL"])
+	if err != nil || interval <= 0 {
+		interval = DefaultOutboxRelayInterval
+	}
+	limit := str.StringToInt(contractUC.EnvConfig["OUTBOX_RELAY_LIMIT"])
+	if limit <= 0 {
+		limit = DefaultOutboxRelayLimit
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		uc := OutboxUC{ContractUC: contractUC}
+		for {
+			claimed, err := uc.Relay(limit)
+			if err != nil || claimed < limit {
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"errors"
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/hmacsha"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/str"
+	"kriyapeople/server/request"
+	"kriyapeople/usecase/viewmodel"
+	"time"
+
+	"github.com/rs/xid"
+)
+
+var (
+	// DefaultSignatureTolerance maximum age of the signed request timestamp
+	DefaultSignatureTolerance = 5 * time.Minute
+	// PartnerNonceRedisKey prefix of redis key for every used nonce, followed by client id and nonce
+	PartnerNonceRedisKey = "partnerNonce"
+
+	partnerClientIDPrefix = "pc_"
+)
+
+// PartnerClientUC ...
+type PartnerClientUC struct {
+	*ContractUC
+}
+
+// SignedRequest partner request part covered by the signature
+type SignedRequest struct {
+	ClientID  string
+	Signature string
+	Timestamp int64
+	Nonce     string
+	Method    string
+	Path      string
+	Body      []byte
+}
+
+// BuildBody ...
+func (uc PartnerClientUC) BuildBody(data *model.PartnerClientEntity, res *viewmodel.PartnerClientVM) {
+	res.ID = data.ID
+	res.ClientID = data.ClientID
+	res.Name = data.Name
+	res.IsActive = data.IsActive
+	res.LastUsedAt = data.LastUsedAt.String
+	res.CreatedAt = data.CreatedAt
+	res.UpdatedAt = data.UpdatedAt
+}
+
+// FindAll ...
+func (uc PartnerClientUC) FindAll(page, limit int) (res []viewmodel.PartnerClientVM, pagination viewmodel.PaginationVM, err error) {
+	ctx := "PartnerClientUC.FindAll"
+
+	limit = uc.LimitMax(limit)
+	limit, offset := uc.PaginationPageOffset(page, limit)
+
+	m := model.NewPartnerClientModel(uc.DB)
+	data, count, err := m.FindAll(offset, limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, pagination, err
+	}
+	pagination = PaginationRes(page, count, limit)
+
+	for _, r := range data {
+		temp := viewmodel.PartnerClientVM{}
+		uc.BuildBody(&r, &temp)
+		res = append(res, temp)
+	}
+
+	return res, pagination, err
+}
+
+// FindByID ...
+func (uc PartnerClientUC) FindByID(id string) (res viewmodel.PartnerClientVM, err error) {
+	ctx := "PartnerClientUC.FindByID"
+
+	m := model.NewPartnerClientModel(uc.DB)
+	data, err := m.FindByID(id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+	uc.BuildBody(&data, &res)
+
+	return res, err
+}
+
+// newSecret generate the signing secret, only the encrypted secret is stored
+func (uc PartnerClientUC) newSecret() (secret, encrypted string, err error) {
+	secret = str.RandomSecureString(32)
+	encrypted, err = uc.Aes.Encrypt(secret)
+
+	return secret, encrypted, err
+}
+
+// Create ...
+func (uc PartnerClientUC) Create(data *request.PartnerClientRequest) (res viewmodel.PartnerClientVM, err error) {
+	ctx := "PartnerClientUC.Create"
+
+	secret, encrypted, err := uc.newSecret()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "new_secret", uc.ReqID)
+		return res, err
+	}
+
+	m := model.NewPartnerClientModel(uc.DB)
+	id, err := m.Store(model.PartnerClientEntity{
+		ClientID: partnerClientIDPrefix + xid.New().String(),
+		Name:     data.Name,
+		Secret:   encrypted,
+		IsActive: true,
+	}, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res, err = uc.FindByID(id)
+	res.Secret = secret
+
+	return res, err
+}
+
+// UpdateStatus disabled client is rejected on the next request
+func (uc PartnerClientUC) UpdateStatus(id string, data *request.PartnerClientStatusRequest) (res viewmodel.PartnerClientVM, err error) {
+	ctx := "PartnerClientUC.UpdateStatus"
+
+	m := model.NewPartnerClientModel(uc.DB)
+	_, err = m.UpdateStatus(id, data.IsActive, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return uc.FindByID(id)
+}
+
+// RotateSecret replace the signing secret, request signed with the old secret is rejected right away
+func (uc PartnerClientUC) RotateSecret(id string) (res viewmodel.PartnerClientVM, err error) {
+	ctx := "PartnerClientUC.RotateSecret"
+
+	secret, encrypted, err := uc.newSecret()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "new_secret", uc.ReqID)
+		return res, err
+	}
+
+	m := model.NewPartnerClientModel(uc.DB)
+	_, err = m.UpdateSecret(id, encrypted, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res, err = uc.FindByID(id)
+	res.Secret = secret
+
+	return res, err
+}
+
+// Delete ...
+func (uc PartnerClientUC) Delete(id string) (res viewmodel.PartnerClientVM, err error) {
+	ctx := "PartnerClientUC.Delete"
+
+	m := model.NewPartnerClientModel(uc.DB)
+	res.ID, err = m.Destroy(id, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return res, err
+}
+
+// tolerance ...
+func (uc PartnerClientUC) tolerance() time.Duration {
+	res, err := time.ParseDuration(uc.EnvConfig["PARTNER_SIGNATURE_TOLERANCE"])
+	if err != nil || res <= 0 {
+		return DefaultSignatureTolerance
+	}
+
+	return res
+}
+
+// Verify check the signed request of the partner client. The nonce is stored only after the signature
+// is valid and it is kept for twice the tolerance, so the same request can not be sent again in the window
+func (uc PartnerClientUC) Verify(data SignedRequest, now time.Time) (res viewmodel.PartnerClientVM, err error) {
+	ctx := "PartnerClientUC.Verify"
+
+	tolerance := uc.tolerance()
+	age := now.Sub(time.Unix(data.Timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		logruslogger.Log(logruslogger.WarnLevel, data.ClientID, ctx, "expired_timestamp", uc.ReqID)
+		return res, errors.New(helper.ExpiredSignature)
+	}
+
+	m := model.NewPartnerClientModel(uc.DB)
+	client, err := m.FindByClientID(data.ClientID)
+	if err != nil || !client.IsActive {
+		logruslogger.Log(logruslogger.WarnLevel, data.ClientID, ctx, "invalid_client", uc.ReqID)
+		return res, errors.New(helper.InvalidSignature)
+	}
+	secret, err := uc.Aes.Decrypt(client.Secret)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "decrypt_secret", uc.ReqID)
+		return res, errors.New(helper.InvalidSignature)
+	}
+
+	cred := hmacsha.Credential{Key: secret}
+	content := hmacsha.CanonicalRequest(data.Method, data.Path, data.Timestamp, data.Nonce, data.Body)
+	if !cred.Verify(content, data.Signature) {
+		logruslogger.Log(logruslogger.WarnLevel, data.ClientID, ctx, "invalid_signature", uc.ReqID)
+		return res, errors.New(helper.InvalidSignature)
+	}
+
+	ok, err := uc.Lock(PartnerNonceRedisKey+client.ClientID+":"+data.Nonce, "1", 2*tolerance)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		logruslogger.Log(logruslogger.WarnLevel, data.ClientID+" "+data.Nonce, ctx, "replayed_nonce", uc.ReqID)
+		return res, errors.New(helper.ReplayedNonce)
+	}
+
+	_, err = m.UpdateLastUsed(client.ID, now.UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "update_last_used", uc.ReqID)
+	}
+	uc.BuildBody(&client, &res)
+
+	return res, nil
+}
@@ -0,0 +1,153 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"kriyapeople/helper"
+	"kriyapeople/pkg/amqp"
+	"kriyapeople/pkg/amqpconsumer"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/usecase/viewmodel"
+	"time"
+)
+
+var (
+	// DefaultDeadLetterLimit ...
+	DefaultDeadLetterLimit = 20
+	// MaxDeadLetterLimit maximum messages read from the dead letter queue on every request
+	MaxDeadLetterLimit = 1000
+)
+
+// QueueUC ...
+type QueueUC struct {
+	*ContractUC
+}
+
+// openDeadLetter open the dead letter queue of the worker queue name
+func (uc QueueUC) openDeadLetter(name string) (res *amqpconsumer.DeadLetter, queue amqp.Queue, err error) {
+	ctx := "QueueUC.openDeadLetter"
+
+	queue, ok := amqp.FindQueue(name)
+	if !ok {
+		logruslogger.Log(logruslogger.WarnLevel, name, ctx, "invalid_queue", uc.ReqID)
+		return res, queue, errors.New(helper.InvalidQueue)
+	}
+
+	res, err = amqpconsumer.OpenDeadLetter(uc.EnvConfig["AMQP_URL"], queue.Incoming, queue.DeadLetter)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "open", uc.ReqID)
+		return res, queue, errors.New(helper.QueueUnavailable)
+	}
+
+	return res, queue, err
+}
+
+// ListDeadLetter list the messages at the head of the dead letter queue without removing them
+func (uc QueueUC) ListDeadLetter(name string, limit int) (res viewmodel.DeadLetterVM, err error) {
+	ctx := "QueueUC.ListDeadLetter"
+
+	if limit <= 0 {
+		limit = DefaultDeadLetterLimit
+	}
+	if limit > MaxDeadLetterLimit {
+		limit = MaxDeadLetterLimit
+	}
+
+	dl, queue, err := uc.openDeadLetter(name)
+	if err != nil {
+		return res, err
+	}
+	defer dl.Close()
+
+	res = viewmodel.DeadLetterVM{
+		Queue:      queue.Name,
+		DeadLetter: queue.DeadLetter,
+		Messages:   []viewmodel.DeadLetterMessageVM{},
+	}
+	res.Total, err = dl.Count()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "count", uc.ReqID)
+		return res, errors.New(helper.QueueUnavailable)
+	}
+
+	data, err := dl.List(limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "list", uc.ReqID)
+		return res, errors.New(helper.QueueUnavailable)
+	}
+	for _, d := range data {
+		// Json body is shown as object, any other body as string
+		var body interface{}
+		if json.Unmarshal(d.Body, &body) != nil {
+			body = string(d.Body)
+		}
+		publishedAt := ""
+		if !d.Timestamp.IsZero() {
+			publishedAt = d.Timestamp.UTC().Format(time.RFC3339)
+		}
+
+		res.Messages = append(res.Messages, viewmodel.DeadLetterMessageVM{
+			ID:          d.ID,
+			ContentType: d.ContentType,
+			Body:        body,
+			RetryCount:  d.RetryCount,
+			LastError:   d.LastError,
+			Reason:      d.Reason,
+			PublishedAt: publishedAt,
+		})
+	}
+
+	return res, err
+}
+
+// ReplayDeadLetter publish the selected messages back into the incoming queue
+func (uc QueueUC) ReplayDeadLetter(name string, ids []string) (res viewmodel.DeadLetterResultVM, err error) {
+	ctx := "QueueUC.ReplayDeadLetter"
+
+	dl, queue, err := uc.openDeadLetter(name)
+	if err != nil {
+		return res, err
+	}
+	defer dl.Close()
+
+	replayed, err := dl.Replay(ids, MaxDeadLetterLimit)
+	res = viewmodel.DeadLetterResultVM{
+		Queue: queue.Name,
+		Count: len(replayed),
+		IDs:   replayed,
+	}
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "replay", uc.ReqID)
+		return res, errors.New(helper.QueueUnavailable)
+	}
+
+	return res, err
+}
+
+// PurgeDeadLetter remove the selected messages, or every message when all is true
+func (uc QueueUC) PurgeDeadLetter(name string, ids []string, all bool) (res viewmodel.DeadLetterResultVM, err error) {
+	ctx := "QueueUC.PurgeDeadLetter"
+
+	if all {
+		ids = nil
+	} else if len(ids) == 0 {
+		logruslogger.Log(logruslogger.WarnLevel, name, ctx, "empty_ids", uc.ReqID)
+		return res, errors.New(helper.InvalidBody)
+	}
+
+	dl, queue, err := uc.openDeadLetter(name)
+	if err != nil {
+		return res, err
+	}
+	defer dl.Close()
+
+	res.Queue = queue.Name
+	res.IDs = ids
+	res.Count, err = dl.Purge(ids, MaxDeadLetterLimit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "purge", uc.ReqID)
+		return res, errors.New(helper.QueueUnavailable)
+	}
+
+	return res, err
+}
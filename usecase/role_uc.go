@@ -1,11 +1,14 @@
 package usecase
 
 import (
+	"database/sql"
 	"kriyapeople/model"
+	"kriyapeople/pkg/event"
 	"kriyapeople/pkg/logruslogger"
 	"kriyapeople/pkg/str"
 	"kriyapeople/usecase/viewmodel"
 	"strings"
+	"time"
 )
 
 // RoleUC ...
@@ -80,3 +83,14 @@ func (uc RoleUC) FindByCode(code string) (res viewmodel.RoleVM, err error) {
 
 	return res, err
 }
+
+// AddChangedEvent write role.changed event of the user into outbox inside the transaction
+func (uc RoleUC) AddChangedEvent(tx *sql.Tx, userID, oldRoleID, newRoleID string) error {
+	outboxUc := OutboxUC{ContractUC: uc.ContractUC}
+
+	return outboxUc.AddEvent(tx, event.New(event.RoleChanged, event.RoleChangedData{
+		UserID:    userID,
+		OldRoleID: oldRoleID,
+		NewRoleID: newRoleID,
+	}, time.Now()))
+}
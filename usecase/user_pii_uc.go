@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"database/sql"
+	"errors"
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase/viewmodel"
+	"strings"
+)
+
+var (
+	// UserPIIFields fields of users data which can be encrypted with USER_ENCRYPTED_FIELDS
+	UserPIIFields = []string{"email", "username"}
+	// DefaultUserPIIMigrateBatch ...
+	DefaultUserPIIMigrateBatch = 100
+
+	// The ciphertext and blind index is kept beside the field, ex: email_enc and email_bidx, the field itself is null
+	userPIIEncSuffix        = "_enc"
+	userPIIBlindIndexSuffix = "_bidx"
+)
+
+// UserPIIUC field level encryption of users data
+type UserPIIUC struct {
+	*ContractUC
+}
+
+// EncryptedFields supported fields listed in USER_ENCRYPTED_FIELDS
+func (uc UserPIIUC) EncryptedFields() (res []string) {
+	for _, field := range strings.Split(uc.EnvConfig["USER_ENCRYPTED_FIELDS"], ",") {
+		field = strings.TrimSpace(field)
+		if str.Contains(UserPIIFields, field) && !str.Contains(res, field) {
+			res = append(res, field)
+		}
+	}
+
+	return res
+}
+
+// BlindIndex deterministic hmac of the case insensitive value, the field is part of the content so the same
+// value in different fields has different index. Empty without USER_BLIND_INDEX_KEY
+func (uc UserPIIUC) BlindIndex(field, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if uc.BlindIndexKey.Key == "" || value == "" {
+		return ""
+	}
+
+	return uc.BlindIndexKey.Encrypt(field + ":" + value)
+}
+
+// Encrypt replace the pii fields in the body with the ciphertext and blind index when the field is encrypted,
+// or clear the ciphertext and blind index when it is not. Fields which are not in the body are left as is,
+// so the body can be merged into the existing data
+func (uc UserPIIUC) Encrypt(body map[string]interface{}) (res map[string]interface{}, err error) {
+	ctx := "UserPIIUC.Encrypt"
+
+	res = map[string]interface{}{}
+	for key, value := range body {
+		res[key] = value
+	}
+
+	fields := uc.EncryptedFields()
+	for _, field := range UserPIIFields {
+		if _, ok := body[field]; !ok {
+			continue
+		}
+
+		value := interfacepkg.InterfaceStringToString(body, field)
+		if !str.Contains(fields, field) || value == "" {
+			res[field] = value
+			res[field+userPIIEncSuffix] = nil
+			res[field+userPIIBlindIndexSuffix] = nil
+			continue
+		}
+		if uc.BlindIndexKey.Key == "" {
+			logruslogger.Log(logruslogger.WarnLevel, field, ctx, "empty_blind_index_key", uc.ReqID)
+			return res, errors.New(helper.InternalServer)
+		}
+
+		encrypted, err := uc.Aes.Encrypt(value)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "encrypt", uc.ReqID)
+			return res, errors.New(helper.InternalServer)
+		}
+		res[field] = nil
+		res[field+userPIIEncSuffix] = encrypted
+		res[field+userPIIBlindIndexSuffix] = uc.BlindIndex(field, value)
+	}
+
+	return res, err
+}
+
+// Decrypt value of the pii field, the ciphertext is used when it exist
+func (uc UserPIIUC) Decrypt(plaintext, encrypted sql.NullString) string {
+	ctx := "UserPIIUC.Decrypt"
+
+	if encrypted.String == "" {
+		return plaintext.String
+	}
+	res, err := uc.Aes.Decrypt(encrypted.String)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "decrypt", uc.ReqID)
+		return ""
+	}
+
+	return res
+}
+
+// migrateData encrypt or decrypt every pii field of the data following USER_ENCRYPTED_FIELDS,
+// existing ciphertext is kept when only its blind index is missing
+func (uc UserPIIUC) migrateData(data map[string]interface{}) (changed bool, err error) {
+	fields := uc.EncryptedFields()
+	for _, field := range UserPIIFields {
+		encKey := field + userPIIEncSuffix
+		indexKey := field + userPIIBlindIndexSuffix
+
+		encrypted := interfacepkg.InterfaceStringToString(data, encKey)
+		value := interfacepkg.InterfaceStringToString(data, field)
+		if encrypted != "" {
+			value, err = uc.Aes.Decrypt(encrypted)
+			if err != nil {
+				return changed, err
+			}
+		}
+
+		if !str.Contains(fields, field) || value == "" {
+			if encrypted == "" && interfacepkg.InterfaceStringToString(data, indexKey) == "" {
+				continue
+			}
+			data[field] = value
+			delete(data, encKey)
+			delete(data, indexKey)
+			changed = true
+			continue
+		}
+
+		index := uc.BlindIndex(field, value)
+		if index == "" {
+			return changed, errors.New("USER_BLIND_INDEX_KEY is not configured")
+		}
+		if encrypted != "" && data[field] == nil && interfacepkg.InterfaceStringToString(data, indexKey) == index {
+			continue
+		}
+		if encrypted == "" {
+			encrypted, err = uc.Aes.Encrypt(value)
+			if err != nil {
+				return changed, err
+			}
+		}
+		data[field] = nil
+		data[encKey] = encrypted
+		data[indexKey] = index
+		changed = true
+	}
+
+	return changed, err
+}
+
+// Migrate apply USER_ENCRYPTED_FIELDS to the stored users, run it after the fields or the blind index key
+// is changed. User which can not be decrypted is counted as failed and left as is
+func (uc UserPIIUC) Migrate(dryRun bool, batch int) (res viewmodel.UserPIIMigrateVM, err error) {
+	ctx := "UserPIIUC.Migrate"
+
+	if batch <= 0 {
+		batch = DefaultUserPIIMigrateBatch
+	}
+	res.Fields = uc.EncryptedFields()
+
+	m := model.NewAdminModel(uc.DB)
+	lastID := ""
+	for {
+		data, err := m.FindAllData(lastID, batch)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+			return res, err
+		}
+		if len(data) == 0 {
+			break
+		}
+
+		for _, row := range data {
+			lastID = row.ID
+			res.Checked++
+
+			body := interfacepkg.UnmarshallMap(row.Data)
+			changed, err := uc.migrateData(body)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, row.ID+" "+err.Error(), ctx, "migrate_data", uc.ReqID)
+				res.Failed++
+				continue
+			}
+			if !changed {
+				continue
+			}
+			if dryRun {
+				res.Updated++
+				continue
+			}
+
+			_, err = m.ReplaceData(row.ID, row.Data, interfacepkg.MarshallMap(body))
+			if err == sql.ErrNoRows {
+				res.Changed++
+				continue
+			}
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+				return res, err
+			}
+			res.Updated++
+		}
+	}
+
+	return res, nil
+}
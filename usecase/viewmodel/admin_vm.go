@@ -2,14 +2,16 @@ package viewmodel
 
 // UserVM ...
 type UserVM struct {
-	ID          string     `json:"id"`
-	RoleID      string     `json:"role_id"`
-	RoleName    string     `json:"role_name"`
-	Data        string     `json:"data"`
-	Information UserDataVM `json:"information"`
-	CreatedAt   string     `json:"created_at"`
-	UpdatedAt   string     `json:"updated_at"`
-	DeletedAt   string     `json:"deleted_at"`
+	ID              string     `json:"id"`
+	RoleID          string     `json:"role_id"`
+	RoleName        string     `json:"role_name"`
+	Data            string     `json:"data"`
+	Information     UserDataVM `json:"information"`
+	ProfileImageID  string     `json:"profile_image_id"`
+	ProfileImageURL string     `json:"profile_image_url"`
+	CreatedAt       string     `json:"created_at"`
+	UpdatedAt       string     `json:"updated_at"`
+	DeletedAt       string     `json:"deleted_at"`
 }
 
 // UserDataVM ...
@@ -18,6 +20,7 @@ type UserDataVM struct {
 	Status   StatusVM `json:"status"`
 	Password string   `json:"password"`
 	UserName string   `json:"username"`
+	Language string   `json:"language,omitempty"`
 }
 
 // StatusVM ...
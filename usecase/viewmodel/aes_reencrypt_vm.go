@@ -0,0 +1,12 @@
+package viewmodel
+
+// AesReencryptVM ...
+type AesReencryptVM struct {
+	Table       string `json:"table"`
+	Column      string `json:"column"`
+	Field       string `json:"field,omitempty"`
+	Checked     int    `json:"checked"`
+	Reencrypted int    `json:"reencrypted"`
+	Changed     int    `json:"changed"`
+	Failed      int    `json:"failed"`
+}
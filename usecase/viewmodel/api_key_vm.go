@@ -0,0 +1,24 @@
+package viewmodel
+
+// APIKeyVM key is shown only when the key is created
+type APIKeyVM struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Key        string   `json:"key,omitempty"`
+	KeyPrefix  string   `json:"key_prefix"`
+	Scopes     []string `json:"scopes"`
+	AllowedIPs []string `json:"allowed_ips"`
+	ExpiresAt  string   `json:"expires_at"`
+	LastUsedAt string   `json:"last_used_at"`
+	LastUsedIP string   `json:"last_used_ip"`
+	CreatedBy  string   `json:"created_by"`
+	RevokedAt  string   `json:"revoked_at"`
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+// APIKeyScopeVM ...
+type APIKeyScopeVM struct {
+	Scope string `json:"scope"`
+	Role  string `json:"role"`
+}
@@ -0,0 +1,8 @@
+package viewmodel
+
+// EncryptionSessionVM key is sent only in the encrypted response of the session creation
+type EncryptionSessionVM struct {
+	ID        string `json:"id"`
+	Key       string `json:"key"`
+	ExpiresAt string `json:"expires_at"`
+}
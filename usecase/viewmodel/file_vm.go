@@ -2,12 +2,41 @@ package viewmodel
 
 // FileVM ....
 type FileVM struct {
-	ID         string `json:"id"`
-	Type       string `json:"type"`
-	URL        string `json:"url"`
-	TempURL    string `json:"temp_url"`
-	UserUpload string `json:"user_upload"`
-	CreatedAt  string `json:"created_at"`
-	UpdatedAt  string `json:"updated_at"`
-	DeletedAt  string `json:"deleted_at"`
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	URL        string            `json:"url"`
+	TempURL    string            `json:"temp_url"`
+	UserUpload string            `json:"user_upload"`
+	Status     string            `json:"status"`
+	Thumbnails map[string]string `json:"thumbnails"`
+	Size       int64             `json:"size"`
+	CreatedAt  string            `json:"created_at"`
+	UpdatedAt  string            `json:"updated_at"`
+	DeletedAt  string            `json:"deleted_at"`
+}
+
+// FileCleanReportVM ...
+type FileCleanReportVM struct {
+	DryRun  bool     `json:"dry_run"`
+	Before  string   `json:"before"`
+	Count   int      `json:"count"`
+	Deleted []string `json:"deleted"`
+	Failed  []string `json:"failed"`
+}
+
+// FileUsageTypeVM ...
+type FileUsageTypeVM struct {
+	Files int64 `json:"files"`
+	Bytes int64 `json:"bytes"`
+}
+
+// FileUsageVM ...
+type FileUsageVM struct {
+	UserID   string                     `json:"user_id"`
+	UserName string                     `json:"username"`
+	Files    int64                      `json:"files"`
+	Bytes    int64                      `json:"bytes"`
+	MaxFiles int64                      `json:"max_files"`
+	MaxBytes int64                      `json:"max_bytes"`
+	Types    map[string]FileUsageTypeVM `json:"types"`
 }
@@ -0,0 +1,8 @@
+package viewmodel
+
+// MailTemplateVM ...
+type MailTemplateVM struct {
+	Types         []string `json:"types"`
+	Locales       []string `json:"locales"`
+	DefaultLocale string   `json:"default_locale"`
+}
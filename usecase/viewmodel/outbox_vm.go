@@ -0,0 +1,7 @@
+package viewmodel
+
+// OutboxLagVM ...
+type OutboxLagVM struct {
+	Pending    int64   `json:"pending"`
+	LagSeconds float64 `json:"lag_seconds"`
+}
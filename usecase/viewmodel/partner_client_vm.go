@@ -0,0 +1,13 @@
+package viewmodel
+
+// PartnerClientVM secret is shown only when the client is created or the secret is rotated
+type PartnerClientVM struct {
+	ID         string `json:"id"`
+	ClientID   string `json:"client_id"`
+	Name       string `json:"name"`
+	Secret     string `json:"secret,omitempty"`
+	IsActive   bool   `json:"is_active"`
+	LastUsedAt string `json:"last_used_at"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
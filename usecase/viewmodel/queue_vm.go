@@ -0,0 +1,27 @@
+package viewmodel
+
+// DeadLetterMessageVM ...
+type DeadLetterMessageVM struct {
+	ID          string      `json:"id"`
+	ContentType string      `json:"content_type"`
+	Body        interface{} `json:"body"`
+	RetryCount  int         `json:"retry_count"`
+	LastError   string      `json:"last_error"`
+	Reason      string      `json:"reason"`
+	PublishedAt string      `json:"published_at"`
+}
+
+// DeadLetterVM ...
+type DeadLetterVM struct {
+	Queue      string                `json:"queue"`
+	DeadLetter string                `json:"dead_letter"`
+	Total      int                   `json:"total"`
+	Messages   []DeadLetterMessageVM `json:"messages"`
+}
+
+// DeadLetterResultVM ...
+type DeadLetterResultVM struct {
+	Queue string   `json:"queue"`
+	Count int      `json:"count"`
+	IDs   []string `json:"ids"`
+}
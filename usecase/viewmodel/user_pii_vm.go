@@ -0,0 +1,10 @@
+package viewmodel
+
+// UserPIIMigrateVM ...
+type UserPIIMigrateVM struct {
+	Fields  []string `json:"fields"`
+	Checked int      `json:"checked"`
+	Updated int      `json:"updated"`
+	Changed int      `json:"changed"`
+	Failed  int      `json:"failed"`
+}
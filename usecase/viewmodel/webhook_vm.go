@@ -0,0 +1,33 @@
+package viewmodel
+
+// WebhookEndpointVM secret is shown only when the endpoint is created or the secret is rotated
+type WebhookEndpointVM struct {
+	ID           string   `json:"id"`
+	URL          string   `json:"url"`
+	Secret       string   `json:"secret,omitempty"`
+	Events       []string `json:"events"`
+	Description  string   `json:"description"`
+	IsActive     bool     `json:"is_active"`
+	FailureCount int      `json:"failure_count"`
+	DisabledAt   string   `json:"disabled_at"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+// WebhookDeliveryVM ...
+type WebhookDeliveryVM struct {
+	ID             string      `json:"id"`
+	EndpointID     string      `json:"endpoint_id"`
+	EventID        string      `json:"event_id"`
+	EventType      string      `json:"event_type"`
+	Payload        interface{} `json:"payload"`
+	Status         string      `json:"status"`
+	Attempts       int         `json:"attempts"`
+	NextAttemptAt  string      `json:"next_attempt_at"`
+	ResponseStatus int64       `json:"response_status"`
+	ResponseBody   string      `json:"response_body"`
+	LastError      string      `json:"last_error"`
+	DurationMs     int64       `json:"duration_ms"`
+	CreatedAt      string      `json:"created_at"`
+	DeliveredAt    string      `json:"delivered_at"`
+}
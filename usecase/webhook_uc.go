@@ -0,0 +1,513 @@
+package usecase
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"kriyapeople/helper"
+	"kriyapeople/model"
+	"kriyapeople/pkg/event"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/str"
+	"kriyapeople/pkg/webhook"
+	"kriyapeople/server/request"
+	"kriyapeople/usecase/viewmodel"
+	"time"
+)
+
+var (
+	// DefaultWebhookRelayInterval ...
+	DefaultWebhookRelayInterval = 5 * time.Second
+	// DefaultWebhookRelayLimit ...
+	DefaultWebhookRelayLimit = 20
+	// DefaultWebhookTimeout ...
+	DefaultWebhookTimeout = 10 * time.Second
+	// DefaultWebhookMaxAttempts attempts before the delivery is marked as failed
+	DefaultWebhookMaxAttempts = 8
+	// DefaultWebhookBackoffMin ...
+	DefaultWebhookBackoffMin = 30 * time.Second
+	// DefaultWebhookBackoffMax ...
+	DefaultWebhookBackoffMax = 6 * time.Hour
+	// DefaultWebhookDisableAfter consecutive failed attempts before the endpoint is disabled
+	DefaultWebhookDisableAfter = 20
+
+	webhookSecretPrefix = "whsec_"
+)
+
+// WebhookUC ...
+type WebhookUC struct {
+	*ContractUC
+}
+
+// BuildBody ...
+func (uc WebhookUC) BuildBody(data *model.WebhookEndpointEntity, res *viewmodel.WebhookEndpointVM) {
+	res.ID = data.ID
+	res.URL = data.URL
+	res.Events = []string{}
+	json.Unmarshal([]byte(data.Events), &res.Events)
+	res.Description = data.Description.String
+	res.IsActive = data.IsActive
+	res.FailureCount = data.FailureCount
+	res.DisabledAt = data.DisabledAt.String
+	res.CreatedAt = data.CreatedAt
+	res.UpdatedAt = data.UpdatedAt
+}
+
+// BuildDeliveryBody ...
+func (uc WebhookUC) BuildDeliveryBody(data *model.WebhookDeliveryEntity, res *viewmodel.WebhookDeliveryVM) {
+	res.ID = data.ID
+	res.EndpointID = data.EndpointID
+	res.EventID = data.EventID
+	res.EventType = data.EventType
+	json.Unmarshal([]byte(data.Payload), &res.Payload)
+	res.Status = data.Status
+	res.Attempts = data.Attempts
+	res.NextAttemptAt = data.NextAttemptAt
+	res.ResponseStatus = data.ResponseStatus.Int64
+	res.ResponseBody = data.ResponseBody.String
+	res.LastError = data.LastError.String
+	res.DurationMs = data.DurationMs.Int64
+	res.CreatedAt = data.CreatedAt
+	res.DeliveredAt = data.DeliveredAt.String
+}
+
+// envDuration ...
+func (uc WebhookUC) envDuration(key string, def time.Duration) time.Duration {
+	res, err := time.ParseDuration(uc.EnvConfig[key])
+	if err != nil || res <= 0 {
+		return def
+	}
+
+	return res
+}
+
+// envInt ...
+func (uc WebhookUC) envInt(key string, def int) int {
+	res := str.StringToInt(uc.EnvConfig[key])
+	if res <= 0 {
+		return def
+	}
+
+	return res
+}
+
+// backoff exponential delay of the next attempt, capped by WEBHOOK_BACKOFF_MAX
+func (uc WebhookUC) backoff(attempts int) time.Duration {
+	min := uc.envDuration("WEBHOOK_BACKOFF_MIN", DefaultWebhookBackoffMin)
+	max := uc.envDuration("WEBHOOK_BACKOFF_MAX", DefaultWebhookBackoffMax)
+
+	res := min
+	for i := 1; i < attempts && res < max; i++ {
+		res *= 2
+	}
+	if res > max {
+		res = max
+	}
+
+	return res
+}
+
+// client ...
+func (uc WebhookUC) client() *webhook.Client {
+	if uc.Webhook != nil {
+		return uc.Webhook
+	}
+
+	return webhook.NewClient(uc.envDuration("WEBHOOK_TIMEOUT", DefaultWebhookTimeout))
+}
+
+// checkEvents every subscribed event must be a known event type
+func (uc WebhookUC) checkEvents(events []string) (res []string, err error) {
+	res = str.Unique(events)
+	for _, e := range res {
+		if !str.Contains(event.Types, e) {
+			return res, errors.New(helper.InvalidWebhookEvent)
+		}
+	}
+
+	return res, err
+}
+
+// FindAll ...
+func (uc WebhookUC) FindAll(page, limit int) (res []viewmodel.WebhookEndpointVM, pagination viewmodel.PaginationVM, err error) {
+	ctx := "WebhookUC.FindAll"
+
+	limit = uc.LimitMax(limit)
+	limit, offset := uc.PaginationPageOffset(page, limit)
+
+	m := model.NewWebhookEndpointModel(uc.DB)
+	data, count, err := m.FindAll(offset, limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, pagination, err
+	}
+	pagination = PaginationRes(page, count, limit)
+
+	for _, r := range data {
+		temp := viewmodel.WebhookEndpointVM{}
+		uc.BuildBody(&r, &temp)
+		res = append(res, temp)
+	}
+
+	return res, pagination, err
+}
+
+// FindByID ...
+func (uc WebhookUC) FindByID(id string) (res viewmodel.WebhookEndpointVM, err error) {
+	ctx := "WebhookUC.FindByID"
+
+	m := model.NewWebhookEndpointModel(uc.DB)
+	data, err := m.FindByID(id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+	uc.BuildBody(&data, &res)
+
+	return res, err
+}
+
+// newSecret generate the signing secret, only the encrypted secret is stored
+func (uc WebhookUC) newSecret() (secret, encrypted string, err error) {
+	secret = webhookSecretPrefix + str.RandomSecureString(32)
+	encrypted, err = uc.Aes.Encrypt(secret)
+
+	return secret, encrypted, err
+}
+
+// Create ...
+func (uc WebhookUC) Create(data *request.WebhookEndpointRequest) (res viewmodel.WebhookEndpointVM, err error) {
+	ctx := "WebhookUC.Create"
+
+	events, err := uc.checkEvents(data.Events)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "check_events", uc.ReqID)
+		return res, err
+	}
+	secret, encrypted, err := uc.newSecret()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "new_secret", uc.ReqID)
+		return res, err
+	}
+
+	now := time.Now().UTC()
+	m := model.NewWebhookEndpointModel(uc.DB)
+	id, err := m.Store(model.WebhookEndpointEntity{
+		URL:         data.URL,
+		Secret:      encrypted,
+		Events:      interfacepkg.Marshall(events),
+		Description: sql.NullString{String: data.Description, Valid: data.Description != ""},
+		IsActive:    data.IsActive,
+	}, now)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res, err = uc.FindByID(id)
+	res.Secret = secret
+
+	return res, err
+}
+
+// Update enabling the disabled endpoint reset the failure counter, pending deliveries resume on the next relay
+func (uc WebhookUC) Update(id string, data *request.WebhookEndpointRequest) (res viewmodel.WebhookEndpointVM, err error) {
+	ctx := "WebhookUC.Update"
+
+	events, err := uc.checkEvents(data.Events)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "check_events", uc.ReqID)
+		return res, err
+	}
+
+	m := model.NewWebhookEndpointModel(uc.DB)
+	_, err = m.Update(id, model.WebhookEndpointEntity{
+		URL:         data.URL,
+		Events:      interfacepkg.Marshall(events),
+		Description: sql.NullString{String: data.Description, Valid: data.Description != ""},
+		IsActive:    data.IsActive,
+	}, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return uc.FindByID(id)
+}
+
+// RotateSecret replace the signing secret, the next attempt is signed with the new secret
+func (uc WebhookUC) RotateSecret(id string) (res viewmodel.WebhookEndpointVM, err error) {
+	ctx := "WebhookUC.RotateSecret"
+
+	secret, encrypted, err := uc.newSecret()
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "new_secret", uc.ReqID)
+		return res, err
+	}
+
+	m := model.NewWebhookEndpointModel(uc.DB)
+	_, err = m.UpdateSecret(id, encrypted, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	res, err = uc.FindByID(id)
+	res.Secret = secret
+
+	return res, err
+}
+
+// Delete ...
+func (uc WebhookUC) Delete(id string) (res viewmodel.WebhookEndpointVM, err error) {
+	ctx := "WebhookUC.Delete"
+
+	m := model.NewWebhookEndpointModel(uc.DB)
+	res.ID, err = m.Destroy(id, time.Now().UTC())
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, err
+	}
+
+	return res, err
+}
+
+// FindAllDelivery delivery log of the endpoint, newest first
+func (uc WebhookUC) FindAllDelivery(id, status string, page, limit int) (res []viewmodel.WebhookDeliveryVM, pagination viewmodel.PaginationVM, err error) {
+	ctx := "WebhookUC.FindAllDelivery"
+
+	limit = uc.LimitMax(limit)
+	limit, offset := uc.PaginationPageOffset(page, limit)
+
+	m := model.NewWebhookDeliveryModel(uc.DB)
+	data, count, err := m.FindAllByEndpoint(id, status, offset, limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "query", uc.ReqID)
+		return res, pagination, err
+	}
+	pagination = PaginationRes(page, count, limit)
+
+	for _, r := range data {
+		temp := viewmodel.WebhookDeliveryVM{}
+		uc.BuildDeliveryBody(&r, &temp)
+		res = append(res, temp)
+	}
+
+	return res, pagination, err
+}
+
+// Redeliver send the event of the delivery again as a new delivery, the attempt is made right away
+// and failure is retried by the relay like any other delivery
+func (uc WebhookUC) Redeliver(id, deliveryID string) (res viewmodel.WebhookDeliveryVM, err error) {
+	ctx := "WebhookUC.Redeliver"
+
+	endpointModel := model.NewWebhookEndpointModel(uc.DB)
+	endpoint, err := endpointModel.FindByID(id)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_endpoint", uc.ReqID)
+		return res, err
+	}
+	if !endpoint.IsActive {
+		logruslogger.Log(logruslogger.WarnLevel, id, ctx, "inactive_endpoint", uc.ReqID)
+		return res, errors.New(helper.InactiveWebhook)
+	}
+
+	m := model.NewWebhookDeliveryModel(uc.DB)
+	old, err := m.FindByID(id, deliveryID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_delivery", uc.ReqID)
+		return res, err
+	}
+
+	// The relay must not take the delivery while it is sent here
+	now := time.Now().UTC()
+	newID, err := m.Store(id, old.EventID, old.EventType, old.Payload, now.Add(uc.lease(1)), now)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "store", uc.ReqID)
+		return res, err
+	}
+	delivery, err := m.FindByID(id, newID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_new_delivery", uc.ReqID)
+		return res, err
+	}
+
+	_, err = uc.deliver(uc.client(), &endpoint, &delivery)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "deliver", uc.ReqID)
+		return res, err
+	}
+
+	delivery, err = m.FindByID(id, newID)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_new_delivery", uc.ReqID)
+		return res, err
+	}
+	uc.BuildDeliveryBody(&delivery, &res)
+
+	return res, err
+}
+
+// AddDeliveries queue the event for every active endpoint subscribed to it, inside the business transaction
+func (uc WebhookUC) AddDeliveries(tx *sql.Tx, e event.Envelope) (err error) {
+	ctx := "WebhookUC.AddDeliveries"
+
+	endpointModel := model.NewWebhookEndpointModel(uc.DB)
+	m := model.NewWebhookDeliveryModel(uc.DB)
+	if tx != nil {
+		endpointModel = model.NewWebhookEndpointModelTx(tx)
+		m = model.NewWebhookDeliveryModelTx(tx)
+	}
+
+	endpoints, err := endpointModel.FindAllActiveByEvent(e.Type)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "find_endpoint", uc.ReqID)
+		return err
+	}
+
+	now := time.Now().UTC()
+	payload := interfacepkg.Marshall(e.Map())
+	for _, endpoint := range endpoints {
+		_, err = m.Store(endpoint.ID, e.ID, e.Type, payload, now, now)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "store", uc.ReqID)
+			return err
+		}
+	}
+
+	return err
+}
+
+// lease time given to send count deliveries before they can be claimed again
+func (uc WebhookUC) lease(count int) time.Duration {
+	return time.Duration(count+1) * uc.envDuration("WEBHOOK_TIMEOUT", DefaultWebhookTimeout)
+}
+
+// deliver send the delivery and record the attempt. Non 2xx response is a failed attempt, it is retried with
+// backoff until WEBHOOK_MAX_ATTEMPTS and it count toward disabling the endpoint after WEBHOOK_DISABLE_AFTER.
+// It return true when the endpoint is disabled by this attempt
+func (uc WebhookUC) deliver(client *webhook.Client, endpoint *model.WebhookEndpointEntity, d *model.WebhookDeliveryEntity) (disabled bool, err error) {
+	ctx := "WebhookUC.deliver"
+
+	secret, err := uc.Aes.Decrypt(endpoint.Secret)
+	if err != nil {
+		return disabled, err
+	}
+
+	now := time.Now().UTC()
+	resp, sendErr := client.Send(webhook.Request{
+		URL:        endpoint.URL,
+		Secret:     secret,
+		DeliveryID: d.ID,
+		EventID:    d.EventID,
+		EventType:  d.EventType,
+		Body:       []byte(d.Payload),
+	}, now)
+	if sendErr == nil && !resp.Success() {
+		sendErr = fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	attempt := model.WebhookDeliveryEntity{
+		Status:         model.WebhookDeliveryStatusSuccess,
+		NextAttemptAt:  now.Format(time.RFC3339Nano),
+		ResponseStatus: sql.NullInt64{Int64: int64(resp.StatusCode), Valid: resp.StatusCode != 0},
+		ResponseBody:   sql.NullString{String: resp.Body, Valid: resp.StatusCode != 0},
+		DurationMs:     sql.NullInt64{Int64: resp.Duration.Milliseconds(), Valid: true},
+		DeliveredAt:    sql.NullString{String: now.Format(time.RFC3339Nano), Valid: true},
+	}
+	if sendErr != nil {
+		attempt.Status = model.WebhookDeliveryStatusPending
+		attempt.NextAttemptAt = now.Add(uc.backoff(d.Attempts + 1)).Format(time.RFC3339Nano)
+		attempt.LastError = sql.NullString{String: sendErr.Error(), Valid: true}
+		attempt.DeliveredAt = sql.NullString{}
+		if d.Attempts+1 >= uc.envInt("WEBHOOK_MAX_ATTEMPTS", DefaultWebhookMaxAttempts) {
+			attempt.Status = model.WebhookDeliveryStatusFailed
+		}
+	}
+
+	m := model.NewWebhookDeliveryModel(uc.DB)
+	_, err = m.MarkAttempt(d.ID, attempt)
+	if err != nil {
+		return disabled, err
+	}
+
+	endpointModel := model.NewWebhookEndpointModel(uc.DB)
+	if sendErr == nil {
+		_, err = endpointModel.ResetFailure(endpoint.ID)
+		return disabled, err
+	}
+
+	logruslogger.Log(logruslogger.WarnLevel, d.ID+" "+sendErr.Error(), ctx, "send", uc.ReqID)
+	disabled, err = endpointModel.AddFailure(endpoint.ID, uc.envInt("WEBHOOK_DISABLE_AFTER", DefaultWebhookDisableAfter), now)
+	if err != nil {
+		return disabled, err
+	}
+	if disabled {
+		logruslogger.Log(logruslogger.WarnLevel, endpoint.ID+" "+endpoint.URL, ctx, "endpoint_disabled", uc.ReqID)
+	}
+
+	return disabled, nil
+}
+
+// Relay send the due deliveries, it return the number of claimed deliveries
+func (uc WebhookUC) Relay(limit int) (claimed int, err error) {
+	ctx := "WebhookUC.Relay"
+
+	m := model.NewWebhookDeliveryModel(uc.DB)
+	data, err := m.Claim(time.Now().UTC(), uc.lease(limit), limit)
+	if err != nil {
+		logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "claim", uc.ReqID)
+		return claimed, err
+	}
+
+	client := uc.client()
+	endpointModel := model.NewWebhookEndpointModel(uc.DB)
+	endpoints := map[string]*model.WebhookEndpointEntity{}
+	for _, d := range data {
+		endpoint, ok := endpoints[d.EndpointID]
+		if !ok {
+			e, err := endpointModel.FindByID(d.EndpointID)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, d.EndpointID+" "+err.Error(), ctx, "find_endpoint", uc.ReqID)
+				continue
+			}
+			endpoint = &e
+			endpoints[d.EndpointID] = endpoint
+		}
+		// Endpoint disabled by the previous delivery of the batch keep the rest for later
+		if !endpoint.IsActive {
+			continue
+		}
+
+		disabled, err := uc.deliver(client, endpoint, &d)
+		if err != nil {
+			logruslogger.Log(logruslogger.WarnLevel, d.ID+" "+err.Error(), ctx, "deliver", uc.ReqID)
+			continue
+		}
+		if disabled {
+			endpoint.IsActive = false
+		}
+	}
+
+	return len(data), nil
+}
+
+// WebhookDeliveryScheduler send the due deliveries every WEBHOOK_RELAY_INTERVAL, a full batch is followed right away
+func WebhookDeliveryScheduler(contractUC *ContractUC) {
+	uc := WebhookUC{ContractUC: contractUC}
+	interval := uc.envDuration("WEBHOOK_RELAY_INTERVAL", DefaultWebhookRelayInterval)
+	limit := uc.envInt("WEBHOOK_RELAY_LIMIT", DefaultWebhookRelayLimit)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for {
+			claimed, err := uc.Relay(limit)
+			if err != nil || claimed < limit {
+				break
+			}
+		}
+	}
+}
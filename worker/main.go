@@ -0,0 +1,201 @@
+package main
+
+import (
+	"kriyapeople/pkg/aes"
+	"kriyapeople/pkg/amqp"
+	"kriyapeople/pkg/amqpconsumer"
+	"kriyapeople/pkg/env"
+	"kriyapeople/pkg/hmacsha"
+	"kriyapeople/pkg/interfacepkg"
+	"kriyapeople/pkg/logruslogger"
+	"kriyapeople/pkg/mail"
+	"kriyapeople/pkg/mailtemplate"
+	"kriyapeople/pkg/pg"
+	"kriyapeople/pkg/storage"
+	"kriyapeople/pkg/str"
+	"kriyapeople/usecase"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-redis/redis/v7"
+	"github.com/rs/xid"
+	streadway "github.com/streadway/amqp"
+)
+
+var (
+	envConfig map[string]string
+
+	// DefaultShutdownTimeout ...
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+// handlers handler of every queue in amqp.Queues, concurrency is read from WORKER_[NAME]_CONCURRENCY
+var handlers = map[string]func(streadway.Delivery, *usecase.ContractUC) error{
+	"activation_mail":     usecase.ActivationMailConsumer,
+	"reset_password_mail": usecase.ResetPasswordMailConsumer,
+	"change_email_mail":   usecase.ChangeEmailMailConsumer,
+	"image_process":       usecase.ImageProcessConsumer,
+}
+
+// Init first time running function
+func init() {
+	// Load env variable from .env file
+	envConfig = env.NewEnvConfig("../.env")
+}
+
+func main() {
+	ctx := "worker"
+
+	// Connect to redis
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     envConfig["REDIS_HOST"],
+		Password: envConfig["REDIS_PASSWORD"],
+		DB:       0,
+	})
+	_, err := redisClient.Ping().Result()
+	if err != nil {
+		panic(err)
+	}
+	defer redisClient.Close()
+
+	// Postgre DB connection
+	dbInfo := pg.Connection{
+		Host:    envConfig["DATABASE_HOST"],
+		DB:      envConfig["DATABASE_DB"],
+		User:    envConfig["DATABASE_USER"],
+		Pass:    envConfig["DATABASE_PASSWORD"],
+		Port:    str.StringToInt(envConfig["DATABASE_PORT"]),
+		SslMode: "disable",
+	}
+	db, err := dbInfo.Connect()
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+
+	// File storage connection
+	storageInfo := storage.Connection{
+		Driver:    envConfig["STORAGE_DRIVER"],
+		Path:      envConfig["FILE_STATIC_FILE"],
+		URL:       envConfig["APP_IMAGE_URL"] + envConfig["FILE_PATH"],
+		Endpoint:  envConfig["S3_ENDPOINT"],
+		AccessKey: envConfig["S3_ACCESS_KEY"],
+		SecretKey: envConfig["S3_SECRET_KEY"],
+		Bucket:    envConfig["S3_BUCKET"],
+		Region:    envConfig["S3_REGION"],
+		UseSSL:    str.StringToBool(envConfig["S3_USE_SSL"]),
+	}
+	fileStorage, err := storageInfo.Connect()
+	if err != nil {
+		panic(err)
+	}
+	htmlStorage, err := storage.NewLocalStorage(envConfig["HTML_FILE_STATIC_FILE"], envConfig["APP_IMAGE_URL"]+envConfig["HTML_FILE_PATH"])
+	if err != nil {
+		panic(err)
+	}
+
+	// Mail driver
+	mailTimeout, _ := time.ParseDuration(envConfig["MAIL_TIMEOUT"])
+	mailInfo := mail.Connection{
+		Driver:       envConfig["MAIL_DRIVER"],
+		Timeout:      mailTimeout,
+		MandrillKey:  envConfig["MANDRILL_KEY"],
+		SMTPHost:     envConfig["SMTP_HOST"],
+		SMTPPort:     str.StringToInt(envConfig["SMTP_PORT"]),
+		SMTPUser:     envConfig["SMTP_USER"],
+		SMTPPassword: envConfig["SMTP_PASSWORD"],
+		Path:         envConfig["MAIL_FILE_PATH"],
+	}
+	mailer, err := mailInfo.Connect()
+	if err != nil {
+		panic(err)
+	}
+	mailTemplate, err := mailtemplate.New(envConfig["MAIL_TEMPLATE_PATH"], envConfig["APP_LOCALE"])
+	if err != nil {
+		panic(err)
+	}
+
+	// The encrypted user data is read by the mail consumers
+	aesCredential, err := aes.NewCredential(envConfig["AES_KEY"], envConfig["AES_KEYS"], envConfig["AES_PRIMARY_KEY_ID"])
+	if err != nil {
+		panic(err)
+	}
+
+	contractUC := usecase.ContractUC{
+		ReqID:         xid.New().String(),
+		DB:            db,
+		Redis:         redisClient,
+		EnvConfig:     envConfig,
+		Aes:           aesCredential,
+		BlindIndexKey: hmacsha.Credential{Key: envConfig["USER_BLIND_INDEX_KEY"]},
+		Storage:       fileStorage,
+		HTMLStorage:   htmlStorage,
+		Mailer:        mailer,
+		MailTemplate:  mailTemplate,
+	}
+
+	reconnectMin, _ := time.ParseDuration(envConfig["AMQP_RECONNECT_MIN"])
+	reconnectMax, _ := time.ParseDuration(envConfig["AMQP_RECONNECT_MAX"])
+	retryMax := str.StringToInt(envConfig["AMQP_RETRY_MAX"])
+	retryDelays := amqpconsumer.ParseDelays(envConfig["AMQP_RETRY_DELAYS"])
+
+	var running sync.WaitGroup
+	consumers := []*amqpconsumer.Consumer{}
+	for _, q := range amqp.Queues {
+		q := q
+		fn, ok := handlers[q.Name]
+		threads := str.StringToInt(envConfig["WORKER_"+strings.ToUpper(q.Name)+"_CONCURRENCY"])
+		if !ok || threads <= 0 {
+			continue
+		}
+
+		consumer := amqpconsumer.NewConsumer(q.Name, envConfig["AMQP_URL"], q.Exchange, "direct", q.DeadLetter).
+			SetReconnect(reconnectMin, reconnectMax).
+			SetRetry(retryMax, retryDelays)
+		consumers = append(consumers, consumer)
+
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			consumer.Handle(func(d streadway.Delivery) error {
+				return fn(d, &contractUC)
+			}, threads, q.Incoming, q.DeadLetter)
+		}()
+
+		startBody := map[string]interface{}{
+			"Queue":       q.Incoming,
+			"Concurrency": threads,
+		}
+		logruslogger.Log(logruslogger.InfoLevel, interfacepkg.Marshall(startBody), ctx, "consumer_start", "")
+	}
+
+	// Wait for the stop signal, then let every in-flight delivery finish
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-stop
+	logruslogger.Log(logruslogger.InfoLevel, sig.String(), ctx, "shutdown", "")
+
+	timeout, err := time.ParseDuration(envConfig["WORKER_SHUTDOWN_TIMEOUT"])
+	if err != nil || timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	var stopping sync.WaitGroup
+	for _, consumer := range consumers {
+		stopping.Add(1)
+		go func(consumer *amqpconsumer.Consumer) {
+			defer stopping.Done()
+			err := consumer.Shutdown(timeout)
+			if err != nil {
+				logruslogger.Log(logruslogger.WarnLevel, err.Error(), ctx, "shutdown", "")
+			}
+		}(consumer)
+	}
+	stopping.Wait()
+	running.Wait()
+
+	logruslogger.Log(logruslogger.InfoLevel, "", ctx, "stopped", "")
+}